@@ -0,0 +1,64 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+/*
+#if defined WIN32
+#include <windows.h>
+#include <TTSAPI.H>
+#else
+#include <dtk/ttsapi.h>
+#endif
+*/
+import "C"
+
+import "runtime/cgo"
+
+// dectalkCallback is passed to TextToSpeechStartupEx and called by the engine
+// from its own threads. It must never block.
+//
+//export dectalkCallback
+func dectalkCallback(lParam1, lParam2 C.LONG, dwCallbackParameter C.DWORD, uiMsg C.UINT) {
+	// The engine hands back the cgo.Handle of the instance passed to
+	// TextToSpeechStartupEx, since Go pointers must not be handed to C.
+	t := cgo.Handle(dwCallbackParameter).Value().(*TTS)
+
+	switch uiMsg {
+	case bufferMessage:
+		b, generation := t.memory.take(uintptr(lParam2))
+		if b == nil {
+			return
+		}
+		// The event has to be built before handing back the buffer since
+		// the application may reuse it right away.
+		if t.events != nil {
+			t.events.push(&BufferFilledEvent{
+				Length:     b.Len(),
+				Phonemes:   b.Phonemes(),
+				IndexMarks: b.IndexMarks(),
+			})
+		}
+		t.memory.handBack(b, generation)
+	case indexMessage:
+		if t.events != nil {
+			t.events.push(&IndexMarkEvent{
+				Value: uint32(lParam2),
+			})
+		}
+	case statusMessage:
+		if t.events != nil {
+			t.events.push(&StatusEvent{
+				Param1: int64(lParam1),
+				Param2: int64(lParam2),
+			})
+		}
+	case errorMessage:
+		t.lastError.Store(uint32(lParam2))
+		if t.events != nil {
+			t.events.push(&ErrorEvent{
+				Err: mmResultToError(C.uint(lParam2)),
+			})
+		}
+	}
+}
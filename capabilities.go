@@ -0,0 +1,215 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+/*
+#if defined WIN32
+#include <windows.h>
+#include <TTSAPI.H>
+#include <TTSFEAT.H>
+#else
+#include <dtk/ttsapi.h>
+#include <dtk/ttsfeat.h>
+#endif
+
+#ifndef TTS_FEATURE_ACCESS32
+#error "TTSFEAT.H does not define TTS_FEATURE_ACCESS32"
+#endif
+#ifndef TTS_FEATURE_MULTI_LANGUAGE
+#error "TTSFEAT.H does not define TTS_FEATURE_MULTI_LANGUAGE"
+#endif
+#ifndef TTS_FEATURE_IN_MEMORY
+#error "TTSFEAT.H does not define TTS_FEATURE_IN_MEMORY"
+#endif
+#ifndef TTS_FEATURE_WAVE_OUT_FILE
+#error "TTSFEAT.H does not define TTS_FEATURE_WAVE_OUT_FILE"
+#endif
+#ifndef TTS_FEATURE_LOG_FILE
+#error "TTSFEAT.H does not define TTS_FEATURE_LOG_FILE"
+#endif
+#ifndef TTS_FEATURE_USER_DICTIONARY
+#error "TTSFEAT.H does not define TTS_FEATURE_USER_DICTIONARY"
+#endif
+#ifndef TTS_FEATURE_INDEX_MARKS
+#error "TTSFEAT.H does not define TTS_FEATURE_INDEX_MARKS"
+#endif
+#ifndef TTS_FEATURE_SAPI5_AUDIO
+#error "TTSFEAT.H does not define TTS_FEATURE_SAPI5_AUDIO"
+#endif
+#ifndef TTS_FEATURE_WAVE_FORMAT_1M08
+#error "TTSFEAT.H does not define TTS_FEATURE_WAVE_FORMAT_1M08"
+#endif
+#ifndef TTS_FEATURE_WAVE_FORMAT_1M16
+#error "TTSFEAT.H does not define TTS_FEATURE_WAVE_FORMAT_1M16"
+#endif
+#ifndef TTS_FEATURE_WAVE_FORMAT_08M08
+#error "TTSFEAT.H does not define TTS_FEATURE_WAVE_FORMAT_08M08"
+#endif
+*/
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// Features is a bitmask describing the features of DECtalk Software as
+// returned by [GetFeatures]. The flags take their values from TTSFEAT.H of the
+// installed engine.
+type Features uint32
+
+const (
+	// The build contains the Access32 fast typing support, see [TTS.Typing].
+	FeatureAccess32 Features = C.TTS_FEATURE_ACCESS32
+
+	// The build supports the Multi-Language (ML) engine, see [StartLang].
+	FeatureMultiLanguage Features = C.TTS_FEATURE_MULTI_LANGUAGE
+
+	// Speech samples can be written to memory, see [TTS.OpenInMemory].
+	FeatureInMemory Features = C.TTS_FEATURE_IN_MEMORY
+
+	// Speech samples can be written to wave files, see [TTS.OpenWaveOutFile].
+	FeatureWaveOutFile Features = C.TTS_FEATURE_WAVE_OUT_FILE
+
+	// Text, phonemes and syllables can be logged, see [TTS.OpenLogFile].
+	FeatureLogFile Features = C.TTS_FEATURE_LOG_FILE
+
+	// User dictionaries can be loaded, see [TTS.LoadUserDictionary].
+	FeatureUserDictionary Features = C.TTS_FEATURE_USER_DICTIONARY
+
+	// Index marks are reported, see [IndexMarkEvent].
+	FeatureIndexMarks Features = C.TTS_FEATURE_INDEX_MARKS
+
+	// Audio can be played through SAPI 5, see [UseSAPI5AudioDevice].
+	FeatureSAPI5Audio Features = C.TTS_FEATURE_SAPI5_AUDIO
+
+	// Speech samples can be produced in [WaveFormat1M08].
+	FeatureWaveFormat1M08 Features = C.TTS_FEATURE_WAVE_FORMAT_1M08
+
+	// Speech samples can be produced in [WaveFormat1M16].
+	FeatureWaveFormat1M16 Features = C.TTS_FEATURE_WAVE_FORMAT_1M16
+
+	// Speech samples can be produced in [WaveFormat08M08].
+	FeatureWaveFormat08M08 Features = C.TTS_FEATURE_WAVE_FORMAT_08M08
+)
+
+var featureNames = []struct {
+	flag Features
+	name string
+}{
+	{FeatureAccess32, "Access32"},
+	{FeatureMultiLanguage, "MultiLanguage"},
+	{FeatureInMemory, "InMemory"},
+	{FeatureWaveOutFile, "WaveOutFile"},
+	{FeatureLogFile, "LogFile"},
+	{FeatureUserDictionary, "UserDictionary"},
+	{FeatureIndexMarks, "IndexMarks"},
+	{FeatureSAPI5Audio, "SAPI5Audio"},
+	{FeatureWaveFormat1M08, "WaveFormat1M08"},
+	{FeatureWaveFormat1M16, "WaveFormat1M16"},
+	{FeatureWaveFormat08M08, "WaveFormat08M08"},
+}
+
+// Has reports whether all of the given feature flags are set.
+func (f Features) Has(flags Features) bool {
+	return f&flags == flags
+}
+
+// SupportsWaveFormat reports whether speech samples can be produced in the
+// given format.
+func (f Features) SupportsWaveFormat(format WaveFormat) bool {
+	switch format {
+	case WaveFormat1M08:
+		return f.Has(FeatureWaveFormat1M08)
+	case WaveFormat1M16:
+		return f.Has(FeatureWaveFormat1M16)
+	case WaveFormat08M08:
+		return f.Has(FeatureWaveFormat08M08)
+	}
+	return false
+}
+
+// String returns the names of the set flags separated by "|". Unknown flags
+// are appended in hexadecimal.
+func (f Features) String() string {
+	if f == 0 {
+		return "0"
+	}
+	var names []string
+	for _, n := range featureNames {
+		if f&n.flag == n.flag {
+			names = append(names, n.name)
+			f &^= n.flag
+		}
+	}
+	if f != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint32(f)))
+	}
+	return strings.Join(names, "|")
+}
+
+// GetFeatures retrieves information about the features of DECtalk Software.
+func GetFeatures() Features {
+	return Features(C.TextToSpeechGetFeatures())
+}
+
+// LanguageParams describes a language supported by the text-to-speech system.
+type LanguageParams struct {
+	Language   uint32
+	Dialect    uint32
+	Attributes uint32
+}
+
+// Capabilities describes the capabilities of the text-to-speech system as
+// returned by [GetCaps].
+type Capabilities struct {
+	// Languages holds one entry per supported language.
+	Languages []LanguageParams
+
+	// SampleRate is the native sample rate of the engine in Hz.
+	SampleRate uint32
+
+	// MinimumSpeakingRate and MaximumSpeakingRate are the limits for
+	// [TTS.SetRate] in words per minute.
+	MinimumSpeakingRate uint32
+	MaximumSpeakingRate uint32
+
+	// PredefinedSpeakers is the number of built-in voices, see [Speaker].
+	PredefinedSpeakers uint32
+
+	// CharacterSet identifies the character set the engine expects.
+	CharacterSet uint32
+
+	// Version is the numerically encoded version of the engine.
+	Version uint32
+}
+
+// GetCaps retrieves the capabilities of the text-to-speech system.
+func GetCaps() (*Capabilities, error) {
+	var capsC C.TTS_CAPS_T
+	if err := mmResultToError(C.TextToSpeechGetCaps(&capsC)); err != nil {
+		return nil, err
+	}
+
+	caps := &Capabilities{
+		SampleRate:          uint32(capsC.dwSampleRate),
+		MinimumSpeakingRate: uint32(capsC.dwMinimumSpeakingRate),
+		MaximumSpeakingRate: uint32(capsC.dwMaximumSpeakingRate),
+		PredefinedSpeakers:  uint32(capsC.dwNumberOfPredefinedSpeakers),
+		CharacterSet:        uint32(capsC.dwCharacterSet),
+		Version:             uint32(capsC.dwVersion),
+	}
+	if n := int(capsC.dwNumberOfLanguages); n > 0 && capsC.lpLanguageParamsArray != nil {
+		caps.Languages = make([]LanguageParams, n)
+		for i, lang := range unsafe.Slice(capsC.lpLanguageParamsArray, n) {
+			caps.Languages[i] = LanguageParams{
+				Language:   uint32(lang.dwLanguage),
+				Dialect:    uint32(lang.dwDialect),
+				Attributes: uint32(lang.dwLanguageAttributes),
+			}
+		}
+	}
+	return caps, nil
+}
@@ -0,0 +1,219 @@
+package container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// aifcVersion is the timestamp of the AIFF-C version in the FVER chunk.
+const aifcVersion = 0xa2805140
+
+// aifcCompression returns the compression type and name of AIFF-C for
+// encodings that plain AIFF can not hold.
+func aifcCompression(e Encoding) (string, string, bool) {
+	switch e {
+	case Float32:
+		return "fl32", "32-bit floating point", true
+	case MuLaw:
+		return "ulaw", "\xb5Law 2:1", true
+	case ALaw:
+		return "alaw", "ALaw 2:1", true
+	}
+	return "", "", false
+}
+
+// aiffHeader returns the FORM, COMM and SSND headers up to the start of the
+// samples.
+func aiffHeader(f Format, dataLen int64) ([]byte, error) {
+	compression, name, aifc := aifcCompression(f.Encoding)
+	sampleSize := f.Encoding.BytesPerSample() * 8
+	if f.Encoding == MuLaw || f.Encoding == ALaw {
+		// The size of the decoded samples, by convention.
+		sampleSize = 16
+	}
+
+	var comm bytes.Buffer
+	frames := uint32(unknownSize)
+	if dataLen != UnknownLength {
+		frames = uint32(dataLen / int64(f.FrameSize()))
+	}
+	_ = binary.Write(&comm, binary.BigEndian, uint16(f.Channels))
+	_ = binary.Write(&comm, binary.BigEndian, frames)
+	_ = binary.Write(&comm, binary.BigEndian, uint16(sampleSize))
+	comm.Write(extended(float64(f.SampleRate)))
+	if aifc {
+		comm.WriteString(compression)
+		// A Pascal string padded to an even length.
+		comm.WriteByte(byte(len(name)))
+		comm.WriteString(name)
+		if (len(name)+1)%2 != 0 {
+			comm.WriteByte(0)
+		}
+	}
+
+	var chunks bytes.Buffer
+	formType := "AIFF"
+	if aifc {
+		formType = "AIFC"
+		version := make([]byte, 4)
+		binary.BigEndian.PutUint32(version, aifcVersion)
+		writeChunk(&chunks, "FVER", version)
+	}
+	writeChunk(&chunks, "COMM", comm.Bytes())
+
+	ssndLen := uint32(unknownSize)
+	formLen := uint32(unknownSize)
+	if dataLen != UnknownLength {
+		ssndLen = uint32(8 + dataLen)
+		formLen = uint32(4 + int64(chunks.Len()) + 8 + 8 + dataLen + dataLen%2)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("FORM")
+	_ = binary.Write(&b, binary.BigEndian, formLen)
+	b.WriteString(formType)
+	chunks.WriteTo(&b)
+	b.WriteString("SSND")
+	_ = binary.Write(&b, binary.BigEndian, ssndLen)
+	_ = binary.Write(&b, binary.BigEndian, uint32(0)) // offset
+	_ = binary.Write(&b, binary.BigEndian, uint32(0)) // block size
+	return b.Bytes(), nil
+}
+
+func writeChunk(b *bytes.Buffer, id string, data []byte) {
+	b.WriteString(id)
+	_ = binary.Write(b, binary.BigEndian, uint32(len(data)))
+	b.Write(data)
+	if len(data)%2 != 0 {
+		b.WriteByte(0)
+	}
+}
+
+// extended encodes a positive number as an 80-bit IEEE 754 extended precision
+// number, as used for the sample rate of AIFF files.
+func extended(x float64) []byte {
+	b := make([]byte, 10)
+	if x <= 0 {
+		return b
+	}
+	frac, exp := math.Frexp(x)
+	// frac is in [0.5, 1), the mantissa has an explicit integer bit.
+	mantissa := uint64(math.Ldexp(frac, 64))
+	binary.BigEndian.PutUint16(b, uint16(exp-1+16383))
+	binary.BigEndian.PutUint64(b[2:], mantissa)
+	return b
+}
+
+// parseExtended decodes an 80-bit IEEE 754 extended precision number.
+func parseExtended(b []byte) float64 {
+	exp := int(binary.BigEndian.Uint16(b) & 0x7fff)
+	mantissa := binary.BigEndian.Uint64(b[2:])
+	if mantissa == 0 {
+		return 0
+	}
+	x := math.Ldexp(float64(mantissa), exp-16383-63)
+	if b[0]&0x80 != 0 {
+		x = -x
+	}
+	return x
+}
+
+// readAIFF reads the chunks after the "FORM" tag up to the start of the
+// samples. The COMM chunk has to come before the SSND chunk.
+func readAIFF(r io.Reader) (Format, int64, error) {
+	var form struct {
+		Size uint32
+		Type [4]byte
+	}
+	if err := binary.Read(r, binary.BigEndian, &form); err != nil {
+		return Format{}, 0, err
+	}
+	aifc := false
+	switch string(form.Type[:]) {
+	case "AIFF":
+	case "AIFC":
+		aifc = true
+	default:
+		return Format{}, 0, fmt.Errorf("%w: FORM of type %q", ErrUnknownContainer, form.Type[:])
+	}
+
+	var f Format
+	for {
+		id, size, err := readChunkHeader(r, binary.BigEndian)
+		if err != nil {
+			return Format{}, 0, err
+		}
+		switch id {
+		case "COMM":
+			if size < 18 {
+				return Format{}, 0, fmt.Errorf("%w: COMM chunk of %d bytes", ErrUnsupportedFormat, size)
+			}
+			chunk := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return Format{}, 0, err
+			}
+			if f, err = parseAIFFFormat(chunk, aifc); err != nil {
+				return Format{}, 0, err
+			}
+		case "SSND":
+			if f.Encoding == 0 {
+				return Format{}, 0, fmt.Errorf("%w: SSND before COMM chunk", ErrUnsupportedFormat)
+			}
+			var ssnd struct {
+				Offset    uint32
+				BlockSize uint32
+			}
+			if err := binary.Read(r, binary.BigEndian, &ssnd); err != nil {
+				return Format{}, 0, err
+			}
+			if err := skip(r, int64(ssnd.Offset)); err != nil {
+				return Format{}, 0, err
+			}
+			if size == unknownSize || size < 8+ssnd.Offset {
+				return f, UnknownLength, nil
+			}
+			return f, int64(size) - 8 - int64(ssnd.Offset), nil
+		default:
+			if err := skip(r, int64(size)+int64(size%2)); err != nil {
+				return Format{}, 0, err
+			}
+		}
+	}
+}
+
+func parseAIFFFormat(chunk []byte, aifc bool) (Format, error) {
+	f := Format{
+		Channels:   int(binary.BigEndian.Uint16(chunk[0:])),
+		SampleRate: int(math.Round(parseExtended(chunk[8:18]))),
+	}
+	sampleSize := binary.BigEndian.Uint16(chunk[6:])
+	compression := "NONE"
+	if aifc && len(chunk) >= 22 {
+		compression = string(chunk[18:22])
+	}
+
+	switch compression {
+	case "NONE", "twos":
+		switch (sampleSize + 7) / 8 {
+		case 1:
+			f.Encoding = PCM8
+		case 2:
+			f.Encoding = PCM16
+		case 3:
+			f.Encoding = PCM24
+		}
+	case "fl32", "FL32":
+		f.Encoding = Float32
+	case "ulaw", "ULAW":
+		f.Encoding = MuLaw
+	case "alaw", "ALAW":
+		f.Encoding = ALaw
+	}
+	if !f.Valid() {
+		return Format{}, fmt.Errorf("%w: AIFF compression %q with %d bits", ErrUnsupportedFormat, compression, sampleSize)
+	}
+	return f, nil
+}
@@ -0,0 +1,90 @@
+package container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Encodings of the AU header.
+const (
+	auMuLaw   = 1
+	auPCM8    = 2
+	auPCM16   = 3
+	auPCM24   = 4
+	auFloat32 = 6
+	auALaw    = 27
+)
+
+// auHeaderSize is the size of the header written, including the minimum of
+// four bytes of annotation.
+const auHeaderSize = 28
+
+var auEncodings = map[Encoding]uint32{
+	MuLaw:   auMuLaw,
+	PCM8:    auPCM8,
+	PCM16:   auPCM16,
+	PCM24:   auPCM24,
+	Float32: auFloat32,
+	ALaw:    auALaw,
+}
+
+// auHeader returns the header up to the start of the samples. AU has an
+// official marker for an unknown length.
+func auHeader(f Format, dataLen int64) ([]byte, error) {
+	size := uint32(unknownSize)
+	if dataLen != UnknownLength {
+		size = uint32(dataLen)
+	}
+	var b bytes.Buffer
+	b.WriteString(".snd")
+	for _, field := range []uint32{
+		auHeaderSize,
+		size,
+		auEncodings[f.Encoding],
+		uint32(f.SampleRate),
+		uint32(f.Channels),
+		0, // annotation
+	} {
+		_ = binary.Write(&b, binary.BigEndian, field)
+	}
+	return b.Bytes(), nil
+}
+
+// readAU reads the header after the ".snd" magic up to the start of the
+// samples.
+func readAU(r io.Reader) (Format, int64, error) {
+	var h struct {
+		Offset     uint32
+		Size       uint32
+		Encoding   uint32
+		SampleRate uint32
+		Channels   uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &h); err != nil {
+		return Format{}, 0, err
+	}
+	if h.Offset < 24 {
+		return Format{}, 0, fmt.Errorf("%w: AU data offset %d", ErrUnknownContainer, h.Offset)
+	}
+	f := Format{
+		SampleRate: int(h.SampleRate),
+		Channels:   int(h.Channels),
+	}
+	for e, code := range auEncodings {
+		if code == h.Encoding {
+			f.Encoding = e
+		}
+	}
+	if !f.Valid() {
+		return Format{}, 0, fmt.Errorf("%w: AU encoding %d", ErrUnsupportedFormat, h.Encoding)
+	}
+	if err := skip(r, int64(h.Offset)-24); err != nil {
+		return Format{}, 0, err
+	}
+	if h.Size == unknownSize {
+		return f, UnknownLength, nil
+	}
+	return f, int64(h.Size), nil
+}
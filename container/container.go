@@ -0,0 +1,362 @@
+// Package container writes and reads the headers of WAV, AIFF and Sun AU
+// files around the raw samples produced by the engine, for example in
+// speech-to-memory mode or by [dectalkdapi.TTS.OpenWaveOutFile].
+//
+// Samples are always passed to a [Writer] and returned by a [Reader] in the
+// layout of the engine and of WAV files: little-endian, with 8-bit linear
+// samples unsigned. They are converted to the big-endian, signed layout of
+// AIFF and AU files on the fly:
+//
+//	w, err := container.NewWriter(f, container.AIFF, container.Format1M16, container.UnknownLength)
+//	if err != nil {
+//		return err
+//	}
+//	if err := tts.SynthesizeTo(ctx, w, text, dectalkdapi.WaveFormat1M16); err != nil {
+//		return err
+//	}
+//	return w.Close()
+//
+// If the length of the samples is not known up front, a header announcing an
+// unknown length is written, so that the audio can be piped to another
+// program while it is still being synthesized. If the destination can seek,
+// such as an [*os.File], the header is completed by [Writer.Close].
+package container
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrUnknownContainer is returned by [NewReader] for data that is not a
+	// WAV, AIFF or AU file.
+	ErrUnknownContainer = errors.New("unknown container")
+
+	// ErrUnsupportedFormat is returned for sample formats that can not be
+	// stored in or read from a container.
+	ErrUnsupportedFormat = errors.New("unsupported sample format")
+
+	// ErrPartialSample is returned by [Writer.Close] if the data written
+	// ends within a sample.
+	ErrPartialSample = errors.New("sample data ends within a sample")
+
+	// ErrLength is returned by [Writer.Close] if the length of the data
+	// written differs from the length given to [NewWriter].
+	ErrLength = errors.New("sample data does not match the length in the header")
+)
+
+// Encoding is the encoding of a single sample.
+type Encoding int
+
+const (
+	// PCM8 is 8-bit linear PCM.
+	PCM8 Encoding = iota + 1
+
+	// PCM16 is 16-bit linear PCM.
+	PCM16
+
+	// PCM24 is 24-bit linear PCM.
+	PCM24
+
+	// Float32 is 32-bit IEEE 754 floating point ranging from -1 to 1.
+	Float32
+
+	// MuLaw is 8-bit G.711 μ-law.
+	MuLaw
+
+	// ALaw is 8-bit G.711 A-law.
+	ALaw
+)
+
+var encodingNames = map[Encoding]string{
+	PCM8:    "8-bit PCM",
+	PCM16:   "16-bit PCM",
+	PCM24:   "24-bit PCM",
+	Float32: "32-bit float",
+	MuLaw:   "μ-law",
+	ALaw:    "A-law",
+}
+
+func (e Encoding) String() string {
+	if name, ok := encodingNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("Encoding(%d)", int(e))
+}
+
+// BytesPerSample returns the size of a single sample in bytes, or 0 for
+// unknown encodings.
+func (e Encoding) BytesPerSample() int {
+	switch e {
+	case PCM8, MuLaw, ALaw:
+		return 1
+	case PCM16:
+		return 2
+	case PCM24:
+		return 3
+	case Float32:
+		return 4
+	}
+	return 0
+}
+
+// Format describes the samples held by a container.
+type Format struct {
+	Encoding   Encoding
+	SampleRate int
+	Channels   int
+}
+
+// The formats of the engine, see [dectalkdapi.WaveFormat].
+var (
+	// Format1M08 is mono 8-bit PCM at 11.025 kHz.
+	Format1M08 = Format{Encoding: PCM8, SampleRate: 11025, Channels: 1}
+
+	// Format1M16 is mono 16-bit PCM at 11.025 kHz.
+	Format1M16 = Format{Encoding: PCM16, SampleRate: 11025, Channels: 1}
+
+	// Format08M08 is mono μ-law at 8 kHz.
+	Format08M08 = Format{Encoding: MuLaw, SampleRate: 8000, Channels: 1}
+)
+
+func (f Format) String() string {
+	return fmt.Sprintf("%s, %d Hz, %d channels", f.Encoding, f.SampleRate, f.Channels)
+}
+
+// FrameSize returns the size of one sample of every channel in bytes.
+func (f Format) FrameSize() int {
+	return f.Encoding.BytesPerSample() * f.Channels
+}
+
+// Valid reports whether the format has a known encoding, a sample rate and
+// between 1 and 65535 channels.
+func (f Format) Valid() bool {
+	return f.Encoding.BytesPerSample() > 0 && f.SampleRate > 0 && f.Channels > 0 && f.Channels <= 0xffff
+}
+
+// Container is a file format holding samples.
+type Container int
+
+const (
+	// WAV is the RIFF WAVE format.
+	WAV Container = iota + 1
+
+	// AIFF is the Audio Interchange File Format. Samples that are not
+	// linear PCM are stored in its AIFF-C variant.
+	AIFF
+
+	// AU is the Sun/NeXT audio format.
+	AU
+)
+
+func (c Container) String() string {
+	switch c {
+	case WAV:
+		return "WAV"
+	case AIFF:
+		return "AIFF"
+	case AU:
+		return "AU"
+	}
+	return fmt.Sprintf("Container(%d)", int(c))
+}
+
+// Extension returns the usual file name extension of the container, such as
+// ".wav".
+func (c Container) Extension() string {
+	switch c {
+	case WAV:
+		return ".wav"
+	case AIFF:
+		return ".aiff"
+	case AU:
+		return ".au"
+	}
+	return ""
+}
+
+// ContainerFor returns the container matching the extension of a file name.
+func ContainerFor(name string) (Container, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".wav", ".wave":
+		return WAV, true
+	case ".aif", ".aiff", ".aifc":
+		return AIFF, true
+	case ".au", ".snd":
+		return AU, true
+	}
+	return 0, false
+}
+
+// UnknownLength is passed to [NewWriter] if the length of the samples is not
+// known yet, and returned by [Reader.Len] for streamed files.
+const UnknownLength = -1
+
+// unknownSize is stored in the size fields of headers for an unknown length.
+const unknownSize = 0xffffffff
+
+// header returns the header of a container holding dataLen bytes of samples,
+// or UnknownLength. The header has the same size for every length.
+func header(c Container, f Format, dataLen int64) ([]byte, error) {
+	if !f.Valid() {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, f)
+	}
+	if dataLen > unknownSize-64 {
+		return nil, fmt.Errorf("%d bytes of samples do not fit into a %v file", dataLen, c)
+	}
+	switch c {
+	case WAV:
+		return wavHeader(f, dataLen)
+	case AIFF:
+		return aiffHeader(f, dataLen)
+	case AU:
+		return auHeader(f, dataLen)
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnknownContainer, c)
+}
+
+// swapped reports whether samples of the container differ from the layout of
+// the engine, see convert.
+func swapped(c Container, e Encoding) bool {
+	return c != WAV && e != MuLaw && e != ALaw
+}
+
+// convert converts whole samples between the layout of the engine and the
+// big-endian, signed layout of AIFF and AU. The conversion is its own inverse.
+func convert(p []byte, e Encoding) {
+	switch size := e.BytesPerSample(); size {
+	case 1:
+		for i := range p {
+			p[i] ^= 0x80
+		}
+	default:
+		for i := 0; i+size <= len(p); i += size {
+			s := p[i : i+size]
+			for j, k := 0, size-1; j < k; j, k = j+1, k-1 {
+				s[j], s[k] = s[k], s[j]
+			}
+		}
+	}
+}
+
+// Writer writes samples into a container.
+type Writer struct {
+	w         io.Writer
+	container Container
+	format    Format
+	length    int64
+
+	// seeker is set if the header can be completed on Close, start being
+	// the offset of the header.
+	seeker io.WriteSeeker
+	start  int64
+
+	written int64
+	partial []byte
+	buf     []byte
+	closed  bool
+}
+
+// NewWriter writes the header of a container holding dataLen bytes of samples
+// in the given format to w and returns a writer for the samples.
+//
+// If dataLen is [UnknownLength], the header announces an unknown length, which
+// most programs accept for audio streamed through a pipe. If w is an
+// [io.WriteSeeker], Close then replaces it with a complete header.
+func NewWriter(w io.Writer, c Container, f Format, dataLen int64) (*Writer, error) {
+	h, err := header(c, f, dataLen)
+	if err != nil {
+		return nil, err
+	}
+	cw := &Writer{
+		w:         w,
+		container: c,
+		format:    f,
+		length:    dataLen,
+	}
+	if ws, ok := w.(io.WriteSeeker); ok && dataLen == UnknownLength {
+		// Pipes implement Seek as well, but fail.
+		if start, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			cw.seeker, cw.start = ws, start
+		}
+	}
+	if _, err := w.Write(h); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write writes samples in the layout of the engine, converting them to the
+// layout of the container. Samples may be split across calls.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed container writer")
+	}
+	if !swapped(w.container, w.format.Encoding) {
+		n, err := w.w.Write(p)
+		w.written += int64(n)
+		return n, err
+	}
+
+	size := w.format.Encoding.BytesPerSample()
+	w.buf = append(append(w.buf[:0], w.partial...), p...)
+	whole := len(w.buf) - len(w.buf)%size
+	convert(w.buf[:whole], w.format.Encoding)
+	n, err := w.w.Write(w.buf[:whole])
+	w.written += int64(n)
+	if err != nil {
+		// Report the bytes of p that made it, as far as they are known.
+		n -= len(w.partial)
+		if n < 0 {
+			n = 0
+		}
+		return n, err
+	}
+	w.partial = append(w.partial[:0], w.buf[whole:]...)
+	return len(p), nil
+}
+
+// Close pads the data as required by the container and, if the length was
+// unknown and the destination can seek, completes the header. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if len(w.partial) > 0 {
+		return ErrPartialSample
+	}
+	if w.length != UnknownLength && w.written != w.length {
+		return fmt.Errorf("%w: %d bytes announced, %d bytes written", ErrLength, w.length, w.written)
+	}
+	// Chunks of RIFF and IFF files have an even length.
+	if w.written%2 != 0 && w.container != AU {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	if w.seeker == nil {
+		return nil
+	}
+
+	h, err := header(w.container, w.format, w.written)
+	if err != nil {
+		return err
+	}
+	end, err := w.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := w.seeker.Seek(w.start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.seeker.Write(h); err != nil {
+		return err
+	}
+	_, err = w.seeker.Seek(end, io.SeekStart)
+	return err
+}
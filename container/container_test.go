@@ -0,0 +1,208 @@
+package container_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/icedream/go-dectalkdapi/container"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	samples := []byte{0x00, 0x7f, 0x80, 0xff, 0x12, 0x34, 0x56, 0x78, 0x9a}
+	for _, c := range []container.Container{container.WAV, container.AIFF, container.AU} {
+		for _, f := range []container.Format{
+			container.Format1M08,
+			container.Format1M16,
+			container.Format08M08,
+			{Encoding: container.PCM24, SampleRate: 44100, Channels: 1},
+			{Encoding: container.Float32, SampleRate: 48000, Channels: 2},
+		} {
+			data := samples[:len(samples)-len(samples)%f.FrameSize()]
+
+			var b bytes.Buffer
+			w, err := container.NewWriter(&b, c, f, int64(len(data)))
+			if err != nil {
+				t.Fatalf("NewWriter(%v, %v) failed: %v", c, f, err)
+			}
+			// Split samples across writes.
+			for _, p := range [][]byte{data[:1], data[1:]} {
+				if _, err := w.Write(p); err != nil {
+					t.Fatalf("Write() failed: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() failed: %v", err)
+			}
+
+			r, err := container.NewReader(&b)
+			if err != nil {
+				t.Fatalf("NewReader(%v, %v) failed: %v", c, f, err)
+			}
+			if r.Container() != c || r.Format() != f || r.Len() != int64(len(data)) {
+				t.Errorf("%v, %v: got %v, %v with %d bytes", c, f, r.Container(), r.Format(), r.Len())
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() failed: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("%v, %v: got samples %x, want %x", c, f, got, data)
+			}
+		}
+	}
+}
+
+func TestSwapped(t *testing.T) {
+	var b bytes.Buffer
+	w, err := container.NewWriter(&b, container.AU, container.Format1M16, 4)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	if _, err := w.Write([]byte{0x01, 0x02, 0x03, 0x04}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if got, want := b.Bytes()[b.Len()-4:], []byte{0x02, 0x01, 0x04, 0x03}; !bytes.Equal(got, want) {
+		t.Errorf("got big-endian samples %x, want %x", got, want)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5, 6}
+	for _, c := range []container.Container{container.WAV, container.AIFF, container.AU} {
+		// A pipe can not seek, so the header keeps the unknown length.
+		var b bytes.Buffer
+		w, err := container.NewWriter(&b, c, container.Format1M16, container.UnknownLength)
+		if err != nil {
+			t.Fatalf("NewWriter(%v) failed: %v", c, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+		r, err := container.NewReader(&b)
+		if err != nil {
+			t.Fatalf("NewReader(%v) failed: %v", c, err)
+		}
+		if r.Len() != container.UnknownLength {
+			t.Errorf("%v: got length %d, want unknown", c, r.Len())
+		}
+		if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%v: got samples %x, %v", c, got, err)
+		}
+
+		// A file can, so the header is completed on Close.
+		f, err := os.Create(filepath.Join(t.TempDir(), "stream"+c.Extension()))
+		if err != nil {
+			t.Fatalf("Create() failed: %v", err)
+		}
+		defer f.Close()
+		w, err = container.NewWriter(f, c, container.Format1M16, container.UnknownLength)
+		if err != nil {
+			t.Fatalf("NewWriter(%v) failed: %v", c, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatalf("Seek() failed: %v", err)
+		}
+		r, err = container.NewReader(f)
+		if err != nil {
+			t.Fatalf("NewReader(%v) failed: %v", c, err)
+		}
+		if r.Len() != int64(len(data)) {
+			t.Errorf("%v: got length %d after Close, want %d", c, r.Len(), len(data))
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := container.NewReader(bytes.NewReader([]byte("OggS\x00\x00"))); !errors.Is(err, container.ErrUnknownContainer) {
+		t.Errorf("NewReader() on Ogg: got %v, want ErrUnknownContainer", err)
+	}
+	if _, err := container.NewWriter(io.Discard, container.WAV, container.Format{}, 0); !errors.Is(err, container.ErrUnsupportedFormat) {
+		t.Errorf("NewWriter() with zero format: got %v, want ErrUnsupportedFormat", err)
+	}
+
+	w, err := container.NewWriter(io.Discard, container.AIFF, container.Format1M16, 4)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	if _, err := w.Write([]byte{1, 2, 3}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, container.ErrPartialSample) {
+		t.Errorf("Close() after partial sample: got %v, want ErrPartialSample", err)
+	}
+
+	w, err = container.NewWriter(io.Discard, container.WAV, container.Format1M16, 4)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, container.ErrLength) {
+		t.Errorf("Close() without samples: got %v, want ErrLength", err)
+	}
+}
+
+func TestContainerFor(t *testing.T) {
+	tests := map[string]container.Container{
+		"out.wav":      container.WAV,
+		"OUT.AIF":      container.AIFF,
+		"dir/out.aifc": container.AIFF,
+		"out.snd":      container.AU,
+	}
+	for name, want := range tests {
+		if got, ok := container.ContainerFor(name); !ok || got != want {
+			t.Errorf("ContainerFor(%q) = %v, %v, want %v", name, got, ok, want)
+		}
+	}
+	if _, ok := container.ContainerFor("out.mp3"); ok {
+		t.Errorf("ContainerFor(%q) succeeded", "out.mp3")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	samples := []float64{0, 0.5, -0.5, -1, 0.999}
+	for _, e := range []container.Encoding{container.PCM8, container.PCM16, container.PCM24, container.Float32, container.MuLaw, container.ALaw} {
+		got := container.Decode(nil, container.Encode(nil, samples, e), e)
+		if len(got) != len(samples) {
+			t.Fatalf("%v: got %d samples, want %d", e, len(got), len(samples))
+		}
+		maxErr := math.Ldexp(1, 1-8*e.BytesPerSample())
+		switch e {
+		case container.Float32:
+			maxErr = 1e-7
+		case container.MuLaw, container.ALaw:
+			// G.711 keeps about 5 bits of precision near full scale.
+			maxErr = 1.0 / 32
+		}
+		for i := range samples {
+			if math.Abs(got[i]-samples[i]) > maxErr {
+				t.Errorf("%v: got %v for %v", e, got[i], samples[i])
+			}
+		}
+	}
+
+	// Clipping and the silence of G.711.
+	if got := container.Encode(nil, []float64{2, -2}, container.PCM16); !bytes.Equal(got, []byte{0xff, 0x7f, 0x00, 0x80}) {
+		t.Errorf("got clipped samples %x", got)
+	}
+	if got := container.Encode(nil, []float64{0, 0}, container.MuLaw); !bytes.Equal(got, []byte{0xff, 0xff}) {
+		t.Errorf("got μ-law silence %x", got)
+	}
+	if got := container.Encode(nil, []float64{0}, container.ALaw); !bytes.Equal(got, []byte{0xd5}) {
+		t.Errorf("got A-law silence %x", got)
+	}
+}
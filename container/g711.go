@@ -0,0 +1,76 @@
+package container
+
+// G.711 companding as in the reference implementation by Sun Microsystems,
+// working on 16-bit linear samples.
+
+func muLawDecode(u byte) int16 {
+	u = ^u
+	t := int16(u&0x0f)<<3 + 0x84
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return 0x84 - t
+	}
+	return t - 0x84
+}
+
+func muLawEncode(s int16) byte {
+	const bias, clip = 0x84, 32635
+	x := int(s)
+	sign := byte(0)
+	if x < 0 {
+		x, sign = -x, 0x80
+	}
+	if x > clip {
+		x = clip
+	}
+	x += bias
+	exp := 7
+	for mask := 0x4000; x&mask == 0 && exp > 0; mask >>= 1 {
+		exp--
+	}
+	mantissa := byte(x>>(exp+3)) & 0x0f
+	return ^(sign | byte(exp)<<4 | mantissa)
+}
+
+func aLawDecode(a byte) int16 {
+	a ^= 0x55
+	t := int16(a&0x0f) << 4
+	switch seg := (a & 0x70) >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&0x80 != 0 {
+		return t
+	}
+	return -t
+}
+
+// aLawSegments holds the largest 13-bit magnitude of each A-law segment.
+var aLawSegments = [8]int{0x1f, 0x3f, 0x7f, 0xff, 0x1ff, 0x3ff, 0x7ff, 0xfff}
+
+func aLawEncode(s int16) byte {
+	x := int(s) >> 3
+	mask := byte(0xd5)
+	if x < 0 {
+		x, mask = -x-1, 0x55
+	}
+	seg := 0
+	for seg < len(aLawSegments) && x > aLawSegments[seg] {
+		seg++
+	}
+	if seg == len(aLawSegments) {
+		return 0x7f ^ mask
+	}
+	a := byte(seg) << 4
+	if seg < 2 {
+		a |= byte(x>>1) & 0x0f
+	} else {
+		a |= byte(x>>seg) & 0x0f
+	}
+	return a ^ mask
+}
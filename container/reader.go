@@ -0,0 +1,111 @@
+package container
+
+import (
+	"fmt"
+	"io"
+)
+
+// Reader reads the samples of a container.
+type Reader struct {
+	r         io.Reader
+	container Container
+	format    Format
+	length    int64
+
+	// remaining is the number of bytes of samples left, or UnknownLength.
+	remaining int64
+}
+
+// NewReader reads the headers of a WAV, AIFF or AU file from r, which is then
+// positioned at the first sample. Headers announcing an unknown length are
+// accepted; the samples then extend to the end of r.
+func NewReader(r io.Reader) (*Reader, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, err
+	}
+
+	var c Container
+	var read func(io.Reader) (Format, int64, error)
+	switch string(magic[:]) {
+	case "RIFF":
+		c, read = WAV, readWAV
+	case "FORM":
+		c, read = AIFF, readAIFF
+	case ".snd":
+		c, read = AU, readAU
+	default:
+		return nil, fmt.Errorf("%w: magic %q", ErrUnknownContainer, magic[:])
+	}
+	f, length, err := read(r)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &Reader{
+		r:         r,
+		container: c,
+		format:    f,
+		length:    length,
+		remaining: length,
+	}, nil
+}
+
+// Container returns the type of the container.
+func (r *Reader) Container() Container {
+	return r.container
+}
+
+// Format returns the format of the samples.
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// Len returns the length of the samples in bytes as stated by the header, or
+// [UnknownLength].
+func (r *Reader) Len() int64 {
+	return r.length
+}
+
+// Read reads samples, converted to the layout of the engine. It only returns
+// whole samples, so p must hold at least one.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	if r.remaining > 0 && int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	size := r.format.Encoding.BytesPerSample()
+	swap := swapped(r.container, r.format.Encoding)
+	if swap {
+		if len(p) < size {
+			return 0, io.ErrShortBuffer
+		}
+		p = p[:len(p)-len(p)%size]
+	}
+
+	n, err := r.r.Read(p)
+	if rest := n % size; swap && rest != 0 && err == nil {
+		var m int
+		m, err = io.ReadFull(r.r, p[n:n+size-rest])
+		n += m
+	}
+	if swap {
+		// A partial sample is only left at the end of a truncated file.
+		n -= n % size
+		convert(p[:n], r.format.Encoding)
+	}
+	if r.remaining > 0 {
+		r.remaining -= int64(n)
+		if r.remaining == 0 && err == nil {
+			err = io.EOF
+		}
+	}
+	if err == io.EOF && r.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
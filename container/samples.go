@@ -0,0 +1,75 @@
+package container
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Decode appends the whole samples in p, given in the layout of the engine,
+// to dst as numbers ranging from -1 to 1 and returns the extended slice.
+// Samples of all channels stay interleaved.
+func Decode(dst []float64, p []byte, e Encoding) []float64 {
+	size := e.BytesPerSample()
+	if size == 0 {
+		return dst
+	}
+	for ; len(p) >= size; p = p[size:] {
+		var x float64
+		switch e {
+		case PCM8:
+			x = float64(int(p[0])-0x80) / (1 << 7)
+		case PCM16:
+			x = float64(int16(binary.LittleEndian.Uint16(p))) / (1 << 15)
+		case PCM24:
+			v := int32(p[0]) | int32(p[1])<<8 | int32(int8(p[2]))<<16
+			x = float64(v) / (1 << 23)
+		case Float32:
+			x = float64(math.Float32frombits(binary.LittleEndian.Uint32(p)))
+		case MuLaw:
+			x = float64(muLawDecode(p[0])) / (1 << 15)
+		case ALaw:
+			x = float64(aLawDecode(p[0])) / (1 << 15)
+		}
+		dst = append(dst, x)
+	}
+	return dst
+}
+
+// Encode appends samples ranging from -1 to 1 to dst in the layout of the
+// engine and returns the extended slice. Samples are rounded to the nearest
+// value of the encoding and clipped, except for [Float32].
+func Encode(dst []byte, samples []float64, e Encoding) []byte {
+	for _, x := range samples {
+		switch e {
+		case PCM8:
+			dst = append(dst, byte(quantize(x, 8)+0x80))
+		case PCM16:
+			dst = binary.LittleEndian.AppendUint16(dst, uint16(quantize(x, 16)))
+		case PCM24:
+			v := quantize(x, 24)
+			dst = append(dst, byte(v), byte(v>>8), byte(v>>16))
+		case Float32:
+			dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(float32(x)))
+		case MuLaw:
+			dst = append(dst, muLawEncode(int16(quantize(x, 16))))
+		case ALaw:
+			dst = append(dst, aLawEncode(int16(quantize(x, 16))))
+		}
+	}
+	return dst
+}
+
+// quantize rounds x to a signed integer of the given number of bits.
+func quantize(x float64, bits uint) int32 {
+	scale := float64(int32(1) << (bits - 1))
+	v := math.Round(x * scale)
+	switch {
+	case v >= scale:
+		return int32(scale) - 1
+	case v < -scale:
+		return -int32(scale)
+	case math.IsNaN(v):
+		return 0
+	}
+	return int32(v)
+}
@@ -0,0 +1,193 @@
+package container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Format tags of the WAV fmt chunk.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatALaw       = 6
+	wavFormatMuLaw      = 7
+	wavFormatExtensible = 0xfffe
+)
+
+func wavFormatTag(e Encoding) uint16 {
+	switch e {
+	case Float32:
+		return wavFormatFloat
+	case MuLaw:
+		return wavFormatMuLaw
+	case ALaw:
+		return wavFormatALaw
+	}
+	return wavFormatPCM
+}
+
+// wavHeader returns the RIFF headers up to the start of the samples.
+func wavHeader(f Format, dataLen int64) ([]byte, error) {
+	tag := wavFormatTag(f.Encoding)
+	frameSize := f.FrameSize()
+
+	// Formats other than PCM need the extended fmt chunk as well as a fact
+	// chunk holding the number of samples per channel.
+	fmtLen := 16
+	factLen := 0
+	if tag != wavFormatPCM {
+		fmtLen = 18
+		factLen = 8 + 4
+	}
+
+	riffLen, dataSize, frames := uint32(unknownSize), uint32(unknownSize), uint32(unknownSize)
+	if dataLen != UnknownLength {
+		padded := dataLen + dataLen%2
+		riffLen = uint32(4 + 8 + int64(fmtLen) + int64(factLen) + 8 + padded)
+		dataSize = uint32(dataLen)
+		frames = uint32(dataLen / int64(frameSize))
+	}
+
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'},
+		riffLen,
+		[4]byte{'W', 'A', 'V', 'E'},
+
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(fmtLen),
+		tag,
+		uint16(f.Channels),
+		uint32(f.SampleRate),
+		uint32(f.SampleRate * frameSize), // bytes per second
+		uint16(frameSize),                // block align
+		uint16(f.Encoding.BytesPerSample() * 8),
+	}
+	if tag != wavFormatPCM {
+		header = append(header,
+			uint16(0), // size of the fmt extension
+
+			[4]byte{'f', 'a', 'c', 't'},
+			uint32(4),
+			frames,
+		)
+	}
+	header = append(header,
+		[4]byte{'d', 'a', 't', 'a'},
+		dataSize,
+	)
+
+	var b bytes.Buffer
+	for _, field := range header {
+		// Writing to a bytes.Buffer does not fail.
+		_ = binary.Write(&b, binary.LittleEndian, field)
+	}
+	return b.Bytes(), nil
+}
+
+// readWAV reads the RIFF headers after the "RIFF" tag up to the start of the
+// samples.
+func readWAV(r io.Reader) (Format, int64, error) {
+	var riff struct {
+		Size uint32
+		Wave [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
+		return Format{}, 0, err
+	}
+	if riff.Wave != [4]byte{'W', 'A', 'V', 'E'} {
+		return Format{}, 0, fmt.Errorf("%w: RIFF file of type %q", ErrUnknownContainer, riff.Wave[:])
+	}
+
+	var f Format
+	for {
+		id, size, err := readChunkHeader(r, binary.LittleEndian)
+		if err != nil {
+			return Format{}, 0, err
+		}
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return Format{}, 0, fmt.Errorf("%w: fmt chunk of %d bytes", ErrUnsupportedFormat, size)
+			}
+			chunk := make([]byte, size)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return Format{}, 0, err
+			}
+			if f, err = parseWAVFormat(chunk); err != nil {
+				return Format{}, 0, err
+			}
+		case "data":
+			if f.Encoding == 0 {
+				return Format{}, 0, fmt.Errorf("%w: data before fmt chunk", ErrUnsupportedFormat)
+			}
+			if size == unknownSize {
+				return f, UnknownLength, nil
+			}
+			return f, int64(size), nil
+		default:
+			if err := skip(r, int64(size)+int64(size%2)); err != nil {
+				return Format{}, 0, err
+			}
+		}
+	}
+}
+
+func parseWAVFormat(chunk []byte) (Format, error) {
+	le := binary.LittleEndian
+	tag := le.Uint16(chunk[0:])
+	f := Format{
+		Channels:   int(le.Uint16(chunk[2:])),
+		SampleRate: int(le.Uint32(chunk[4:])),
+	}
+	bits := le.Uint16(chunk[14:])
+	// The extensible format stores the tag in the first two bytes of the
+	// sub-format GUID.
+	if tag == wavFormatExtensible && len(chunk) >= 26 {
+		tag = le.Uint16(chunk[24:])
+	}
+
+	switch {
+	case tag == wavFormatPCM && bits == 8:
+		f.Encoding = PCM8
+	case tag == wavFormatPCM && bits == 16:
+		f.Encoding = PCM16
+	case tag == wavFormatPCM && bits == 24:
+		f.Encoding = PCM24
+	case tag == wavFormatFloat && bits == 32:
+		f.Encoding = Float32
+	case tag == wavFormatMuLaw && bits == 8:
+		f.Encoding = MuLaw
+	case tag == wavFormatALaw && bits == 8:
+		f.Encoding = ALaw
+	default:
+		return Format{}, fmt.Errorf("%w: WAV format tag %d with %d bits", ErrUnsupportedFormat, tag, bits)
+	}
+	if !f.Valid() {
+		return Format{}, fmt.Errorf("%w: %v", ErrUnsupportedFormat, f)
+	}
+	return f, nil
+}
+
+func readChunkHeader(r io.Reader, order binary.ByteOrder) (string, uint32, error) {
+	var chunk struct {
+		ID   [4]byte
+		Size uint32
+	}
+	if err := binary.Read(r, order, &chunk); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", 0, err
+	}
+	return string(chunk.ID[:]), chunk.Size, nil
+}
+
+func skip(r io.Reader, n int64) error {
+	_, err := io.CopyN(io.Discard, r, n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
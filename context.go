@@ -0,0 +1,67 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+import "context"
+
+// withContext runs f, which may block inside the engine, until it returns or
+// ctx is done. In the latter case the queued text is discarded, which
+// interrupts f, and ctx.Err() is returned right away. Once f has returned,
+// the text-to-speech system is reset fully in the background, returning to
+// the startup state; calls made in the meantime wait for that.
+func (t *TTS) withContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Neither discarding the text nor returning waits for the state
+		// lock or the worker thread, both of which f may hold.
+		settled := make(chan struct{})
+		if t.interrupt() == nil && t.state.resetting.CompareAndSwap(nil, &settled) {
+			go t.resetAfter(done, settled)
+		}
+		return ctx.Err()
+	}
+}
+
+// resetAfter resets the text-to-speech system fully once f of withContext has
+// returned and then releases the calls waiting for it.
+func (t *TTS) resetAfter(done <-chan error, settled chan struct{}) {
+	defer func() {
+		t.state.resetting.Store(nil)
+		close(settled)
+	}()
+	<-done
+	if t.state.lockExclusive("Reset") != nil {
+		return
+	}
+	defer t.state.leaveExclusive()
+	_ = t.resetFully()
+}
+
+// SpeakContext is like [TTS.Speak], but discards the queued text and returns
+// ctx.Err() as soon as ctx is done. The text-to-speech system is then reset
+// fully, as by [TTS.Reset], before the next call proceeds.
+func (t *TTS) SpeakContext(ctx context.Context, text string, flags TTSFlags) error {
+	return t.withContext(ctx, func() error {
+		return t.Speak(text, flags)
+	})
+}
+
+// SyncContext is like [TTS.Sync], but gives up waiting once ctx is done. All
+// queued text is then flushed and ctx.Err() is returned right away. The
+// text-to-speech system is reset fully, as by [TTS.Reset], before the next
+// call proceeds.
+func (t *TTS) SyncContext(ctx context.Context) error {
+	return t.withContext(ctx, t.Sync)
+}
@@ -1,7 +1,14 @@
 package dectalkdapi_test
 
 import (
+	"context"
+	"errors"
 	"github.com/icedream/go-dectalkdapi"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -103,3 +110,443 @@ func TestGetStatusMultipleIdentifiers(t *testing.T) {
 		t.Logf("Status %d: Identifier=%v, Value=%d", i, s.Identifier, s.Value)
 	}
 }
+
+func TestGetCaps(t *testing.T) {
+	caps, err := dectalkdapi.GetCaps()
+	if err != nil {
+		t.Fatalf("GetCaps() failed: %v", err)
+	}
+
+	if caps.SampleRate == 0 {
+		t.Error("SampleRate should be > 0")
+	}
+	if caps.MinimumSpeakingRate > caps.MaximumSpeakingRate {
+		t.Errorf("MinimumSpeakingRate %d should not exceed MaximumSpeakingRate %d",
+			caps.MinimumSpeakingRate, caps.MaximumSpeakingRate)
+	}
+
+	t.Logf("Capabilities: %+v", caps)
+}
+
+func TestFeaturesString(t *testing.T) {
+	tests := []struct {
+		features dectalkdapi.Features
+		expected string
+	}{
+		{0, "0"},
+		{dectalkdapi.FeatureInMemory, "InMemory"},
+		{dectalkdapi.FeatureMultiLanguage | dectalkdapi.FeatureSAPI5Audio, "MultiLanguage|SAPI5Audio"},
+		{dectalkdapi.FeatureLogFile | 1<<31, "LogFile|0x80000000"},
+	}
+
+	for _, test := range tests {
+		if s := test.features.String(); s != test.expected {
+			t.Errorf("Features(%d).String() = %q, expected %q", uint32(test.features), s, test.expected)
+		}
+	}
+}
+
+func TestGetFeatures(t *testing.T) {
+	features := dectalkdapi.GetFeatures()
+	t.Logf("Features: %v", features)
+
+	// Every build of the engine writes wave files in its native format.
+	if !features.Has(dectalkdapi.FeatureWaveOutFile) {
+		t.Errorf("Expected %v to include WaveOutFile", features)
+	}
+	if !features.SupportsWaveFormat(dectalkdapi.WaveFormat1M16) {
+		t.Errorf("Expected %v to support WaveFormat1M16", features)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	tts, err := dectalkdapi.Startup(dectalkdapi.DoNotUseAudioDevice | dectalkdapi.ReportOpenError)
+	if err != nil {
+		t.Fatalf("Startup() failed: %v", err)
+	}
+
+	if err := tts.OpenInMemory(dectalkdapi.WaveFormat1M16); err != nil {
+		t.Fatalf("OpenInMemory() failed: %v", err)
+	}
+	if mode := tts.Mode(); mode != dectalkdapi.ModeInMemory {
+		t.Errorf("Expected mode %v, got %v", dectalkdapi.ModeInMemory, mode)
+	}
+	if err := tts.OpenWaveOutFile("test.wav", dectalkdapi.WaveFormat1M16); !errors.Is(err, dectalkdapi.ErrWrongMode) {
+		t.Errorf("OpenWaveOutFile() in speech-to-memory mode should fail with ErrWrongMode, got %v", err)
+	}
+
+	if err := tts.Speak("Hello.", dectalkdapi.Force); err != nil {
+		t.Fatalf("Speak() failed: %v", err)
+	}
+	if err := tts.CloseInMemory(); !errors.Is(err, dectalkdapi.ErrResetRequired) {
+		t.Errorf("CloseInMemory() before Reset() should fail with ErrResetRequired, got %v", err)
+	}
+	if err := tts.Reset(false); err != nil {
+		t.Fatalf("Reset() failed: %v", err)
+	}
+	if err := tts.CloseInMemory(); err != nil {
+		t.Fatalf("CloseInMemory() failed: %v", err)
+	}
+
+	if err := tts.OpenLogFile(filepath.Join(t.TempDir(), "test.log"), dectalkdapi.Text); err != nil {
+		t.Fatalf("OpenLogFile() failed: %v", err)
+	}
+	// Shutdown has to close the log file by itself.
+	if err := tts.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() failed: %v", err)
+	}
+	if err := tts.Speak("Hello.", dectalkdapi.Force); !errors.Is(err, dectalkdapi.ErrShutdown) {
+		t.Errorf("Speak() after Shutdown() should fail with ErrShutdown, got %v", err)
+	}
+	if err := tts.Shutdown(); !errors.Is(err, dectalkdapi.ErrShutdown) {
+		t.Errorf("Shutdown() twice should fail with ErrShutdown, got %v", err)
+	}
+}
+
+func TestStartupLocked(t *testing.T) {
+	tts, err := dectalkdapi.StartupLocked(dectalkdapi.DoNotUseAudioDevice|dectalkdapi.ReportOpenError, nil)
+	if err != nil {
+		t.Fatalf("StartupLocked() failed: %v", err)
+	}
+	defer tts.Shutdown()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(rate uint32) {
+			defer wg.Done()
+			if err := tts.SetRate(rate); err != nil {
+				t.Errorf("SetRate() failed: %v", err)
+			}
+			if _, err := tts.GetRate(); err != nil {
+				t.Errorf("GetRate() failed: %v", err)
+			}
+			if err := tts.Speak("Hello.", dectalkdapi.Force); err != nil {
+				t.Errorf("Speak() failed: %v", err)
+			}
+		}(uint32(100 + i*10))
+	}
+	wg.Wait()
+
+	if err := tts.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+}
+
+func TestStartupLanguage(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	lang, err := dectalkdapi.StartLang("us")
+	if err != nil {
+		t.Skipf("StartLang() failed: %v", err)
+	}
+	defer lang.Close()
+	if !dectalkdapi.SelectLang(lang) {
+		t.Fatal("SelectLang() failed")
+	}
+
+	tts, err := dectalkdapi.Startup(dectalkdapi.DoNotUseAudioDevice | dectalkdapi.ReportOpenError)
+	if err != nil {
+		t.Fatalf("Startup() failed: %v", err)
+	}
+	defer tts.Shutdown()
+
+	if tts.Language() != lang {
+		t.Errorf("Language() = %v, expected the selected language", tts.Language())
+	}
+	if e := tts.Encoder(); e.Language != "us" {
+		t.Errorf("Encoder().Language = %q, expected \"us\"", e.Language)
+	}
+}
+
+func TestPool(t *testing.T) {
+	pool, err := dectalkdapi.NewPool(dectalkdapi.PoolConfig{
+		Size:     2,
+		FailFast: true,
+	})
+	if err != nil {
+		t.Fatalf("NewPool() failed: %v", err)
+	}
+	defer pool.Close()
+
+	if pool.Size() == 0 {
+		t.Fatal("Size should be > 0")
+	}
+
+	ctx := context.Background()
+	tts, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	rate, err := tts.GetRate()
+	if err != nil {
+		t.Fatalf("GetRate() failed: %v", err)
+	}
+	if err := tts.SetRate(rate + 50); err != nil {
+		t.Fatalf("SetRate() failed: %v", err)
+	}
+	if err := pool.Put(tts); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	if err := pool.Put(tts); !errors.Is(err, dectalkdapi.ErrNotFromPool) {
+		t.Errorf("Put() of an idle instance should fail with ErrNotFromPool, got %v", err)
+	}
+
+	// Exhaust the pool, the rate must have been restored for every instance.
+	var taken []*dectalkdapi.TTS
+	for i := 0; i < pool.Size(); i++ {
+		tts, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if r, _ := tts.GetRate(); r != rate {
+			t.Errorf("Expected rate %d after Put(), got %d", rate, r)
+		}
+		taken = append(taken, tts)
+	}
+	if _, err := pool.Get(ctx); !errors.Is(err, dectalkdapi.ErrPoolExhausted) {
+		t.Errorf("Get() on exhausted pool should fail with ErrPoolExhausted, got %v", err)
+	}
+	for _, tts := range taken {
+		if err := pool.Put(tts); err != nil {
+			t.Errorf("Put() failed: %v", err)
+		}
+	}
+}
+
+func TestSyncContext(t *testing.T) {
+	tts, err := dectalkdapi.Startup(dectalkdapi.DoNotUseAudioDevice | dectalkdapi.ReportOpenError)
+	if err != nil {
+		t.Fatalf("Startup() failed: %v", err)
+	}
+	defer tts.Shutdown()
+
+	if err := tts.SpeakContext(context.Background(), "[:tone 440 1000]", dectalkdapi.Force); err != nil {
+		t.Fatalf("SpeakContext() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tts.SyncContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("SyncContext() with cancelled context should fail with context.Canceled, got %v", err)
+	}
+	if _, err := tts.SynthesizeContext(ctx, "Hello."); !errors.Is(err, context.Canceled) {
+		t.Errorf("SynthesizeContext() with cancelled context should fail with context.Canceled, got %v", err)
+	}
+	name := filepath.Join(t.TempDir(), "hello.wav")
+	if err := tts.SynthesizeFile(ctx, name, "Hello."); !errors.Is(err, context.Canceled) {
+		t.Errorf("SynthesizeFile() with cancelled context should fail with context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("SynthesizeFile() should remove the file when it fails, got %v", err)
+	}
+	if mode := tts.Mode(); mode != dectalkdapi.ModeStartup {
+		t.Errorf("Expected mode %v after cancellation, got %v", dectalkdapi.ModeStartup, mode)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	tests := []struct {
+		lang   string
+		policy dectalkdapi.Unrepresentable
+		text   string
+		want   string
+	}{
+		{"sp", dectalkdapi.Transliterate, "¿Qué año?", "\xbfQu\xe9 a\xf1o?"},
+		{"gr", dectalkdapi.Transliterate, "Grüße", "Gr\xfc\xdfe"},
+		{"fr", dectalkdapi.Transliterate, "café “Œuvre”", "caf\xe9 \"OEuvre\""},
+		{"us", dectalkdapi.Transliterate, "Łódź", "L\xf3dz"},
+		{"us", dectalkdapi.Transliterate, "5€", "5 euro "},
+		{"gr", dectalkdapi.SpellOut, "5€", "5 Euro "},
+		{"gr", dectalkdapi.SpellOut, "x≥1", "x gr\xf6\xdfer gleich 1"},
+		{"us", dectalkdapi.SpellOut, "中", " U+4E2D "},
+		{"us", dectalkdapi.Drop, "a中b€", "ab"},
+		{"fr", dectalkdapi.Transliterate, "cafe\u0301", "caf\xe9"},
+	}
+	for _, test := range tests {
+		encoder := dectalkdapi.Encoder{Language: test.lang, Unrepresentable: test.policy}
+		got, err := encoder.Encode(test.text)
+		if err != nil {
+			t.Errorf("Encode(%q) failed: %v", test.text, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("Encode(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+
+	encoder := dectalkdapi.Encoder{Language: "us", Unrepresentable: dectalkdapi.Reject}
+	_, err := encoder.Encode("ab中")
+	var encodingErr *dectalkdapi.EncodingError
+	if !errors.As(err, &encodingErr) || encodingErr.Offset != 2 || encodingErr.Rune != '中' {
+		t.Errorf("Expected an EncodingError at offset 2, got %v", err)
+	}
+	if !errors.Is(err, dectalkdapi.ErrUnrepresentable) {
+		t.Errorf("Expected ErrUnrepresentable, got %v", err)
+	}
+
+	// White space is typed like any other letter, spelled out names are not.
+	typing := dectalkdapi.Encoder{Language: "us", Unrepresentable: dectalkdapi.Transliterate}
+	for r, want := range map[rune]byte{' ': ' ', '\t': '\t', '\n': '\n', 'a': 'a', 'é': 0xe9, 'Ł': 'L'} {
+		if got, ok := dectalkdapi.TypingCharacter(typing, r); !ok || got != want {
+			t.Errorf("TypingCharacter(%q) = %q, %v, want %q", r, got, ok, want)
+		}
+	}
+	if got, ok := dectalkdapi.TypingCharacter(typing, '€'); ok {
+		t.Errorf("TypingCharacter('€') = %q, want nothing", got)
+	}
+}
+
+func TestParsePhonemes(t *testing.T) {
+	phonemes := dectalkdapi.ParsePhonemes("[hx'eh-low w`er-ld.]")
+	want := []dectalkdapi.Phoneme{
+		{Symbol: "hx", Word: 0, Syllable: 0},
+		{Symbol: "eh", Stress: dectalkdapi.PrimaryStress, Word: 0, Syllable: 0},
+		{Symbol: "l", Word: 0, Syllable: 1},
+		{Symbol: "ow", Word: 0, Syllable: 1},
+		{Symbol: "w", Word: 1, Syllable: 0},
+		{Symbol: "er", Stress: dectalkdapi.SecondaryStress, Word: 1, Syllable: 0},
+		{Symbol: "l", Word: 1, Syllable: 1},
+		{Symbol: "d", Word: 1, Syllable: 1},
+	}
+	if len(phonemes) != len(want) {
+		t.Fatalf("Expected %d phonemes, got %d: %v", len(want), len(phonemes), phonemes)
+	}
+	for i := range want {
+		if phonemes[i] != want[i] {
+			t.Errorf("Phoneme %d: expected %+v, got %+v", i, want[i], phonemes[i])
+		}
+	}
+
+	syllables := dectalkdapi.GroupSyllables(phonemes)
+	if len(syllables) != 4 {
+		t.Fatalf("Expected 4 syllables, got %d: %v", len(syllables), syllables)
+	}
+	if s := syllables[0].String(); s != "hx'eh" {
+		t.Errorf("Expected first syllable %q, got %q", "hx'eh", s)
+	}
+	if syllables[0].Stress != dectalkdapi.PrimaryStress || syllables[3].Word != 1 {
+		t.Errorf("Unexpected syllables: %+v", syllables)
+	}
+
+	// A syllable with secondary and primary stress is primarily stressed.
+	syllables = dectalkdapi.GroupSyllables(dectalkdapi.ParsePhonemes("`ah'ay"))
+	if len(syllables) != 1 || syllables[0].Stress != dectalkdapi.PrimaryStress {
+		t.Errorf("Expected one syllable with primary stress, got %+v", syllables)
+	}
+
+	// Letters without separators split into valid symbols.
+	tests := map[string]string{
+		"yuw":   "y uw",
+		"aar":   "aa r",
+		"dhax":  "dh ax",
+		"werld": "w er l d",
+	}
+	for log, want := range tests {
+		var symbols []string
+		for _, p := range dectalkdapi.ParsePhonemes(log) {
+			symbols = append(symbols, p.Symbol)
+		}
+		if got := strings.Join(symbols, " "); got != want {
+			t.Errorf("ParsePhonemes(%q): expected %q, got %q", log, want, got)
+		}
+	}
+}
+
+func TestPhonemize(t *testing.T) {
+	tts, err := dectalkdapi.Startup(dectalkdapi.DoNotUseAudioDevice | dectalkdapi.ReportOpenError)
+	if err != nil {
+		t.Fatalf("Startup() failed: %v", err)
+	}
+	defer tts.Shutdown()
+
+	phonemes, err := tts.Phonemize("Hello world.")
+	if err != nil {
+		t.Fatalf("Phonemize() failed: %v", err)
+	}
+	if len(phonemes) == 0 {
+		t.Fatal("Expected phonemes")
+	}
+	if last := phonemes[len(phonemes)-1]; last.Word != 1 {
+		t.Errorf("Expected last phoneme in word 1, got %+v", last)
+	}
+	if mode := tts.Mode(); mode != dectalkdapi.ModeStartup {
+		t.Errorf("Expected mode %v after Phonemize(), got %v", dectalkdapi.ModeStartup, mode)
+	}
+
+	t.Logf("Phonemes: %v", phonemes)
+}
+
+// copyCompiler stands in for the dictionary tools of DECtalk by copying the
+// source.
+func copyCompiler(source, dictFile string) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dictFile, data, 0o644)
+}
+
+func TestDictionaryManager(t *testing.T) {
+	// A compiled dictionary needs no compiler and is published as it is.
+	compiled := filepath.Join(t.TempDir(), "user.dic")
+	if err := os.WriteFile(compiled, []byte("compiled"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := dectalkdapi.NewDictionaryManager(dectalkdapi.DictionaryManagerConfig{Source: compiled})
+	if err != nil {
+		t.Fatalf("NewDictionaryManager() failed for a compiled source: %v", err)
+	}
+	if data, err := os.ReadFile(m.Active().File); err != nil || string(data) != "compiled" {
+		t.Errorf("Expected a copy of the compiled source, got %q: %v", data, err)
+	}
+	m.Close()
+
+	source := filepath.Join(t.TempDir(), "lexicon.txt")
+	if err := os.WriteFile(source, []byte("tomato taxm'eytow\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dectalkdapi.NewDictionaryManager(dectalkdapi.DictionaryManagerConfig{Source: source}); !errors.Is(err, dectalkdapi.ErrNoDictionaryCompiler) {
+		t.Errorf("NewDictionaryManager() without a compiler should fail with ErrNoDictionaryCompiler, got %v", err)
+	}
+	config := dectalkdapi.DictionaryManagerConfig{Source: source, Compiler: copyCompiler}
+	m, err = dectalkdapi.NewDictionaryManager(config)
+	if err != nil {
+		t.Fatalf("NewDictionaryManager() failed: %v", err)
+	}
+	defer m.Close()
+	if _, err := dectalkdapi.NewDictionaryManager(config); !errors.Is(err, dectalkdapi.ErrDictionaryManagerRunning) {
+		t.Errorf("Second NewDictionaryManager() should fail with ErrDictionaryManagerRunning, got %v", err)
+	}
+
+	tts, err := dectalkdapi.Startup(dectalkdapi.DoNotUseAudioDevice | dectalkdapi.ReportOpenError)
+	if err != nil {
+		t.Fatalf("Startup() failed: %v", err)
+	}
+	defer tts.Shutdown()
+
+	if err := tts.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	active := m.Active()
+	if active.Entries != 1 || tts.UserDictionary() != active.File {
+		t.Errorf("Expected %+v to be loaded, got %q", active, tts.UserDictionary())
+	}
+
+	if err := os.WriteFile(source, []byte("tomato taxm'aatow\npotato paxt'eytow\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if err := tts.Speak("tomato", dectalkdapi.Force); err != nil {
+		t.Fatalf("Speak() failed: %v", err)
+	}
+	if err := tts.Sync(); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if next := m.Active(); next.Version <= active.Version || next.Entries != 2 || tts.UserDictionary() != next.File {
+		t.Errorf("Expected %+v to be loaded, got %q", next, tts.UserDictionary())
+	}
+}
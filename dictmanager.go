@@ -0,0 +1,316 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/icedream/go-dectalkdapi/userdict"
+)
+
+// DictionaryManagerConfig configures a [DictionaryManager].
+type DictionaryManagerConfig struct {
+	// Source is the path of the dictionary source, either a dictionary
+	// compiled by the DECtalk tools with the extension ".dic", which is
+	// loaded as it is, or any format accepted by userdict.ParseFile, such
+	// as a text or YAML lexicon.
+	Source string
+
+	// Compiler compiles a Source that is not compiled yet into the format
+	// loaded by the engine, usually by running a dictionary tool of DECtalk
+	// with userdict.Command. It is only needed for such sources.
+	Compiler userdict.Compiler
+
+	// Interval is how often Source is checked for changes. The default is 2
+	// seconds.
+	Interval time.Duration
+
+	// OnError, if not nil, is called for errors while reloading Source in
+	// the background and while loading the dictionary into an instance.
+	OnError func(error)
+}
+
+// ActiveDictionary describes the dictionary published by a
+// [DictionaryManager].
+type ActiveDictionary struct {
+	// Source is the path of the dictionary source.
+	Source string
+
+	// File is the path of the compiled dictionary that is loaded into the
+	// instances, as reported by [TTS.UserDictionary].
+	File string
+
+	// Version is increased every time a dictionary is published.
+	Version uint64
+
+	// Entries is the number of entries of the dictionary, or 0 for a
+	// compiled Source.
+	Entries int
+
+	// ModTime is the modification time of Source when it was compiled.
+	ModTime time.Time
+}
+
+// DictionaryManager compiles a user dictionary from a source file and
+// recompiles it whenever the file changes. Every [TTS] of the process switches
+// to the latest dictionary at the next safe point between utterances: when
+// [TTS.Sync] returns, or when [TTS.Speak] is called while no text is queued
+// and nothing is being spoken. Instances started later pick it up the same
+// way.
+//
+// Only one manager can run at a time, since each instance can only have a
+// single user dictionary loaded. While it runs, [PoolConfig.UserDictionary] is
+// ignored.
+type DictionaryManager struct {
+	config DictionaryManagerConfig
+
+	stop chan struct{}
+	done chan struct{}
+
+	// mu serializes reloads and protects the fields below.
+	mu      sync.Mutex
+	closed  bool
+	modTime time.Time
+	size    int64
+
+	// files holds the compiled dictionaries that may still be loaded by an
+	// instance, the newest last.
+	files []string
+}
+
+// managedDictionary is the dictionary published by the running
+// DictionaryManager.
+type managedDictionary struct {
+	ActiveDictionary
+	onError func(error)
+}
+
+var (
+	managed            atomic.Pointer[managedDictionary]
+	managerRunning     atomic.Bool
+	dictionaryVersions atomic.Uint64
+)
+
+// NewDictionaryManager compiles config.Source and starts watching it for
+// changes. It fails with [ErrDictionaryManagerRunning] if another manager has
+// not been closed yet. Lexemes of a PLS lexicon that can not be converted are
+// reported to config.OnError.
+func NewDictionaryManager(config DictionaryManagerConfig) (*DictionaryManager, error) {
+	if !managerRunning.CompareAndSwap(false, true) {
+		return nil, ErrDictionaryManagerRunning
+	}
+	if config.Interval <= 0 {
+		config.Interval = 2 * time.Second
+	}
+
+	m := &DictionaryManager{
+		config: config,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	var unmapped *userdict.UnmappedError
+	if err := m.Reload(); errors.As(err, &unmapped) {
+		m.report(err)
+	} else if err != nil {
+		managerRunning.Store(false)
+		return nil, err
+	}
+	go m.watch()
+	return m, nil
+}
+
+func (m *DictionaryManager) watch() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			err := m.reloadIfChanged()
+			if err != nil && !errors.Is(err, ErrDictionaryManagerClosed) {
+				m.report(err)
+			}
+		}
+	}
+}
+
+func (m *DictionaryManager) report(err error) {
+	if m.config.OnError != nil {
+		m.config.OnError(err)
+	}
+}
+
+func (m *DictionaryManager) reloadIfChanged() error {
+	info, err := os.Stat(m.config.Source)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	changed := !info.ModTime().Equal(m.modTime) || info.Size() != m.size
+	m.mu.Unlock()
+	if !changed {
+		return nil
+	}
+	return m.Reload()
+}
+
+// Reload compiles the source right away and publishes the result, even if the
+// source has not changed. If the source fails to compile, the previous
+// dictionary stays active. If lexemes of a PLS lexicon can not be converted,
+// the others are published and a *userdict.UnmappedError is returned.
+func (m *DictionaryManager) Reload() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrDictionaryManagerClosed
+	}
+
+	info, err := os.Stat(m.config.Source)
+	if err != nil {
+		return err
+	}
+	var d *userdict.Dictionary
+	var unmapped *userdict.UnmappedError
+	compiled := strings.EqualFold(filepath.Ext(m.config.Source), ".dic")
+	if !compiled {
+		if m.config.Compiler == nil {
+			return fmt.Errorf("%s: %w", m.config.Source, ErrNoDictionaryCompiler)
+		}
+		d, err = userdict.ParseFile(m.config.Source)
+		if err != nil && !errors.As(err, &unmapped) {
+			return fmt.Errorf("%s: %w", m.config.Source, err)
+		}
+	}
+
+	// Even a compiled source is copied, so that instances loading it later
+	// do not see it change underneath them.
+	f, err := os.CreateTemp("", "dectalk-*.dic")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	if compiled {
+		err = copyFile(f, m.config.Source)
+	} else if err = f.Close(); err == nil {
+		err = d.Compile(m.config.Compiler, name)
+	}
+	if err != nil {
+		os.Remove(name)
+		return err
+	}
+	entries := 0
+	if d != nil {
+		entries = d.Len()
+	}
+
+	m.modTime, m.size = info.ModTime(), info.Size()
+	m.files = append(m.files, name)
+	// The previous file is kept for instances that are about to load it.
+	for len(m.files) > 2 {
+		os.Remove(m.files[0])
+		m.files = m.files[1:]
+	}
+	managed.Store(&managedDictionary{
+		ActiveDictionary: ActiveDictionary{
+			Source:  m.config.Source,
+			File:    name,
+			Version: dictionaryVersions.Add(1),
+			Entries: entries,
+			ModTime: info.ModTime(),
+		},
+		onError: m.config.OnError,
+	})
+	if unmapped != nil {
+		return fmt.Errorf("%s: %w", m.config.Source, unmapped)
+	}
+	return nil
+}
+
+// copyFile copies the file source to f and closes f.
+func copyFile(f *os.File, source string) error {
+	src, err := os.Open(source)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(f, src)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Active returns the dictionary that instances switch to.
+func (m *DictionaryManager) Active() ActiveDictionary {
+	if d := managed.Load(); d != nil {
+		return d.ActiveDictionary
+	}
+	return ActiveDictionary{}
+}
+
+// Close stops watching the source. Instances keep the dictionary they have
+// loaded, but no longer switch to a new one.
+func (m *DictionaryManager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrDictionaryManagerClosed
+	}
+	m.closed = true
+	managed.Store(nil)
+	m.mu.Unlock()
+
+	close(m.stop)
+	<-m.done
+	for _, name := range m.files {
+		os.Remove(name)
+	}
+	m.files = nil
+	managerRunning.Store(false)
+	return nil
+}
+
+// dictionaryPending reports whether the instance has not switched to the
+// dictionary published by the running DictionaryManager yet.
+func (t *TTS) dictionaryPending() bool {
+	d := managed.Load()
+	return d != nil && t.dictionaryVersion.Load() != d.Version
+}
+
+// applyManagedDictionary switches to the dictionary published by the running
+// DictionaryManager, if it has not been loaded yet. It must only be called
+// between state.enter and state.leave while no text is queued.
+func (t *TTS) applyManagedDictionary() {
+	d := managed.Load()
+	if d == nil {
+		return
+	}
+	if t.dictionaryVersion.Load() == d.Version {
+		return
+	}
+
+	// A dictionary loaded automatically at startup is not tracked, so
+	// unloading is always attempted and fails if there is none.
+	_ = t.unloadUserDictionary()
+	if err := t.loadUserDictionary(d.File); err != nil {
+		// The version is left as it is, so that loading is retried at
+		// the next safe point.
+		if d.onError != nil {
+			d.onError(fmt.Errorf("loading user dictionary %s: %w", d.File, err))
+		}
+		return
+	}
+	t.dictionaryVersion.Store(d.Version)
+}
@@ -0,0 +1,109 @@
+// Package dsp post-processes the speech samples produced by the engine before
+// they are encoded: gain, peak and EBU R128 loudness normalization, trimming
+// of leading and trailing silence, fades, a DC offset filter and a limiter.
+//
+// The voices of the engine differ in perceived loudness, even at the same
+// [:volume]. A [Chain] runs stages over a whole utterance, so a set of prompts
+// comes out equally loud:
+//
+//	c := dsp.New(
+//		dsp.DCFilter(),
+//		dsp.TrimSilence(-50, 50*time.Millisecond),
+//		dsp.LoudnessNormalize(-16),
+//		dsp.Limiter(-1, 50*time.Millisecond),
+//		dsp.FadeOut(10*time.Millisecond),
+//	)
+//	tts.SetProcessor(c)
+//
+// Use [dectalkdapi.TTS.SetProcessor] to run a chain on everything an instance
+// synthesizes, or a [Writer] in front of any other writer. Like the container
+// package, samples are passed to a writer in the layout of the engine:
+// little-endian, with 8-bit linear samples unsigned.
+package dsp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/icedream/go-dectalkdapi/container"
+)
+
+// Stage processes the mono samples of a whole utterance at a sample rate,
+// ranging from -1 to 1, and returns the processed samples. It may modify
+// samples in place and return fewer of them.
+type Stage func(samples []float64, rate int) []float64
+
+// Chain runs stages in order. It is safe for concurrent use.
+type Chain struct {
+	stages []Stage
+}
+
+// New returns a chain running the given stages in order.
+func New(stages ...Stage) *Chain {
+	return &Chain{stages: append([]Stage(nil), stages...)}
+}
+
+// Process runs all stages on samples at a sample rate and returns the
+// processed samples, modifying samples in place.
+func (c *Chain) Process(samples []float64, rate int) []float64 {
+	for _, s := range c.stages {
+		samples = s(samples, rate)
+	}
+	return samples
+}
+
+// ProcessBytes runs all stages on mono samples in the layout of the engine
+// and returns them in the same format.
+func (c *Chain) ProcessBytes(p []byte, format container.Format) ([]byte, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+	samples := c.Process(container.Decode(nil, p, format.Encoding), format.SampleRate)
+	return container.Encode(nil, samples, format.Encoding), nil
+}
+
+func checkFormat(format container.Format) error {
+	if !format.Valid() || format.Channels != 1 {
+		return fmt.Errorf("%w: %v", container.ErrUnsupportedFormat, format)
+	}
+	return nil
+}
+
+// Writer collects the samples of an utterance and writes them processed by a
+// chain to an underlying writer once it is closed.
+type Writer struct {
+	w       io.Writer
+	format  container.Format
+	chain   *Chain
+	samples bytes.Buffer
+}
+
+// NewWriter returns a writer processing mono samples in a format with a chain
+// and writing them in the same format to w.
+func NewWriter(w io.Writer, format container.Format, c *Chain) (*Writer, error) {
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, format: format, chain: c}, nil
+}
+
+// Write collects samples in the layout of the engine.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.samples.Write(p)
+}
+
+// Close processes the samples written and writes them. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.samples.Len()%w.format.FrameSize() != 0 {
+		return container.ErrPartialSample
+	}
+	p, err := w.chain.ProcessBytes(w.samples.Bytes(), w.format)
+	w.samples.Reset()
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(p)
+	return err
+}
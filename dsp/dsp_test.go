@@ -0,0 +1,159 @@
+package dsp_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/icedream/go-dectalkdapi/container"
+	"github.com/icedream/go-dectalkdapi/dsp"
+	"math"
+	"testing"
+	"time"
+)
+
+// sine returns a sine wave of a frequency and amplitude lasting d.
+func sine(freq, amplitude float64, d time.Duration, rate int) []float64 {
+	samples := make([]float64, int(d.Seconds()*float64(rate)))
+	for i := range samples {
+		samples[i] = amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+	}
+	return samples
+}
+
+func TestLoudness(t *testing.T) {
+	// A 1 kHz sine wave at full scale reads -3.01 LUFS in mono.
+	tests := []struct {
+		rate      int
+		tolerance float64
+	}{
+		{48000, 0.05},
+		{11025, 0.2},
+		{8000, 0.3},
+	}
+	for _, test := range tests {
+		got := dsp.Loudness(sine(1000, 1, 2*time.Second, test.rate), test.rate)
+		if math.Abs(got+3.01) > test.tolerance {
+			t.Errorf("Loudness() at %d Hz: got %.2f LUFS, want -3.01", test.rate, got)
+		}
+	}
+
+	// Silence before the tone is gated out, apart from blocks covering
+	// both. Without gating, it would read -27.8 LUFS.
+	samples := append(make([]float64, 48000*4), sine(1000, 0.1, 2*time.Second, 48000)...)
+	if got := dsp.Loudness(samples, 48000); math.Abs(got+23.01) > 0.5 {
+		t.Errorf("Loudness() with silence: got %.2f LUFS, want -23.01", got)
+	}
+	if got := dsp.Loudness(make([]float64, 100), 48000); !math.IsInf(got, -1) {
+		t.Errorf("Loudness() of silence: got %.2f LUFS, want -Inf", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	quiet := sine(440, 0.01, time.Second, 11025)
+	loud := sine(2000, 0.9, 300*time.Millisecond, 11025)
+	c := dsp.New(dsp.LoudnessNormalize(-16))
+	for _, samples := range [][]float64{quiet, loud} {
+		if got := dsp.Loudness(c.Process(samples, 11025), 11025); math.Abs(got+16) > 0.01 {
+			t.Errorf("got %.2f LUFS after normalizing, want -16", got)
+		}
+	}
+
+	samples := dsp.New(dsp.PeakNormalize(-1)).Process(sine(440, 0.1, time.Second, 11025), 11025)
+	if got := dsp.Peak(samples); math.Abs(got+1) > 0.01 {
+		t.Errorf("got a peak of %.2f dBFS after normalizing, want -1", got)
+	}
+	samples = dsp.New(dsp.Gain(-6)).Process([]float64{0.5}, 11025)
+	if want := 0.5 * math.Pow(10, -6.0/20); math.Abs(samples[0]-want) > 1e-12 {
+		t.Errorf("Gain(-6): got %v, want %v", samples[0], want)
+	}
+}
+
+func TestTrimSilence(t *testing.T) {
+	tone := sine(440, 0.5, 100*time.Millisecond, 1000)
+	samples := append(append(make([]float64, 500), tone...), make([]float64, 300)...)
+	got := dsp.New(dsp.TrimSilence(-50, 10*time.Millisecond)).Process(samples, 1000)
+	// The first and last sample of the tone are at a zero crossing.
+	if len(got) < len(tone)+18 || len(got) > len(tone)+20 {
+		t.Errorf("got %d samples, want the %d of the tone and 10 on either side", len(got), len(tone))
+	}
+	if got := dsp.New(dsp.TrimSilence(-50, 0)).Process(make([]float64, 100), 1000); len(got) != 0 {
+		t.Errorf("got %d samples of silence, want none", len(got))
+	}
+}
+
+func TestFades(t *testing.T) {
+	samples := []float64{1, 1, 1, 1, 1, 1, 1, 1}
+	got := dsp.New(dsp.FadeIn(4*time.Millisecond), dsp.FadeOut(2*time.Millisecond)).Process(samples, 1000)
+	want := []float64{0, 0.25, 0.5, 0.75, 1, 1, 0.5, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDCFilter(t *testing.T) {
+	samples := sine(440, 0.5, 2*time.Second, 11025)
+	for i := range samples {
+		samples[i] += 0.2
+	}
+	samples = dsp.New(dsp.DCFilter()).Process(samples, 11025)
+	var sum float64
+	for _, x := range samples[11025:] {
+		sum += x
+	}
+	if mean := sum / 11025; math.Abs(mean) > 1e-3 {
+		t.Errorf("got a DC offset of %v after filtering", mean)
+	}
+	if got := dsp.Peak(samples[11025:]); math.Abs(got-20*math.Log10(0.5)) > 0.1 {
+		t.Errorf("got a peak of %.2f dBFS after filtering, want the tone unchanged", got)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	samples := sine(440, 0.3, time.Second, 11025)
+	for i := 5000; i < 5100; i++ {
+		samples[i] *= 4
+	}
+	samples = dsp.New(dsp.Limiter(-1, 50*time.Millisecond)).Process(samples, 11025)
+	ceiling := math.Pow(10, -1.0/20)
+	for i, x := range samples {
+		if math.Abs(x) > ceiling+1e-9 {
+			t.Fatalf("sample %d is %v, above the ceiling of %v", i, x, ceiling)
+		}
+	}
+	if got := dsp.Peak(samples[:4000]); math.Abs(got-20*math.Log10(0.3)) > 0.01 {
+		t.Errorf("got a peak of %.2f dBFS before the limiting, want the tone unchanged", got)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	format := container.Format1M16
+	in := container.Encode(nil, sine(440, 0.1, time.Second, format.SampleRate), format.Encoding)
+	var b bytes.Buffer
+	w, err := dsp.NewWriter(&b, format, dsp.New(dsp.PeakNormalize(-6)))
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	for _, p := range [][]byte{in[:1001], in[1001:]} {
+		if _, err := w.Write(p); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	if b.Len() != 0 {
+		t.Errorf("got %d bytes before Close(), want none", b.Len())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if b.Len() != len(in) {
+		t.Errorf("got %d bytes, want %d", b.Len(), len(in))
+	}
+	if got := dsp.Peak(container.Decode(nil, b.Bytes(), format.Encoding)); math.Abs(got+6) > 0.01 {
+		t.Errorf("got a peak of %.2f dBFS, want -6", got)
+	}
+
+	stereo := container.Format{Encoding: container.PCM16, SampleRate: 11025, Channels: 2}
+	if _, err := dsp.NewWriter(&b, stereo, dsp.New()); !errors.Is(err, container.ErrUnsupportedFormat) {
+		t.Errorf("NewWriter(%v): got %v, want %v", stereo, err, container.ErrUnsupportedFormat)
+	}
+}
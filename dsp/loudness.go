@@ -0,0 +1,130 @@
+package dsp
+
+import "math"
+
+// Parameters of the gating of ITU-R BS.1770-4 as used by EBU R128.
+const (
+	// loudnessBlock is the length of a gating block in seconds, and
+	// loudnessStep the time between the starts of two blocks.
+	loudnessBlock = 0.4
+	loudnessStep  = 0.1
+
+	// loudnessAbsoluteGate is the level in LUFS below which blocks are
+	// ignored, and loudnessRelativeGate the level in LU below the loudness
+	// of the remaining blocks below which they are ignored as well.
+	loudnessAbsoluteGate = -70
+	loudnessRelativeGate = -10
+)
+
+// biquad is a second-order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) filter(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting returns the two filters of the K-weighting of ITU-R BS.1770,
+// a high shelf modelling the head followed by a high-pass, designed for any
+// sample rate rather than only the 48 kHz that the standard gives
+// coefficients for.
+func kWeighting(rate int) (shelf, highPass *biquad) {
+	k := math.Tan(math.Pi * 1681.974450955533 / float64(rate))
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf = &biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * 38.13547087602444 / float64(rate))
+	q = 0.5003270373238773
+	a0 = 1 + k/q + k*k
+	highPass = &biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return shelf, highPass
+}
+
+// Loudness returns the integrated loudness of mono samples at a sample rate
+// in LUFS as defined by EBU R128, or -Inf for silence. Samples shorter than a
+// gating block of 400 ms are measured as a single block.
+func Loudness(samples []float64, rate int) float64 {
+	shelf, highPass := kWeighting(rate)
+	weighted := make([]float64, len(samples))
+	for i, x := range samples {
+		weighted[i] = highPass.filter(shelf.filter(x))
+	}
+
+	block := int(loudnessBlock * float64(rate))
+	step := int(loudnessStep * float64(rate))
+	if block > len(weighted) || block < 1 {
+		block = len(weighted)
+	}
+	if block == 0 {
+		return math.Inf(-1)
+	}
+	var powers []float64
+	for start := 0; start+block <= len(weighted); start += step {
+		var sum float64
+		for _, x := range weighted[start : start+block] {
+			sum += x * x
+		}
+		powers = append(powers, sum/float64(block))
+		if step < 1 {
+			break
+		}
+	}
+
+	absolute := gatedLoudness(powers, math.Inf(-1), loudnessAbsoluteGate)
+	return gatedLoudness(powers, loudnessAbsoluteGate, absolute+loudnessRelativeGate)
+}
+
+// gatedLoudness returns the loudness of the mean power of the blocks louder
+// than both gates.
+func gatedLoudness(powers []float64, gate1, gate2 float64) float64 {
+	var sum float64
+	var n int
+	for _, p := range powers {
+		if l := blockLoudness(p); l > gate1 && l > gate2 {
+			sum += p
+			n++
+		}
+	}
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	return blockLoudness(sum / float64(n))
+}
+
+func blockLoudness(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+// LoudnessNormalize returns a stage that scales the samples so that their
+// integrated loudness as measured by [Loudness] is at a level in LUFS, such
+// as -23 for EBU R128 broadcast or -16 for streaming. Silence is left as it
+// is. Raising the level may clip, so follow it with a [Limiter].
+func LoudnessNormalize(lufs float64) Stage {
+	return func(samples []float64, rate int) []float64 {
+		loudness := Loudness(samples, rate)
+		if math.IsInf(loudness, -1) {
+			return samples
+		}
+		return Gain(lufs-loudness)(samples, rate)
+	}
+}
@@ -0,0 +1,186 @@
+package dsp
+
+import (
+	"math"
+	"time"
+)
+
+// dcCutoff is the cutoff frequency of the DC offset filter in Hz, well below
+// the lowest pitch of the voices.
+const dcCutoff = 10
+
+// limiterLookahead is how long before a peak the limiter starts to reduce
+// the gain.
+const limiterLookahead = 5 * time.Millisecond
+
+// amplitude returns the amplitude of a level in dBFS.
+func amplitude(dB float64) float64 {
+	return math.Pow(10, dB/20)
+}
+
+// frames returns the number of samples lasting d at a sample rate.
+func frames(d time.Duration, rate int) int {
+	return int(d.Seconds()*float64(rate) + 0.5)
+}
+
+// Gain returns a stage that changes the level of the samples by a number of
+// decibels.
+func Gain(dB float64) Stage {
+	return func(samples []float64, rate int) []float64 {
+		g := amplitude(dB)
+		for i := range samples {
+			samples[i] *= g
+		}
+		return samples
+	}
+}
+
+// Peak returns the highest absolute value of the samples in dBFS, or -Inf
+// for silence.
+func Peak(samples []float64) float64 {
+	var peak float64
+	for _, x := range samples {
+		peak = math.Max(peak, math.Abs(x))
+	}
+	return 20 * math.Log10(peak)
+}
+
+// PeakNormalize returns a stage that scales the samples so that their peak
+// is at a level in dBFS, such as -1. Silence is left as it is.
+func PeakNormalize(dB float64) Stage {
+	return func(samples []float64, rate int) []float64 {
+		peak := Peak(samples)
+		if math.IsInf(peak, -1) {
+			return samples
+		}
+		return Gain(dB-peak)(samples, rate)
+	}
+}
+
+// TrimSilence returns a stage that removes leading and trailing samples
+// below a level in dBFS, such as -50, keeping a margin of the given duration
+// on either side. Samples that are silent throughout are removed entirely.
+func TrimSilence(thresholdDB float64, margin time.Duration) Stage {
+	return func(samples []float64, rate int) []float64 {
+		threshold := amplitude(thresholdDB)
+		start, end := 0, len(samples)
+		for start < end && math.Abs(samples[start]) < threshold {
+			start++
+		}
+		for end > start && math.Abs(samples[end-1]) < threshold {
+			end--
+		}
+		if start == end {
+			return samples[:0]
+		}
+		keep := frames(margin, rate)
+		if start -= keep; start < 0 {
+			start = 0
+		}
+		if end += keep; end > len(samples) {
+			end = len(samples)
+		}
+		return samples[start:end]
+	}
+}
+
+// FadeIn returns a stage that raises the level of the samples linearly from
+// silence over the given duration.
+func FadeIn(d time.Duration) Stage {
+	return func(samples []float64, rate int) []float64 {
+		n := frames(d, rate)
+		for i := 0; i < n && i < len(samples); i++ {
+			samples[i] *= float64(i) / float64(n)
+		}
+		return samples
+	}
+}
+
+// FadeOut returns a stage that lowers the level of the samples linearly to
+// silence over the given duration.
+func FadeOut(d time.Duration) Stage {
+	return func(samples []float64, rate int) []float64 {
+		n := frames(d, rate)
+		for i := 0; i < n && i < len(samples); i++ {
+			samples[len(samples)-1-i] *= float64(i) / float64(n)
+		}
+		return samples
+	}
+}
+
+// DCFilter returns a stage that removes a DC offset with a first-order
+// high-pass filter at 10 Hz.
+func DCFilter() Stage {
+	return func(samples []float64, rate int) []float64 {
+		r := math.Exp(-2 * math.Pi * dcCutoff / float64(rate))
+		var x1, y1 float64
+		for i, x := range samples {
+			y1 = x - x1 + r*y1
+			x1 = x
+			samples[i] = y1
+		}
+		return samples
+	}
+}
+
+// Limiter returns a stage that keeps the samples below a ceiling in dBFS,
+// such as -1, without clipping them. The gain is reduced smoothly over 5 ms
+// before each peak and recovers over the given release time.
+func Limiter(ceilingDB float64, release time.Duration) Stage {
+	return func(samples []float64, rate int) []float64 {
+		ceiling := amplitude(ceilingDB)
+		n := len(samples)
+		lookahead := frames(limiterLookahead, rate)
+		if lookahead < 1 {
+			lookahead = 1
+		}
+
+		// The gain each sample needs on its own.
+		gain := make([]float64, n)
+		for i, x := range samples {
+			gain[i] = 1
+			if a := math.Abs(x); a > ceiling {
+				gain[i] = ceiling / a
+			}
+		}
+
+		// Each sample takes the lowest gain needed within the lookahead
+		// after it, which is then only allowed to rise by the release.
+		held := make([]float64, n)
+		var window []int
+		for i := n - 1; i >= 0; i-- {
+			for len(window) > 0 && gain[window[len(window)-1]] >= gain[i] {
+				window = window[:len(window)-1]
+			}
+			window = append(window, i)
+			if window[0] >= i+lookahead {
+				window = window[1:]
+			}
+			held[i] = gain[window[0]]
+		}
+		coef := 1.0
+		if r := frames(release, rate); r > 0 {
+			coef = 1 - math.Exp(-1/float64(r))
+		}
+		for i := 1; i < n; i++ {
+			held[i] = math.Min(held[i], held[i-1]+(1-held[i-1])*coef)
+		}
+
+		// Averaging over the lookahead before each sample smooths the
+		// attack, and no more than the gain needed at a peak is applied as
+		// every gain averaged already takes the peak into account.
+		var sum float64
+		for i := range samples {
+			sum += held[i]
+			if i >= lookahead {
+				sum -= held[i-lookahead]
+			}
+			count := lookahead
+			if i+1 < count {
+				count = i + 1
+			}
+			samples[i] *= math.Min(1, sum/float64(count))
+		}
+		return samples
+	}
+}
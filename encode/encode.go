@@ -0,0 +1,158 @@
+// Package encode compresses the samples produced by the engine into FLAC,
+// Ogg Opus or MP3 files, which take a fraction of the space of WAV files.
+//
+// FLAC is always available. The Ogg Opus and MP3 encoders use libopusenc and
+// LAME through cgo and are only built with the "opus" and "mp3" build tags
+// respectively; without them, [NewEncoder] returns [ErrUnavailable]:
+//
+//	go build -tags opus,mp3
+//
+// Like the container package, samples are passed in the layout of the engine,
+// and a header announcing an unknown length is completed by Close if the
+// destination can seek.
+package encode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi/container"
+)
+
+var (
+	// ErrUnknownFormat is returned for formats other than [FLAC], [Opus]
+	// and [MP3].
+	ErrUnknownFormat = errors.New("unknown compressed format")
+
+	// ErrUnavailable is returned by [NewEncoder] for formats whose encoder
+	// was not built, see the package documentation.
+	ErrUnavailable = errors.New("encoder not built into this program")
+
+	errClosed = errors.New("write to closed encoder")
+)
+
+// Format is a compressed audio format.
+type Format int
+
+const (
+	// FLAC is the lossless Free Lossless Audio Codec in its native
+	// container.
+	FLAC Format = iota + 1
+
+	// Opus is the lossy Opus codec in an Ogg container, tuned for speech.
+	Opus
+
+	// MP3 is lossy MPEG-1/2/2.5 Audio Layer III with an ID3v2 tag.
+	MP3
+)
+
+func (f Format) String() string {
+	switch f {
+	case FLAC:
+		return "FLAC"
+	case Opus:
+		return "Ogg Opus"
+	case MP3:
+		return "MP3"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// Extension returns the usual file name extension of the format, such as
+// ".flac".
+func (f Format) Extension() string {
+	switch f {
+	case FLAC:
+		return ".flac"
+	case Opus:
+		return ".opus"
+	case MP3:
+		return ".mp3"
+	}
+	return ""
+}
+
+// FormatFor returns the format matching the extension of a file name.
+func FormatFor(name string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".flac":
+		return FLAC, true
+	case ".opus", ".oga", ".ogg":
+		return Opus, true
+	case ".mp3":
+		return MP3, true
+	}
+	return 0, false
+}
+
+// Available reports whether the encoder of a format was built.
+func (f Format) Available() bool {
+	switch f {
+	case FLAC:
+		return true
+	case Opus:
+		return opusAvailable
+	case MP3:
+		return mp3Available
+	}
+	return false
+}
+
+// NewEncoder writes the header of a file in the given format to w, tagged
+// with tags, and returns a writer compressing samples in the format in. Close
+// writes the remaining samples but does not close w.
+func NewEncoder(w io.Writer, f Format, in container.Format, tags Tags) (io.WriteCloser, error) {
+	if !in.Valid() {
+		return nil, fmt.Errorf("%w: %v", container.ErrUnsupportedFormat, in)
+	}
+	switch f {
+	case FLAC:
+		return newFLACEncoder(w, in, tags)
+	case Opus:
+		return newOpusEncoder(w, in, tags)
+	case MP3:
+		return newMP3Encoder(w, in, tags)
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnknownFormat, f)
+}
+
+// Abort releases an encoder returned by [NewEncoder] without writing the rest
+// of the file, such as after the samples failed to arrive. What has been
+// written to the destination so far is left as it is. Close and Abort do
+// nothing afterwards.
+func Abort(w io.WriteCloser) {
+	if a, ok := w.(interface{ abort() }); ok {
+		a.abort()
+	}
+}
+
+// frames splits samples written in arbitrary pieces into whole frames.
+type frames struct {
+	format  container.Format
+	partial []byte
+	buf     []byte
+}
+
+// split returns the whole frames of the data written so far, which stay
+// valid until the next call.
+func (f *frames) split(p []byte) []byte {
+	size := f.format.FrameSize()
+	if len(f.partial) == 0 && len(p)%size == 0 {
+		return p
+	}
+	f.buf = append(append(f.buf[:0], f.partial...), p...)
+	whole := len(f.buf) - len(f.buf)%size
+	f.partial = append(f.partial[:0], f.buf[whole:]...)
+	return f.buf[:whole]
+}
+
+// close returns [container.ErrPartialSample] if the data ended within a frame.
+func (f *frames) close() error {
+	if len(f.partial) > 0 {
+		return container.ErrPartialSample
+	}
+	return nil
+}
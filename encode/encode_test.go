@@ -0,0 +1,376 @@
+package encode_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"github.com/icedream/go-dectalkdapi/container"
+	"github.com/icedream/go-dectalkdapi/encode"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signal returns samples of a tone, silence and noise spanning several
+// frames.
+func signal() []float64 {
+	r := rand.New(rand.NewSource(1))
+	samples := make([]float64, 10000)
+	for i := range samples {
+		switch {
+		case i < 5000:
+			samples[i] = 0.5 * math.Sin(2*math.Pi*440*float64(i)/11025)
+		case i < 7000:
+		default:
+			samples[i] = r.Float64() - 0.5
+		}
+	}
+	return samples
+}
+
+func TestFLAC(t *testing.T) {
+	tags := encode.Tags{Text: "Hello, world.", Speaker: "paul", Version: "DECtalk 5.0"}
+	for _, f := range []container.Format{container.Format1M16, container.Format1M08, container.Format08M08} {
+		in := container.Encode(nil, signal(), f.Encoding)
+		file, err := os.Create(filepath.Join(t.TempDir(), "out.flac"))
+		if err != nil {
+			t.Fatalf("Create() failed: %v", err)
+		}
+		defer file.Close()
+		w, err := encode.NewEncoder(file, encode.FLAC, f, tags)
+		if err != nil {
+			t.Fatalf("NewEncoder(%v) failed: %v", f, err)
+		}
+		// Split samples across writes.
+		for _, p := range [][]byte{in[:1], in[1:4097], in[4097:]} {
+			if _, err := w.Write(p); err != nil {
+				t.Fatalf("Write() failed: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+
+		data, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("ReadFile() failed: %v", err)
+		}
+		s, err := decodeFLAC(data)
+		if err != nil {
+			t.Fatalf("%v: decoding failed: %v", f, err)
+		}
+		if len(data) >= len(in)+len(in)/2 {
+			t.Errorf("%v: got %d bytes for %d bytes of samples", f, len(data), len(in))
+		}
+
+		// Samples that are not linear PCM are stored as 16-bit samples.
+		want := in
+		if f.Encoding == container.MuLaw {
+			want = container.Encode(nil, container.Decode(nil, in, f.Encoding), container.PCM16)
+		}
+		if !bytes.Equal(s.samples, want) {
+			t.Errorf("%v: decoded samples differ", f)
+		}
+		if s.total != len(signal()) || s.md5 != md5.Sum(s.signed) {
+			t.Errorf("%v: got %d samples with MD5 %x in STREAMINFO", f, s.total, s.md5)
+		}
+		for _, c := range []string{"TITLE=Hello, world.", "ARTIST=paul", "ENCODER=DECtalk 5.0"} {
+			if !strings.Contains(strings.Join(s.comments, "\n"), c) {
+				t.Errorf("%v: comment %q missing in %q", f, c, s.comments)
+			}
+		}
+	}
+}
+
+func TestFLACStream(t *testing.T) {
+	// A pipe can not seek, so the length stays unknown.
+	var b bytes.Buffer
+	in := container.Encode(nil, signal(), container.PCM16)
+	w, err := encode.NewEncoder(&b, encode.FLAC, container.Format1M16, encode.Tags{})
+	if err != nil {
+		t.Fatalf("NewEncoder() failed: %v", err)
+	}
+	if _, err := w.Write(in); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	s, err := decodeFLAC(b.Bytes())
+	if err != nil {
+		t.Fatalf("decoding failed: %v", err)
+	}
+	if !bytes.Equal(s.samples, in) || s.total != 0 {
+		t.Errorf("got %d bytes of samples with a length of %d", len(s.samples), s.total)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	var b bytes.Buffer
+	w, err := encode.NewEncoder(&b, encode.FLAC, container.Format1M16, encode.Tags{})
+	if err != nil {
+		t.Fatalf("NewEncoder() failed: %v", err)
+	}
+	if _, err := w.Write(container.Encode(nil, signal(), container.PCM16)); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	n := b.Len()
+	encode.Abort(w)
+	if _, err := w.Write([]byte{0, 0}); err == nil {
+		t.Errorf("Write() after Abort() succeeded")
+	}
+	if err := w.Close(); err != nil || b.Len() != n {
+		t.Errorf("Close() after Abort() wrote %d bytes: %v", b.Len()-n, err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[string]encode.Format{
+		"out.flac":     encode.FLAC,
+		"dir/OUT.OPUS": encode.Opus,
+		"out.ogg":      encode.Opus,
+		"out.mp3":      encode.MP3,
+	}
+	for name, want := range tests {
+		if got, ok := encode.FormatFor(name); !ok || got != want {
+			t.Errorf("FormatFor(%q) = %v, %v, want %v", name, got, ok, want)
+		}
+	}
+	if _, ok := encode.FormatFor("out.wav"); ok {
+		t.Errorf("FormatFor(%q) succeeded", "out.wav")
+	}
+
+	for _, f := range []encode.Format{encode.Opus, encode.MP3} {
+		if f.Available() {
+			continue
+		}
+		if _, err := encode.NewEncoder(io.Discard, f, container.Format1M16, encode.Tags{}); !errors.Is(err, encode.ErrUnavailable) {
+			t.Errorf("NewEncoder(%v) without encoder: got %v, want ErrUnavailable", f, err)
+		}
+	}
+	if _, err := encode.NewEncoder(io.Discard, 0, container.Format1M16, encode.Tags{}); !errors.Is(err, encode.ErrUnknownFormat) {
+		t.Errorf("NewEncoder(0): got %v, want ErrUnknownFormat", err)
+	}
+}
+
+// flacStream is a FLAC file decoded by decodeFLAC.
+type flacStream struct {
+	total    int
+	md5      [16]byte
+	comments []string
+
+	// samples holds the samples in the layout of the engine, signed the
+	// samples as used for the MD5 sum.
+	samples []byte
+	signed  []byte
+}
+
+// decodeFLAC decodes the subset of FLAC written by the encoder: fixed block
+// sizes and independent channels.
+func decodeFLAC(data []byte) (*flacStream, error) {
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		return nil, errors.New("missing marker")
+	}
+	data = data[4:]
+	s := new(flacStream)
+	var bps, channels int
+	for last := false; !last; {
+		last = data[0]&0x80 != 0
+		length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		block := data[4 : 4+length]
+		switch data[0] & 0x7f {
+		case 0:
+			r := &bitReader{data: block}
+			r.read(16 + 16 + 24 + 24 + 20)
+			channels = int(r.read(3)) + 1
+			bps = int(r.read(5)) + 1
+			s.total = int(r.read(36))
+			copy(s.md5[:], block[18:])
+		case 4:
+			n := binary.LittleEndian.Uint32(block)
+			block = block[4+n:]
+			count := binary.LittleEndian.Uint32(block)
+			block = block[4:]
+			for i := uint32(0); i < count; i++ {
+				n := binary.LittleEndian.Uint32(block)
+				s.comments = append(s.comments, string(block[4:4+n]))
+				block = block[4+n:]
+			}
+		}
+		data = data[4+length:]
+	}
+
+	for len(data) > 0 {
+		r := &bitReader{data: data}
+		if r.read(16) != 0xfff8 {
+			return nil, errors.New("missing frame sync code")
+		}
+		blockCode, rateCode := r.read(4), r.read(4)
+		r.read(4 + 3 + 1)
+		// The frame number in UTF-8.
+		for first := r.read(8); first&0xc0 == 0xc0; first <<= 1 {
+			r.read(8)
+		}
+		if blockCode != 7 {
+			return nil, errors.New("unexpected block size code")
+		}
+		n := int(r.read(16)) + 1
+		if rateCode == 13 || rateCode == 14 {
+			r.read(16)
+		}
+		r.read(8) // CRC-8
+
+		samples := make([][]int32, channels)
+		for c := range samples {
+			x, err := r.subframe(n, bps)
+			if err != nil {
+				return nil, err
+			}
+			samples[c] = x
+		}
+		r.align()
+		end := r.pos / 8
+		if crc := binary.BigEndian.Uint16(data[end:]); crc != crc16(data[:end]) {
+			return nil, errors.New("frame CRC mismatch")
+		}
+		data = data[end+2:]
+
+		for i := 0; i < n; i++ {
+			for c := range samples {
+				v := samples[c][i]
+				switch bps {
+				case 8:
+					s.samples = append(s.samples, byte(v+0x80))
+					s.signed = append(s.signed, byte(v))
+				case 16:
+					s.samples = binary.LittleEndian.AppendUint16(s.samples, uint16(v))
+					s.signed = binary.LittleEndian.AppendUint16(s.signed, uint16(v))
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) signed(n int) int32 {
+	v := r.read(n)
+	return int32(int64(v<<(64-n)) >> (64 - n))
+}
+
+func (r *bitReader) align() {
+	r.pos = (r.pos + 7) / 8 * 8
+}
+
+func (r *bitReader) subframe(n, bps int) ([]int32, error) {
+	header := r.read(8)
+	kind := header >> 1 & 0x3f
+	x := make([]int32, 0, n)
+	switch {
+	case kind == 0:
+		v := r.signed(bps)
+		for i := 0; i < n; i++ {
+			x = append(x, v)
+		}
+	case kind == 1:
+		for i := 0; i < n; i++ {
+			x = append(x, r.signed(bps))
+		}
+	case kind&0x38 == 0x08, kind&0x20 != 0:
+		order := int(kind & 7)
+		if kind&0x20 != 0 {
+			order = int(kind&0x1f) + 1
+		}
+		for i := 0; i < order; i++ {
+			x = append(x, r.signed(bps))
+		}
+		var coefs []int32
+		var shift int
+		if kind&0x20 != 0 {
+			precision := int(r.read(4)) + 1
+			shift = int(r.signed(5))
+			for i := 0; i < order; i++ {
+				coefs = append(coefs, r.signed(precision))
+			}
+		}
+		if r.read(2) != 0 {
+			return nil, errors.New("unexpected residual coding method")
+		}
+		partitionOrder := int(r.read(4))
+		for p := 0; p < 1<<partitionOrder; p++ {
+			size := n >> partitionOrder
+			if p == 0 {
+				size -= order
+			}
+			k := int(r.read(4))
+			for i := 0; i < size; i++ {
+				q := 0
+				for r.read(1) == 0 {
+					q++
+				}
+				u := uint32(q)<<k | uint32(r.read(k))
+				res := int32(u>>1) ^ -int32(u&1)
+				x = append(x, predict(x, order, coefs, shift)+res)
+			}
+		}
+	default:
+		return nil, errors.New("unexpected subframe type")
+	}
+	return x, nil
+}
+
+func predict(x []int32, order int, coefs []int32, shift int) int32 {
+	i := len(x)
+	if coefs != nil {
+		var sum int64
+		for j, c := range coefs {
+			sum += int64(c) * int64(x[i-1-j])
+		}
+		return int32(sum >> shift)
+	}
+	switch order {
+	case 1:
+		return x[i-1]
+	case 2:
+		return 2*x[i-1] - x[i-2]
+	case 3:
+		return 3*x[i-1] - 3*x[i-2] + x[i-3]
+	case 4:
+		return 4*x[i-1] - 6*x[i-2] + 4*x[i-3] - x[i-4]
+	}
+	return 0
+}
+
+func crc16(p []byte) uint16 {
+	var c uint16
+	for _, v := range p {
+		c ^= uint16(v) << 8
+		for i := 0; i < 8; i++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x8005
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
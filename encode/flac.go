@@ -0,0 +1,528 @@
+package encode
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"hash"
+	"io"
+	"math/bits"
+
+	"github.com/icedream/go-dectalkdapi/container"
+)
+
+// flacBlockSize is the number of samples per channel in a frame, which is
+// within the streamable subset of FLAC for all sample rates.
+const flacBlockSize = 4096
+
+// flacMaxRiceParameter is the largest parameter of the 4-bit Rice coding.
+const flacMaxRiceParameter = 14
+
+// flacEncoder compresses samples into frames of fixed size, predicting them
+// with the best of the fixed polynomial predictors of FLAC.
+type flacEncoder struct {
+	w      io.Writer
+	format container.Format
+	frames frames
+
+	// pcm is the encoding compressed, which differs from the input for
+	// samples that are not linear PCM.
+	pcm container.Encoding
+	bps int
+
+	// seeker is set if STREAMINFO can be completed on Close, start being
+	// the offset of the stream.
+	seeker io.WriteSeeker
+	start  int64
+
+	md5      hash.Hash
+	block    [][]int32
+	frameNum uint64
+	samples  uint64
+	minFrame int
+	maxFrame int
+
+	bits     bitWriter
+	residual []int32
+	floats   []float64
+	conv     []byte
+	closed   bool
+}
+
+func newFLACEncoder(w io.Writer, in container.Format, tags Tags) (io.WriteCloser, error) {
+	if in.Channels > 8 {
+		return nil, container.ErrUnsupportedFormat
+	}
+	e := &flacEncoder{
+		w:      w,
+		format: in,
+		frames: frames{format: in},
+		pcm:    in.Encoding,
+		md5:    md5.New(),
+		block:  make([][]int32, in.Channels),
+	}
+	switch in.Encoding {
+	case container.MuLaw, container.ALaw:
+		e.pcm = container.PCM16
+	case container.Float32:
+		e.pcm = container.PCM24
+	}
+	e.bps = e.pcm.BytesPerSample() * 8
+	for c := range e.block {
+		e.block[c] = make([]int32, 0, flacBlockSize)
+	}
+
+	if ws, ok := w.(io.WriteSeeker); ok {
+		if start, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			e.seeker, e.start = ws, start
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("fLaC")
+	writeMetadataBlock(&b, 0, false, e.streamInfo())
+	writeMetadataBlock(&b, 4, true, tags.vorbisComment())
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func writeMetadataBlock(b *bytes.Buffer, blockType byte, last bool, data []byte) {
+	if last {
+		blockType |= 0x80
+	}
+	b.WriteByte(blockType)
+	b.Write([]byte{byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))})
+	b.Write(data)
+}
+
+// streamInfo returns the STREAMINFO block. The frame sizes, the number of
+// samples and the MD5 sum are only known once all samples were written, and
+// are 0 for unknown before.
+func (e *flacEncoder) streamInfo() []byte {
+	var sum []byte
+	if e.closed {
+		sum = e.md5.Sum(nil)
+	} else {
+		sum = make([]byte, md5.Size)
+	}
+	var b bitWriter
+	b.write(flacBlockSize, 16) // minimum block size
+	b.write(flacBlockSize, 16) // maximum block size
+	b.write(uint64(e.minFrame), 24)
+	b.write(uint64(e.maxFrame), 24)
+	b.write(uint64(e.format.SampleRate), 20)
+	b.write(uint64(e.format.Channels-1), 3)
+	b.write(uint64(e.bps-1), 5)
+	b.write(e.samples>>32, 4)
+	b.write(e.samples&0xffffffff, 32)
+	return append(b.bytes(), sum...)
+}
+
+func (e *flacEncoder) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errClosed
+	}
+	data := e.frames.split(p)
+	if e.pcm != e.format.Encoding {
+		e.floats = container.Decode(e.floats[:0], data, e.format.Encoding)
+		e.conv = container.Encode(e.conv[:0], e.floats, e.pcm)
+		data = e.conv
+	}
+	if e.pcm == container.PCM8 {
+		// The MD5 sum is over signed samples.
+		e.conv = append(e.conv[:0], data...)
+		for i := range e.conv {
+			e.conv[i] ^= 0x80
+		}
+		e.md5.Write(e.conv)
+	} else {
+		e.md5.Write(data)
+	}
+
+	size := e.pcm.BytesPerSample()
+	channels := len(e.block)
+	for i := 0; i+size <= len(data); i += size {
+		c := i / size % channels
+		e.block[c] = append(e.block[c], pcmSample(data[i:], e.pcm))
+		if c == channels-1 && len(e.block[c]) == flacBlockSize {
+			if err := e.writeFrame(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func pcmSample(p []byte, e container.Encoding) int32 {
+	switch e {
+	case container.PCM8:
+		return int32(p[0]) - 0x80
+	case container.PCM16:
+		return int32(int16(binary.LittleEndian.Uint16(p)))
+	}
+	return int32(p[0]) | int32(p[1])<<8 | int32(int8(p[2]))<<16
+}
+
+// abort drops the samples of the last frame, as the encoder holds no other
+// resources.
+func (e *flacEncoder) abort() {
+	e.closed = true
+}
+
+// Close writes the last frame and, if the destination can seek, completes
+// STREAMINFO.
+func (e *flacEncoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	if len(e.block[0]) > 0 {
+		if err := e.writeFrame(); err != nil {
+			return err
+		}
+	}
+	e.closed = true
+	if err := e.frames.close(); err != nil {
+		return err
+	}
+	if e.seeker == nil {
+		return nil
+	}
+
+	end, err := e.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	// STREAMINFO follows the marker and its block header.
+	if _, err := e.seeker.Seek(e.start+8, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := e.seeker.Write(e.streamInfo()); err != nil {
+		return err
+	}
+	_, err = e.seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+// Sample rates with a code of their own in frame headers.
+var flacRateCodes = map[int]uint64{
+	88200:  1,
+	176400: 2,
+	192000: 3,
+	8000:   4,
+	16000:  5,
+	22050:  6,
+	24000:  7,
+	32000:  8,
+	44100:  9,
+	48000:  10,
+	96000:  11,
+}
+
+// Codes of the sample sizes in frame headers.
+var flacSizeCodes = map[int]uint64{
+	8:  1,
+	16: 4,
+	24: 6,
+}
+
+func (e *flacEncoder) writeFrame() error {
+	n := len(e.block[0])
+	b := &e.bits
+	b.reset()
+
+	b.write(0xfff8, 16) // sync code, fixed block size
+	b.write(7, 4)       // block size in 16 bits at the end of the header
+	rate := e.format.SampleRate
+	rateCode, ok := flacRateCodes[rate]
+	switch {
+	case ok:
+	case rate < 1<<16:
+		rateCode = 13 // in Hz
+	case rate%10 == 0 && rate/10 < 1<<16:
+		rateCode = 14 // in tens of Hz
+	}
+	b.write(rateCode, 4)
+	b.write(uint64(len(e.block)-1), 4) // independent channels
+	b.write(flacSizeCodes[e.bps], 3)
+	b.write(0, 1)
+	b.writeUTF8(e.frameNum)
+	b.write(uint64(n-1), 16)
+	switch rateCode {
+	case 13:
+		b.write(uint64(rate), 16)
+	case 14:
+		b.write(uint64(rate/10), 16)
+	}
+	b.write(uint64(crc8(b.bytes())), 8)
+
+	for c, x := range e.block {
+		e.writeSubframe(x)
+		e.block[c] = x[:0]
+	}
+	b.align()
+	b.write(uint64(crc16(b.bytes())), 16)
+
+	frame := b.bytes()
+	if _, err := e.w.Write(frame); err != nil {
+		return err
+	}
+	if e.minFrame == 0 || len(frame) < e.minFrame {
+		e.minFrame = len(frame)
+	}
+	if len(frame) > e.maxFrame {
+		e.maxFrame = len(frame)
+	}
+	e.frameNum++
+	e.samples += uint64(n)
+	return nil
+}
+
+// writeSubframe writes the samples of a channel as the smallest of a
+// constant, verbatim, fixed or LPC subframe.
+func (e *flacEncoder) writeSubframe(x []int32) {
+	b := &e.bits
+	constant := true
+	for _, v := range x[1:] {
+		if v != x[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		b.write(0, 8)
+		b.writeSigned(x[0], e.bps)
+		return
+	}
+
+	var best *predictor
+	bestBits := 8 + len(x)*e.bps
+	try := func(p *predictor, residual []int32) {
+		_, _, riceBits := bestPartitioning(residual, len(x), p.order())
+		if n := p.bits(e.bps) + riceBits; n < bestBits {
+			best, bestBits = p, n
+		}
+	}
+	for order := 0; order <= 4 && order < len(x); order++ {
+		p := &predictor{fixed: order}
+		e.residual = p.residual(e.residual[:0], x)
+		try(p, e.residual)
+	}
+	for _, p := range lpcPredictors(x, e.bps) {
+		if e.residual = p.residual(e.residual[:0], x); e.residual != nil {
+			try(p, e.residual)
+		}
+	}
+	if best == nil {
+		b.write(1<<1, 8) // verbatim
+		for _, v := range x {
+			b.writeSigned(v, e.bps)
+		}
+		return
+	}
+
+	order := best.order()
+	if best.coefs == nil {
+		b.write(uint64(0x08|order)<<1, 8)
+	} else {
+		b.write(uint64(0x20|(order-1))<<1, 8)
+	}
+	for _, v := range x[:order] {
+		b.writeSigned(v, e.bps)
+	}
+	if best.coefs != nil {
+		b.write(lpcPrecision-1, 4)
+		b.write(uint64(best.shift), 5)
+		for _, c := range best.coefs {
+			b.writeSigned(c, lpcPrecision)
+		}
+	}
+
+	e.residual = best.residual(e.residual[:0], x)
+	partitionOrder, params, _ := bestPartitioning(e.residual, len(x), order)
+	b.write(0, 2) // 4-bit Rice parameters
+	b.write(uint64(partitionOrder), 4)
+	residual := e.residual
+	for p, k := range params {
+		size := len(x) >> partitionOrder
+		if p == 0 {
+			size -= order
+		}
+		b.write(uint64(k), 4)
+		for _, r := range residual[:size] {
+			u := fold(r)
+			b.writeUnary(u >> k)
+			b.write(u&(1<<k-1), uint(k))
+		}
+		residual = residual[size:]
+	}
+}
+
+// bestPartitioning returns the partition order and the Rice parameters of
+// each partition that code the residual of a block of n samples in the
+// fewest bits, and that number of bits including the subframe header.
+func bestPartitioning(residual []int32, n, predictorOrder int) (int, []int, int) {
+	bestOrder, bestBits := 0, -1
+	var bestParams []int
+	for order := 0; order <= 8 && n%(1<<order) == 0 && n>>order > predictorOrder; order++ {
+		total := 8 + 2 + 4
+		params := make([]int, 0, 1<<order)
+		rest := residual
+		for p := 0; p < 1<<order; p++ {
+			size := n >> order
+			if p == 0 {
+				size -= predictorOrder
+			}
+			k, bits := riceParameter(rest[:size])
+			params = append(params, k)
+			total += 4 + bits
+			rest = rest[size:]
+		}
+		if bestBits < 0 || total < bestBits {
+			bestOrder, bestBits, bestParams = order, total, params
+		}
+	}
+	return bestOrder, bestParams, bestBits
+}
+
+// riceParameter returns the Rice parameter that codes a partition in the
+// fewest bits, and that number of bits.
+func riceParameter(residual []int32) (int, int) {
+	var sum uint64
+	for _, r := range residual {
+		sum += fold(r)
+	}
+	if len(residual) == 0 {
+		return 0, 0
+	}
+	// The best parameter is close to the logarithm of the mean.
+	guess := bits.Len64(sum/uint64(len(residual))) - 1
+	bestK, bestBits := 0, -1
+	for k := guess - 1; k <= guess+1; k++ {
+		if k < 0 || k > flacMaxRiceParameter {
+			continue
+		}
+		n := len(residual) * (k + 1)
+		for _, r := range residual {
+			n += int(fold(r) >> k)
+		}
+		if bestBits < 0 || n < bestBits {
+			bestK, bestBits = k, n
+		}
+	}
+	if bestBits < 0 {
+		// Even the mean is beyond the largest parameter.
+		bestK = flacMaxRiceParameter
+		bestBits = len(residual) * (bestK + 1)
+		for _, r := range residual {
+			bestBits += int(fold(r) >> bestK)
+		}
+	}
+	return bestK, bestBits
+}
+
+// fold maps signed residuals to unsigned numbers, interleaving positive and
+// negative ones.
+func fold(r int32) uint64 {
+	return uint64(uint32(r<<1) ^ uint32(r>>31))
+}
+
+// bitWriter collects bits most significant first.
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+func (b *bitWriter) reset() {
+	b.buf, b.acc, b.n = b.buf[:0], 0, 0
+}
+
+// write writes the lowest bits of v, at most 32.
+func (b *bitWriter) write(v uint64, bits uint) {
+	b.acc = b.acc<<bits | v&(1<<bits-1)
+	b.n += bits
+	for b.n >= 8 {
+		b.n -= 8
+		b.buf = append(b.buf, byte(b.acc>>b.n))
+	}
+	b.acc &= 1<<b.n - 1
+}
+
+func (b *bitWriter) writeSigned(v int32, bits int) {
+	b.write(uint64(uint32(v)), uint(bits))
+}
+
+// writeUnary writes q zero bits followed by a one bit.
+func (b *bitWriter) writeUnary(q uint64) {
+	for ; q >= 32; q -= 32 {
+		b.write(0, 32)
+	}
+	b.write(1, uint(q)+1)
+}
+
+// writeUTF8 writes a frame number in the extended UTF-8 coding of FLAC.
+func (b *bitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		b.write(v, 8)
+		return
+	}
+	n := 2
+	for v >= 1<<(5*n+1) {
+		n++
+	}
+	b.write(0xff<<(8-n)&0xff|v>>(6*(n-1)), 8)
+	for i := n - 2; i >= 0; i-- {
+		b.write(0x80|v>>(6*i)&0x3f, 8)
+	}
+}
+
+func (b *bitWriter) align() {
+	if b.n > 0 {
+		b.write(0, 8-b.n)
+	}
+}
+
+// bytes returns the whole bytes written so far.
+func (b *bitWriter) bytes() []byte {
+	return b.buf
+}
+
+var crc8Table, crc16Table = func() ([256]byte, [256]uint16) {
+	var t8 [256]byte
+	var t16 [256]uint16
+	for i := range t8 {
+		c8, c16 := byte(i), uint16(i)<<8
+		for j := 0; j < 8; j++ {
+			if c8&0x80 != 0 {
+				c8 = c8<<1 ^ 0x07
+			} else {
+				c8 <<= 1
+			}
+			if c16&0x8000 != 0 {
+				c16 = c16<<1 ^ 0x8005
+			} else {
+				c16 <<= 1
+			}
+		}
+		t8[i], t16[i] = c8, c16
+	}
+	return t8, t16
+}()
+
+func crc8(p []byte) byte {
+	var c byte
+	for _, v := range p {
+		c = crc8Table[c^v]
+	}
+	return c
+}
+
+func crc16(p []byte) uint16 {
+	var c uint16
+	for _, v := range p {
+		c = c<<8 ^ crc16Table[byte(c>>8)^v]
+	}
+	return c
+}
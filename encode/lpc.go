@@ -0,0 +1,170 @@
+package encode
+
+import "math"
+
+// lpcMaxOrder is the highest order of the linear predictors tried, which is
+// within the streamable subset of FLAC.
+const lpcMaxOrder = 12
+
+// lpcPrecision is the number of bits of the quantized coefficients.
+const lpcPrecision = 14
+
+// predictor predicts each sample of a FLAC subframe from the ones before,
+// either with the fixed polynomial of an order or with quantized linear
+// prediction coefficients.
+type predictor struct {
+	fixed int
+
+	// coefs are the coefficients applied to the samples from the latest
+	// backwards, and shift is the number of fractional bits.
+	coefs []int32
+	shift int
+}
+
+func (p *predictor) order() int {
+	if p.coefs != nil {
+		return len(p.coefs)
+	}
+	return p.fixed
+}
+
+// bits returns the size of the subframe header and the warm-up samples.
+func (p *predictor) bits(bps int) int {
+	n := 8 + p.order()*bps
+	if p.coefs != nil {
+		n += 4 + 5 + len(p.coefs)*lpcPrecision
+	}
+	return n
+}
+
+// residual appends the residual of the prediction to dst, for all samples
+// after the warm-up samples. It returns nil if the residual does not fit into
+// the 31 bits that FLAC allows.
+func (p *predictor) residual(dst, x []int32) []int32 {
+	order := p.order()
+	for i := order; i < len(x); i++ {
+		var prediction int64
+		if p.coefs == nil {
+			switch order {
+			case 1:
+				prediction = int64(x[i-1])
+			case 2:
+				prediction = 2*int64(x[i-1]) - int64(x[i-2])
+			case 3:
+				prediction = 3*int64(x[i-1]) - 3*int64(x[i-2]) + int64(x[i-3])
+			case 4:
+				prediction = 4*int64(x[i-1]) - 6*int64(x[i-2]) + 4*int64(x[i-3]) - int64(x[i-4])
+			}
+		} else {
+			for j, c := range p.coefs {
+				prediction += int64(c) * int64(x[i-1-j])
+			}
+			prediction >>= p.shift
+		}
+		r := int64(x[i]) - prediction
+		if r >= 1<<30 || r < -(1<<30) {
+			return nil
+		}
+		dst = append(dst, int32(r))
+	}
+	return dst
+}
+
+// lpcPredictors returns linear predictors of several orders for the samples
+// of a block, found with the Levinson-Durbin recursion on the autocorrelation
+// of the windowed samples.
+func lpcPredictors(x []int32, bps int) []*predictor {
+	maxOrder := lpcMaxOrder
+	if maxOrder >= len(x) {
+		maxOrder = len(x) - 1
+	}
+	if maxOrder < 1 {
+		return nil
+	}
+
+	// A Welch window reduces the effect of the edges of the block.
+	windowed := make([]float64, len(x))
+	half := float64(len(x)-1) / 2
+	for i, v := range x {
+		d := (float64(i) - half) / (half + 1)
+		windowed[i] = float64(v) * (1 - d*d)
+	}
+	autoc := make([]float64, maxOrder+1)
+	for lag := range autoc {
+		for i := lag; i < len(windowed); i++ {
+			autoc[lag] += windowed[i] * windowed[i-lag]
+		}
+	}
+	if autoc[0] == 0 {
+		return nil
+	}
+
+	var predictors []*predictor
+	lpc := make([]float64, maxOrder)
+	err := autoc[0]
+	for i := 0; i < maxOrder; i++ {
+		r := -autoc[i+1]
+		for j := 0; j < i; j++ {
+			r -= lpc[j] * autoc[i-j]
+		}
+		r /= err
+
+		lpc[i] = r
+		for j := 0; j < i/2; j++ {
+			tmp := lpc[j]
+			lpc[j] += r * lpc[i-1-j]
+			lpc[i-1-j] += r * tmp
+		}
+		if i%2 != 0 {
+			lpc[i/2] += lpc[i/2] * r
+		}
+		err *= 1 - r*r
+
+		switch order := i + 1; order {
+		case 2, 4, 6, 8, 12:
+			coefs := make([]float64, order)
+			for j := range coefs {
+				coefs[j] = -lpc[j]
+			}
+			if p := quantize(coefs); p != nil {
+				predictors = append(predictors, p)
+			}
+		}
+		if err <= 0 {
+			break
+		}
+	}
+	return predictors
+}
+
+// quantize returns a predictor with the coefficients rounded to
+// lpcPrecision bits, carrying the rounding error over to the next
+// coefficient.
+func quantize(coefs []float64) *predictor {
+	var max float64
+	for _, c := range coefs {
+		max = math.Max(max, math.Abs(c))
+	}
+	if max == 0 || math.IsNaN(max) || math.IsInf(max, 0) {
+		return nil
+	}
+	_, exp := math.Frexp(max)
+	shift := lpcPrecision - 1 - exp
+	if shift > 15 {
+		shift = 15
+	}
+	if shift < 0 {
+		return nil
+	}
+
+	limit := float64(int32(1) << (lpcPrecision - 1))
+	p := &predictor{coefs: make([]int32, len(coefs)), shift: shift}
+	var carry float64
+	for i, c := range coefs {
+		v := c*float64(int32(1)<<shift) + carry
+		q := math.Max(-limit, math.Min(limit-1, math.Round(v)))
+		carry = v - q
+		p.coefs[i] = int32(q)
+	}
+	return p
+}
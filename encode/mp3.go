@@ -0,0 +1,192 @@
+//go:build mp3
+// +build mp3
+
+package encode
+
+/*
+#cgo LDFLAGS: -lmp3lame
+
+#include <lame/lame.h>
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/icedream/go-dectalkdapi/container"
+)
+
+const mp3Available = true
+
+// mp3Quality is the variable bitrate quality passed to LAME, from 0 (best)
+// to 9, which keeps speech clear at about 32 kbps for 11.025 kHz.
+const mp3Quality = 6
+
+// mp3Encoder compresses samples with LAME.
+type mp3Encoder struct {
+	w      io.Writer
+	format container.Format
+	frames frames
+	gfp    *C.lame_global_flags
+
+	// seeker is set if the Xing header of the first frame can be completed
+	// on Close, start being the offset of the first frame.
+	seeker io.WriteSeeker
+	start  int64
+
+	floats []float64
+	conv   []byte
+	pcm    []C.short
+	buf    []byte
+	closed bool
+}
+
+func newMP3Encoder(w io.Writer, in container.Format, tags Tags) (io.WriteCloser, error) {
+	if in.Channels > 2 {
+		return nil, fmt.Errorf("%w: MP3 with %d channels", container.ErrUnsupportedFormat, in.Channels)
+	}
+	gfp := C.lame_init()
+	if gfp == nil {
+		return nil, errors.New("failed to initialize LAME")
+	}
+	e := &mp3Encoder{
+		w:      w,
+		format: in,
+		frames: frames{format: in},
+		gfp:    gfp,
+	}
+
+	tag := tags.id3v2()
+	if ws, ok := w.(io.WriteSeeker); ok {
+		if start, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			e.seeker, e.start = ws, start+int64(len(tag))
+		}
+	}
+
+	C.lame_set_in_samplerate(gfp, C.int(in.SampleRate))
+	C.lame_set_num_channels(gfp, C.int(in.Channels))
+	if in.Channels == 1 {
+		C.lame_set_mode(gfp, C.MONO)
+	}
+	C.lame_set_VBR(gfp, C.vbr_default)
+	C.lame_set_VBR_q(gfp, mp3Quality)
+	// The tags are written in Go, with UTF-8 text.
+	C.lame_set_write_id3tag_automatic(gfp, 0)
+	// The Xing header holding the length can only be written by seeking.
+	if e.seeker == nil {
+		C.lame_set_bWriteVbrTag(gfp, 0)
+	}
+	if ret := C.lame_init_params(gfp); ret < 0 {
+		C.lame_close(gfp)
+		return nil, fmt.Errorf("failed to initialize LAME with %v: error %d", in, int(ret))
+	}
+
+	if len(tag) > 0 {
+		if _, err := w.Write(tag); err != nil {
+			C.lame_close(gfp)
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (e *mp3Encoder) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errClosed
+	}
+	data := e.frames.split(p)
+	if e.format.Encoding != container.PCM16 {
+		e.floats = container.Decode(e.floats[:0], data, e.format.Encoding)
+		e.conv = container.Encode(e.conv[:0], e.floats, container.PCM16)
+		data = e.conv
+	}
+	e.pcm = e.pcm[:0]
+	for i := 0; i+2 <= len(data); i += 2 {
+		e.pcm = append(e.pcm, C.short(int16(binary.LittleEndian.Uint16(data[i:]))))
+	}
+	if len(e.pcm) == 0 {
+		return len(p), nil
+	}
+
+	samples := len(e.pcm) / e.format.Channels
+	// The worst case given by the documentation of LAME.
+	e.grow(samples*5/4 + 7200)
+	var n C.int
+	if e.format.Channels == 1 {
+		n = C.lame_encode_buffer(e.gfp, &e.pcm[0], &e.pcm[0], C.int(samples), e.out(), C.int(len(e.buf)))
+	} else {
+		n = C.lame_encode_buffer_interleaved(e.gfp, &e.pcm[0], C.int(samples), e.out(), C.int(len(e.buf)))
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("LAME failed to encode: error %d", int(n))
+	}
+	if _, err := e.w.Write(e.buf[:n]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the remaining samples and, if the destination can seek,
+// completes the Xing header.
+func (e *mp3Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	defer C.lame_close(e.gfp)
+
+	e.grow(7200)
+	n := C.lame_encode_flush(e.gfp, e.out(), C.int(len(e.buf)))
+	if n < 0 {
+		return fmt.Errorf("LAME failed to flush: error %d", int(n))
+	}
+	if _, err := e.w.Write(e.buf[:n]); err != nil {
+		return err
+	}
+	if err := e.frames.close(); err != nil {
+		return err
+	}
+	if e.seeker == nil {
+		return nil
+	}
+
+	size := C.lame_get_lametag_frame(e.gfp, e.out(), C.size_t(len(e.buf)))
+	if size == 0 || int(size) > len(e.buf) {
+		return nil
+	}
+	end, err := e.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := e.seeker.Seek(e.start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := e.seeker.Write(e.buf[:size]); err != nil {
+		return err
+	}
+	_, err = e.seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+// abort releases LAME without flushing it.
+func (e *mp3Encoder) abort() {
+	if e.closed {
+		return
+	}
+	e.closed = true
+	C.lame_close(e.gfp)
+}
+
+func (e *mp3Encoder) grow(n int) {
+	if len(e.buf) < n {
+		e.buf = make([]byte, n)
+	}
+}
+
+func (e *mp3Encoder) out() *C.uchar {
+	return (*C.uchar)(unsafe.Pointer(&e.buf[0]))
+}
@@ -0,0 +1,17 @@
+//go:build !mp3
+// +build !mp3
+
+package encode
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/icedream/go-dectalkdapi/container"
+)
+
+const mp3Available = false
+
+func newMP3Encoder(io.Writer, container.Format, Tags) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("%w: %v requires the mp3 build tag", ErrUnavailable, MP3)
+}
@@ -0,0 +1,170 @@
+//go:build opus
+// +build opus
+
+package encode
+
+/*
+#cgo pkg-config: libopusenc
+
+#include <stdint.h>
+#include <stdlib.h>
+#include <opusenc.h>
+
+// implemented in opus_callback.go
+extern int opusWriteCallback(void *user_data, unsigned char *ptr, opus_int32 len);
+extern int opusCloseCallback(void *user_data);
+
+static OggOpusEnc *create_encoder(uintptr_t handle, OggOpusComments *comments, opus_int32 rate, int channels, int *error) {
+	OpusEncCallbacks callbacks = {
+		(ope_write_func)opusWriteCallback,
+		(ope_close_func)opusCloseCallback,
+	};
+	// Mapping family 1 is needed for more than two channels.
+	return ope_encoder_create_callbacks(&callbacks, (void *)handle, comments, rate, channels, channels > 2, error);
+}
+
+// set_speech tunes the encoder for speech, as ope_encoder_ctl is variadic.
+static int set_speech(OggOpusEnc *enc, opus_int32 bitrate) {
+	int err = ope_encoder_ctl(enc, OPUS_SET_SIGNAL(OPUS_SIGNAL_VOICE));
+	if (err != OPE_OK) {
+		return err;
+	}
+	return ope_encoder_ctl(enc, OPUS_SET_BITRATE(bitrate));
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"io"
+	"runtime/cgo"
+	"unsafe"
+
+	"github.com/icedream/go-dectalkdapi/container"
+)
+
+const opusAvailable = true
+
+// opusBitrate is the bitrate per channel of Opus files, at which speech of
+// the engine is indistinguishable from the original.
+const opusBitrate = 24000
+
+// OpusError is an error code of libopusenc.
+type OpusError int
+
+func (e OpusError) Error() string {
+	return C.GoString(C.ope_strerror(C.int(e)))
+}
+
+// opusEncoder compresses samples with libopusenc, which resamples them to
+// 48 kHz and writes the Ogg pages through callbacks.
+type opusEncoder struct {
+	w      io.Writer
+	format container.Format
+	frames frames
+	enc    *C.OggOpusEnc
+	handle cgo.Handle
+
+	// err is the first error returned by w.
+	err error
+
+	floats []float64
+	pcm    []C.float
+	closed bool
+}
+
+func newOpusEncoder(w io.Writer, in container.Format, tags Tags) (io.WriteCloser, error) {
+	if in.Channels > 8 {
+		return nil, container.ErrUnsupportedFormat
+	}
+	comments := C.ope_comments_create()
+	if comments == nil {
+		return nil, errors.New("failed to allocate Opus comments")
+	}
+	defer C.ope_comments_destroy(comments)
+	for _, f := range tags.fields() {
+		tag, value := C.CString(f.vorbis), C.CString(f.value)
+		ret := C.ope_comments_add(comments, tag, value)
+		C.free(unsafe.Pointer(tag))
+		C.free(unsafe.Pointer(value))
+		if ret != C.OPE_OK {
+			return nil, OpusError(ret)
+		}
+	}
+
+	e := &opusEncoder{
+		w:      w,
+		format: in,
+		frames: frames{format: in},
+	}
+	e.handle = cgo.NewHandle(e)
+	var ret C.int
+	e.enc = C.create_encoder(C.uintptr_t(e.handle), comments, C.opus_int32(in.SampleRate), C.int(in.Channels), &ret)
+	if e.enc == nil {
+		e.handle.Delete()
+		return nil, OpusError(ret)
+	}
+	if ret := C.set_speech(e.enc, C.opus_int32(opusBitrate*in.Channels)); ret != C.OPE_OK {
+		e.destroy()
+		return nil, OpusError(ret)
+	}
+	return e, nil
+}
+
+func (e *opusEncoder) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errClosed
+	}
+	e.floats = container.Decode(e.floats[:0], e.frames.split(p), e.format.Encoding)
+	if len(e.floats) == 0 {
+		return len(p), nil
+	}
+	e.pcm = e.pcm[:0]
+	for _, x := range e.floats {
+		e.pcm = append(e.pcm, C.float(x))
+	}
+	ret := C.ope_encoder_write_float(e.enc, &e.pcm[0], C.int(len(e.pcm)/e.format.Channels))
+	if err := e.check(ret); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the remaining samples and the last Ogg page.
+func (e *opusEncoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	defer e.destroy()
+	if err := e.check(C.ope_encoder_drain(e.enc)); err != nil {
+		return err
+	}
+	return e.frames.close()
+}
+
+// abort releases the encoder without draining it.
+func (e *opusEncoder) abort() {
+	if e.closed {
+		return
+	}
+	e.closed = true
+	e.destroy()
+}
+
+// check returns the error of w behind a failed call, or the error code.
+func (e *opusEncoder) check(ret C.int) error {
+	switch {
+	case e.err != nil:
+		return e.err
+	case ret != C.OPE_OK:
+		return OpusError(ret)
+	}
+	return nil
+}
+
+func (e *opusEncoder) destroy() {
+	C.ope_encoder_destroy(e.enc)
+	e.enc = nil
+	e.handle.Delete()
+}
@@ -0,0 +1,38 @@
+//go:build opus
+// +build opus
+
+package encode
+
+/*
+#include <opusenc.h>
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"unsafe"
+)
+
+// opusWriteCallback passes an Ogg page from libopusenc to the writer of the
+// encoder. It returns non-zero to make libopusenc fail.
+//
+//export opusWriteCallback
+func opusWriteCallback(userData unsafe.Pointer, ptr *C.uchar, length C.opus_int32) C.int {
+	e := cgo.Handle(userData).Value().(*opusEncoder)
+	if e.err != nil {
+		return 1
+	}
+	if _, err := e.w.Write(unsafe.Slice((*byte)(ptr), int(length))); err != nil {
+		e.err = err
+		return 1
+	}
+	return 0
+}
+
+// opusCloseCallback is called by libopusenc at the end of the stream. The
+// writer is left open.
+//
+//export opusCloseCallback
+func opusCloseCallback(userData unsafe.Pointer) C.int {
+	return 0
+}
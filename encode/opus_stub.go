@@ -0,0 +1,17 @@
+//go:build !opus
+// +build !opus
+
+package encode
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/icedream/go-dectalkdapi/container"
+)
+
+const opusAvailable = false
+
+func newOpusEncoder(io.Writer, container.Format, Tags) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("%w: %v requires the opus build tag", ErrUnavailable, Opus)
+}
@@ -0,0 +1,88 @@
+package encode
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Tags holds the metadata stored in a file, see [dectalkdapi.TTS.SynthesizeEncodedTo].
+// Empty fields are left out.
+type Tags struct {
+	// Text is the text that was spoken, stored as the title.
+	Text string
+
+	// Speaker is the name of the voice, stored as the artist.
+	Speaker string
+
+	// Version is the version of the engine, stored as the encoder.
+	Version string
+}
+
+// vendor names the program in FLAC and Opus files.
+const vendor = "go-dectalkdapi"
+
+// fields returns the tags as Vorbis comment fields and ID3v2 frame IDs.
+func (t Tags) fields() []tagField {
+	var fields []tagField
+	for _, f := range []tagField{
+		{"TITLE", "TIT2", t.Text},
+		{"ARTIST", "TPE1", t.Speaker},
+		{"ENCODER", "TSSE", t.Version},
+	} {
+		if f.value != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+type tagField struct {
+	vorbis, id3 string
+	value       string
+}
+
+// vorbisComment returns the tags as a Vorbis comment block as used by FLAC,
+// without the framing bit of Vorbis.
+func (t Tags) vorbisComment() []byte {
+	var b bytes.Buffer
+	fields := t.fields()
+	_ = binary.Write(&b, binary.LittleEndian, uint32(len(vendor)))
+	b.WriteString(vendor)
+	_ = binary.Write(&b, binary.LittleEndian, uint32(len(fields)))
+	for _, f := range fields {
+		_ = binary.Write(&b, binary.LittleEndian, uint32(len(f.vorbis)+1+len(f.value)))
+		b.WriteString(f.vorbis)
+		b.WriteByte('=')
+		b.WriteString(f.value)
+	}
+	return b.Bytes()
+}
+
+// id3v2 returns the tags as an ID3v2.4 tag with UTF-8 text frames, or nil if
+// all tags are empty.
+func (t Tags) id3v2() []byte {
+	fields := t.fields()
+	if len(fields) == 0 {
+		return nil
+	}
+	var frames bytes.Buffer
+	for _, f := range fields {
+		frames.WriteString(f.id3)
+		frames.Write(synchsafe(1 + len(f.value)))
+		frames.Write([]byte{0, 0}) // flags
+		frames.WriteByte(3)        // UTF-8
+		frames.WriteString(f.value)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("ID3")
+	b.Write([]byte{4, 0, 0}) // version 2.4.0, no flags
+	b.Write(synchsafe(frames.Len()))
+	frames.WriteTo(&b)
+	return b.Bytes()
+}
+
+// synchsafe encodes a size as a 28-bit synchsafe integer of ID3v2.
+func synchsafe(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
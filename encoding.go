@@ -0,0 +1,325 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi/normalize"
+)
+
+// Unrepresentable selects what an [Encoder] does with characters that the
+// code page of the engine cannot represent.
+type Unrepresentable int32
+
+const (
+	// Transliterate replaces a character with the closest representable
+	// characters, such as "ł" with "l" or "“" with a plain quote, or with its
+	// name in the language if there is no such replacement. Characters that
+	// have neither are dropped. This is the default.
+	Transliterate Unrepresentable = iota
+
+	// SpellOut replaces a character with its name in the language, such as
+	// "€" with "euro". Characters without a name are transliterated, or
+	// spelled as their Unicode code point, for example "U+4E2D".
+	SpellOut
+
+	// Drop removes the character.
+	Drop
+
+	// Reject fails with an [*EncodingError].
+	Reject
+)
+
+// EncodingError is returned for a character that cannot be represented in the
+// code page of the engine when [Reject] is used.
+type EncodingError struct {
+	// Offset is the byte offset of the character in the text.
+	Offset int
+
+	// Rune is the character.
+	Rune rune
+
+	// Language is the language the text was encoded for.
+	Language string
+}
+
+func (e *EncodingError) Error() string {
+	return fmt.Sprintf("character %q (%U) at offset %d can not be represented for language %q",
+		e.Rune, e.Rune, e.Offset, e.Language)
+}
+
+func (e *EncodingError) Unwrap() error {
+	return ErrUnrepresentable
+}
+
+// Encoder converts UTF-8 text to the single-byte code page expected by the
+// engine for a language. All languages of the DECtalk engine (us, uk, gr, sp,
+// la, fr, it) use ISO 8859-1 (Latin-1), but differ in how characters outside
+// of it are spelled out.
+//
+// Decomposed characters, such as "e" followed by a combining acute accent, are
+// composed where Latin-1 has a matching character.
+type Encoder struct {
+	// Language is the 2-character language ID as accepted by [StartLang]. An
+	// empty or unknown language is handled like "us".
+	Language string
+
+	// Unrepresentable selects what happens with characters outside the code
+	// page.
+	Unrepresentable Unrepresentable
+}
+
+// Encode converts text to the code page of the engine.
+func (e Encoder) Encode(text string) ([]byte, error) {
+	b := make([]byte, 0, len(text))
+	for offset, r := range text {
+		if r < 0x100 {
+			b = append(b, byte(r))
+			continue
+		}
+		if composed, ok := compose(b, r); ok {
+			b[len(b)-1] = composed
+			continue
+		}
+		replacement, err := e.replace(r)
+		if err != nil {
+			err.Offset = offset
+			return nil, err
+		}
+		b = append(b, replacement...)
+	}
+	return b, nil
+}
+
+// EncodeRune converts a single character to the code page of the engine. The
+// result may be empty or longer than a byte, depending on
+// [Encoder.Unrepresentable].
+func (e Encoder) EncodeRune(r rune) ([]byte, error) {
+	if r >= 0 && r < 0x100 {
+		return []byte{byte(r)}, nil
+	}
+	replacement, err := e.replace(r)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(replacement), nil
+}
+
+// typingCharacter returns the single character of the code page of the engine
+// that [TTS.Typing] sends for r. Only the padding around a replacement is
+// trimmed, so that white space itself can be typed.
+func (e Encoder) typingCharacter(r rune) (byte, bool) {
+	encoded, err := e.EncodeRune(r)
+	if len(encoded) > 1 {
+		encoded = bytes.TrimSpace(encoded)
+	}
+	if err != nil || len(encoded) != 1 {
+		return 0, false
+	}
+	return encoded[0], true
+}
+
+// replace returns the Latin-1 replacement for a character outside of Latin-1,
+// including invalid UTF-8 decoded as utf8.RuneError.
+func (e Encoder) replace(r rune) (string, *EncodingError) {
+	if isCombining(r) && e.Unrepresentable != Reject {
+		// A leftover combining mark only decorates the previous
+		// character.
+		return "", nil
+	}
+
+	switch e.Unrepresentable {
+	case Transliterate:
+		if s, ok := transliterate(r); ok {
+			return latin1(s), nil
+		}
+		if s, ok := e.name(r); ok {
+			return latin1(" " + s + " "), nil
+		}
+		return "", nil
+	case SpellOut:
+		if s, ok := e.name(r); ok {
+			return latin1(" " + s + " "), nil
+		}
+		if s, ok := transliterate(r); ok {
+			return latin1(s), nil
+		}
+		return fmt.Sprintf(" %U ", r), nil
+	case Drop:
+		return "", nil
+	}
+	return "", &EncodingError{Rune: r, Language: e.Language}
+}
+
+// name returns the name of a character in the language of the encoder.
+func (e Encoder) name(r rune) (string, bool) {
+	names, ok := characterNames[strings.ToLower(e.Language)]
+	if !ok {
+		names = characterNames["us"]
+	}
+	s, ok := names[r]
+	return s, ok
+}
+
+// latin1 converts a replacement from the tables below, which only hold
+// characters of Latin-1, from UTF-8 to Latin-1.
+func latin1(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		b = append(b, byte(r))
+	}
+	return string(b)
+}
+
+func isCombining(r rune) bool {
+	return r >= 0x0300 && r <= 0x036f
+}
+
+// compositions maps a combining mark to the base letters it composes with and
+// the resulting Latin-1 letters.
+var compositions = map[rune][2]string{
+	0x0300: {"AEIOUaeiou", "ÀÈÌÒÙàèìòù"},
+	0x0301: {"AEIOUYaeiouy", "ÁÉÍÓÚÝáéíóúý"},
+	0x0302: {"AEIOUaeiou", "ÂÊÎÔÛâêîôû"},
+	0x0303: {"ANOano", "ÃÑÕãñõ"},
+	0x0308: {"AEIOUaeiouy", "ÄËÏÖÜäëïöüÿ"},
+	0x030a: {"Aa", "Åå"},
+	0x0327: {"Cc", "Çç"},
+}
+
+// compose combines a combining mark with the last encoded letter, if Latin-1
+// has a matching character.
+func compose(b []byte, mark rune) (byte, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	letters, ok := compositions[mark]
+	if !ok {
+		return 0, false
+	}
+	i := strings.IndexByte(letters[0], b[len(b)-1])
+	if i < 0 {
+		return 0, false
+	}
+	return byte([]rune(letters[1])[i]), true
+}
+
+// latinExtendedA holds the transliterations of U+0100 to U+017F.
+var latinExtendedA = strings.Fields(`
+	A a A a A a C c C c C c C c D d D d E e E e E e E e E e G g G g G g G g
+	H h H h I i I i I i I i I i IJ ij J j K k k L l L l L l L l L l N n N n
+	N n n N n O o O o Ö ö OE oe R r R r R r S s S s S s S s T t T t T t U u
+	U u U u U u Ü ü U u W w Y y Y Z z Z z Z z s
+`)
+
+// transliterations holds replacements for characters outside of Latin
+// Extended-A.
+var transliterations = map[rune]string{
+	'ẞ': "SS",
+	'ƒ': "f",
+	'Ș': "S", 'ș': "s", 'Ț': "T", 'ț': "t",
+	'‘': "'", '’': "'", '‚': "'", '‛': "'", '′': "'",
+	'“': `"`, '”': `"`, '„': `"`, '‟': `"`, '″': `"`,
+	'‹': "<", '›': ">",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-", '−': "-",
+	'…': "...", '•': "-",
+	'\u2002': " ", '\u2003': " ", '\u2009': " ", '\u202f': " ", '\u3000': " ",
+	'\u200b': "", '\u200c': "", '\u200d': "", '\ufeff': "",
+}
+
+func transliterate(r rune) (string, bool) {
+	if r >= 0x100 && r < 0x180 {
+		return latinExtendedA[r-0x100], true
+	}
+	s, ok := transliterations[r]
+	return s, ok
+}
+
+// characterNames holds the names of common characters outside of Latin-1 per
+// language.
+var characterNames = map[string]map[rune]string{
+	"us": englishNames,
+	"uk": englishNames,
+	"gr": {
+		'€': "Euro", '™': "Trademark", '•': "Punkt", '→': "Pfeil", '←': "Pfeil",
+		'≤': "kleiner gleich", '≥': "größer gleich", '≠': "ungleich", '∞': "unendlich",
+		'α': "Alpha", 'β': "Beta", 'γ': "Gamma", 'δ': "Delta", 'π': "Pi", 'Ω': "Omega",
+	},
+	"sp": spanishNames,
+	"la": spanishNames,
+	"fr": {
+		'€': "euro", '™': "marque", '•': "puce", '→': "flèche", '←': "flèche",
+		'≤': "inférieur ou égal", '≥': "supérieur ou égal", '≠': "différent", '∞': "infini",
+		'α': "alpha", 'β': "bêta", 'γ': "gamma", 'δ': "delta", 'π': "pi", 'Ω': "oméga",
+	},
+	"it": {
+		'€': "euro", '™': "marchio", '•': "punto", '→': "freccia", '←': "freccia",
+		'≤': "minore o uguale", '≥': "maggiore o uguale", '≠': "diverso", '∞': "infinito",
+		'α': "alfa", 'β': "beta", 'γ': "gamma", 'δ': "delta", 'π': "pi greco", 'Ω': "omega",
+	},
+}
+
+var englishNames = map[rune]string{
+	'€': "euro", '™': "trademark", '•': "bullet", '→': "arrow", '←': "arrow",
+	'≤': "less than or equal to", '≥': "greater than or equal to", '≠': "not equal to", '∞': "infinity",
+	'α': "alpha", 'β': "beta", 'γ': "gamma", 'δ': "delta", 'π': "pi", 'Ω': "omega",
+}
+
+var spanishNames = map[rune]string{
+	'€': "euro", '™': "marca registrada", '•': "viñeta", '→': "flecha", '←': "flecha",
+	'≤': "menor o igual que", '≥': "mayor o igual que", '≠': "distinto de", '∞': "infinito",
+	'α': "alfa", 'β': "beta", 'γ': "gamma", 'δ': "delta", 'π': "pi", 'Ω': "omega",
+}
+
+// Encoder returns the encoder used by [TTS.Speak] and [TTS.Typing], which
+// encodes for the language returned by [TTS.Language].
+func (t *TTS) Encoder() Encoder {
+	var lang string
+	if t.lang != nil {
+		lang = t.lang.Name()
+	}
+	return Encoder{
+		Language:        lang,
+		Unrepresentable: Unrepresentable(t.unrepresentable.Load()),
+	}
+}
+
+// SetUnrepresentable selects what [TTS.Speak] and [TTS.Typing] do with
+// characters that the code page of the engine cannot represent. The default is
+// [Transliterate].
+func (t *TTS) SetUnrepresentable(u Unrepresentable) {
+	t.unrepresentable.Store(int32(u))
+}
+
+// SetNormalizer sets a normalizer that rewrites all text passed to
+// [TTS.Speak] for the language returned by [TTS.Language], such as
+// [normalize.New]. Without one, the language of the engine build reported by
+// [VersionEx] is used; if that is not known either, the text is left as it is
+// rather than normalized for the wrong language. A nil normalizer, the
+// default, leaves the text as it is.
+func (t *TTS) SetNormalizer(n *normalize.Normalizer) {
+	t.normalizer.Store(n)
+}
+
+// normalizerLanguage returns the language ID to normalize text for, or "" if
+// it is not known.
+func (t *TTS) normalizerLanguage() string {
+	if t.lang != nil {
+		return t.lang.Name()
+	}
+	ver, err := VersionEx()
+	if err != nil {
+		return ""
+	}
+	lang := strings.ToLower(strings.TrimSpace(ver.Language()))
+	for _, l := range normalize.Languages() {
+		if l == lang {
+			return lang
+		}
+	}
+	return ""
+}
@@ -1,5 +1,7 @@
 package dectalkdapi
 
+import "errors"
+
 type MMError struct {
 	Code MMResult
 }
@@ -7,3 +9,55 @@ type MMError struct {
 func (err *MMError) Error() string {
 	return mmErrorMessages[err.Code]
 }
+
+var (
+	// ErrBufferInUse is returned when a speech-to-memory buffer is added
+	// while the engine still owns it.
+	ErrBufferInUse = errors.New("buffer is already in use by the engine")
+
+	// ErrTooManyBuffers is returned when more than [MaxBuffers]
+	// speech-to-memory buffers would be in use at once.
+	ErrTooManyBuffers = errors.New("too many buffers in use")
+
+	// ErrNoLanguages is returned by [EnumLangs] when no language information
+	// is available.
+	ErrNoLanguages = errors.New("no languages available")
+
+	// ErrShutdown is returned when a [TTS] is used after [TTS.Shutdown].
+	ErrShutdown = errors.New("text-to-speech system has been shut down")
+
+	// ErrWrongMode is matched by every [*ModeError].
+	ErrWrongMode = errors.New("not allowed in current mode")
+
+	// ErrResetRequired is returned by [TTS.CloseInMemory] when text may
+	// still be processed, since closing would risk a deadlock.
+	ErrResetRequired = errors.New("reset or sync required before closing speech-to-memory mode")
+
+	// ErrPoolExhausted is returned by [Pool.Get] when all instances are in
+	// use and [PoolConfig.FailFast] is set.
+	ErrPoolExhausted = errors.New("no text-to-speech instance available")
+
+	// ErrPoolClosed is returned when a [Pool] is used after [Pool.Close].
+	ErrPoolClosed = errors.New("pool has been closed")
+
+	// ErrNotFromPool is returned by [Pool.Put] for an instance that has not
+	// been taken from the pool with [Pool.Get], or has been put back already.
+	ErrNotFromPool = errors.New("instance has not been taken from the pool")
+
+	// ErrDictionaryManagerRunning is returned by [NewDictionaryManager] while
+	// another [DictionaryManager] has not been closed.
+	ErrDictionaryManagerRunning = errors.New("dictionary manager is already running")
+
+	// ErrNoDictionaryCompiler is returned by [NewDictionaryManager] for a
+	// source that needs to be compiled without a
+	// [DictionaryManagerConfig.Compiler].
+	ErrNoDictionaryCompiler = errors.New("no dictionary compiler configured")
+
+	// ErrDictionaryManagerClosed is returned when a [DictionaryManager] is
+	// used after [DictionaryManager.Close].
+	ErrDictionaryManagerClosed = errors.New("dictionary manager has been closed")
+
+	// ErrUnrepresentable is wrapped by [*EncodingError] when text contains
+	// characters that the code page of the engine cannot represent.
+	ErrUnrepresentable = errors.New("character can not be represented")
+)
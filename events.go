@@ -0,0 +1,133 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+import "sync"
+
+// Event is a notification sent by the text-to-speech system of an instance
+// started with [StartupWithEvents]. It is one of [*BufferFilledEvent],
+// [*IndexMarkEvent], [*StatusEvent] or [*ErrorEvent].
+type Event interface {
+	isEvent()
+}
+
+// BufferFilledEvent is sent when the engine hands back a speech-to-memory
+// buffer, see [TTS.OpenInMemory]. The buffer itself is received from
+// [TTS.Buffers]; the event carries a copy of its metadata.
+type BufferFilledEvent struct {
+	// Length is the number of bytes of speech samples in the buffer.
+	Length int
+
+	// Phonemes holds the phoneme changes recorded in the buffer.
+	Phonemes []PhonemeMark
+
+	// IndexMarks holds the index marks recorded in the buffer.
+	IndexMarks []IndexMark
+}
+
+// IndexMarkEvent is sent when the audio output reaches an index mark that was
+// placed in the text using the [:index mark] inline command.
+type IndexMarkEvent struct {
+	// Value is the number given in the index mark command.
+	Value uint32
+}
+
+// StatusEvent is sent when the status of the text-to-speech system changes.
+// The parameters are passed on from the engine as-is.
+type StatusEvent struct {
+	Param1 int64
+	Param2 int64
+}
+
+// ErrorEvent is sent when the text-to-speech system encounters an error while
+// processing queued text, for example when the audio device fails.
+type ErrorEvent struct {
+	Err error
+}
+
+func (*BufferFilledEvent) isEvent() {}
+func (*IndexMarkEvent) isEvent()    {}
+func (*StatusEvent) isEvent()       {}
+func (*ErrorEvent) isEvent()        {}
+
+// StartupWithEvents starts up the text-to-speech system like [Startup] and
+// additionally returns a channel that receives the notifications sent by the
+// engine while the instance is running.
+//
+// Events are queued without limit so the engine never waits for the
+// application; they should still be received continuously. The channel is
+// closed after [TTS.Shutdown] once all queued events have been received.
+func StartupWithEvents(deviceOptions DeviceOption) (*TTS, <-chan Event, error) {
+	tts := new(TTS)
+	tts.events = newEventQueue()
+	if err := tts.startup(deviceOptions); err != nil {
+		tts.events.close()
+		return nil, nil, err
+	}
+	return tts, tts.events.out, nil
+}
+
+// eventQueue passes events from the engine callback to a channel without ever
+// blocking the callback.
+type eventQueue struct {
+	mu      sync.Mutex
+	pending []Event
+	closed  bool
+
+	wake chan struct{}
+	out  chan Event
+}
+
+func newEventQueue() *eventQueue {
+	q := &eventQueue{
+		wake: make(chan struct{}, 1),
+		out:  make(chan Event),
+	}
+	go q.run()
+	return q
+}
+
+func (q *eventQueue) push(e Event) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.pending = append(q.pending, e)
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *eventQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *eventQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *eventQueue) run() {
+	defer close(q.out)
+	for range q.wake {
+		q.mu.Lock()
+		pending, closed := q.pending, q.closed
+		q.pending = nil
+		q.mu.Unlock()
+
+		for _, e := range pending {
+			q.out <- e
+		}
+		// Nothing can be pushed after close, so everything has been
+		// delivered at this point.
+		if closed {
+			return
+		}
+	}
+}
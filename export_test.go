@@ -0,0 +1,9 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+// TypingCharacter exposes Encoder.typingCharacter to the tests.
+func TypingCharacter(e Encoder, r rune) (byte, bool) {
+	return e.typingCharacter(r)
+}
@@ -0,0 +1,353 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+/*
+#if defined WIN32
+#include <windows.h>
+#include <TTSAPI.H>
+#else
+#include <stdlib.h>
+#include <dtk/ttsapi.h>
+#endif
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// MaxBuffers is the maximum number of speech-to-memory buffers that can be
+// handed to a single [TTS] at once, counting buffers that have been filled but
+// not yet received from [TTS.Buffers].
+const MaxBuffers = 64
+
+// PhonemeMark describes a phoneme change within a speech-to-memory buffer.
+type PhonemeMark struct {
+	// Phoneme is the engine's numeric code of the phoneme.
+	Phoneme uint32
+
+	// SampleNumber is the sample at which the phoneme starts, counted from
+	// the start of the text-to-speech output.
+	SampleNumber uint32
+
+	// Duration is the length of the phoneme in samples.
+	Duration uint32
+}
+
+// IndexMark describes an index mark (the [:index mark] inline command) that
+// was reached within a speech-to-memory buffer.
+type IndexMark struct {
+	// Value is the number given in the index mark command.
+	Value uint32
+
+	// SampleNumber is the sample at which the index mark was reached,
+	// counted from the start of the text-to-speech output.
+	SampleNumber uint32
+}
+
+// Buffer is a buffer for speech samples produced in speech-to-memory mode,
+// see [TTS.OpenInMemory].
+//
+// The memory backing a Buffer is allocated outside of the Go heap since the
+// engine keeps using it after [TTS.AddBuffer] returns. A Buffer must be
+// released with [Buffer.Free] once it is no longer needed and must not be
+// freed while it is still owned by the engine.
+type Buffer struct {
+	c *C.TTS_BUFFER_T
+}
+
+// NewBuffer allocates a speech-to-memory buffer that can hold up to length
+// bytes of speech samples, phonemes phoneme changes and indexMarks index
+// marks.
+func NewBuffer(length, phonemes, indexMarks int) *Buffer {
+	c := (*C.TTS_BUFFER_T)(C.calloc(1, C.sizeof_TTS_BUFFER_T))
+	c.lpData = (*C.char)(C.calloc(C.size_t(length)+1, 1))
+	c.dwMaximumBufferLength = C.DWORD(length)
+	if phonemes > 0 {
+		c.lpPhonemeArray = (C.LPTTS_PHONEME_T)(C.calloc(C.size_t(phonemes), C.sizeof_TTS_PHONEME_T))
+		c.dwMaximumNumberOfPhonemeChanges = C.DWORD(phonemes)
+	}
+	if indexMarks > 0 {
+		c.lpIndexArray = (C.LPTTS_INDEX_T)(C.calloc(C.size_t(indexMarks), C.sizeof_TTS_INDEX_T))
+		c.dwMaximumNumberOfIndexMarks = C.DWORD(indexMarks)
+	}
+	return &Buffer{c: c}
+}
+
+// Free releases the memory of the buffer. The buffer must not be used
+// afterwards.
+func (b *Buffer) Free() {
+	if b.c == nil {
+		return
+	}
+	C.free(unsafe.Pointer(b.c.lpData))
+	C.free(unsafe.Pointer(b.c.lpPhonemeArray))
+	C.free(unsafe.Pointer(b.c.lpIndexArray))
+	C.free(unsafe.Pointer(b.c))
+	b.c = nil
+}
+
+// Cap returns the maximum number of bytes of speech samples the buffer can
+// hold.
+func (b *Buffer) Cap() int {
+	return int(b.c.dwMaximumBufferLength)
+}
+
+// Len returns the number of bytes of speech samples the engine has written
+// into the buffer.
+func (b *Buffer) Len() int {
+	return int(b.c.dwBufferLength)
+}
+
+// Data returns a copy of the speech samples the engine has written into the
+// buffer, in the format passed to [TTS.OpenInMemory].
+func (b *Buffer) Data() []byte {
+	return C.GoBytes(unsafe.Pointer(b.c.lpData), C.int(b.c.dwBufferLength))
+}
+
+// Phonemes returns the phoneme changes the engine has recorded in the buffer.
+func (b *Buffer) Phonemes() []PhonemeMark {
+	n := int(b.c.dwNumberOfPhonemeChanges)
+	if n == 0 || b.c.lpPhonemeArray == nil {
+		return nil
+	}
+	phonemes := make([]PhonemeMark, n)
+	for i, p := range unsafe.Slice(b.c.lpPhonemeArray, n) {
+		phonemes[i] = PhonemeMark{
+			Phoneme:      uint32(p.dwPhoneme),
+			SampleNumber: uint32(p.dwPhonemeSampleNumber),
+			Duration:     uint32(p.dwPhonemeDuration),
+		}
+	}
+	return phonemes
+}
+
+// IndexMarks returns the index marks the engine has recorded in the buffer.
+func (b *Buffer) IndexMarks() []IndexMark {
+	n := int(b.c.dwNumberOfIndexMarks)
+	if n == 0 || b.c.lpIndexArray == nil {
+		return nil
+	}
+	marks := make([]IndexMark, n)
+	for i, m := range unsafe.Slice(b.c.lpIndexArray, n) {
+		marks[i] = IndexMark{
+			Value:        uint32(m.dwIndexValue),
+			SampleNumber: uint32(m.dwIndexSampleNumber),
+		}
+	}
+	return marks
+}
+
+// reset marks the buffer as empty so it can be handed to the engine again.
+func (b *Buffer) reset() {
+	b.c.dwBufferLength = 0
+	b.c.dwNumberOfPhonemeChanges = 0
+	b.c.dwNumberOfIndexMarks = 0
+}
+
+func (b *Buffer) key() uintptr {
+	return uintptr(unsafe.Pointer(b.c))
+}
+
+// memoryState keeps track of the buffers that have been handed to the engine
+// in speech-to-memory mode.
+type memoryState struct {
+	mu sync.Mutex
+
+	// owned holds the buffers currently owned by the engine, keyed by the
+	// address of their C struct.
+	owned map[uintptr]*Buffer
+
+	// done receives buffers that the engine has handed back.
+	done chan *Buffer
+
+	// generation is increased whenever the engine releases all buffers, so
+	// that buffers taken before are not handed back afterwards.
+	generation uint64
+}
+
+func (m *memoryState) init() {
+	m.owned = map[uintptr]*Buffer{}
+	m.done = make(chan *Buffer, MaxBuffers)
+}
+
+func (m *memoryState) add(b *Buffer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.owned[b.key()]; ok {
+		return ErrBufferInUse
+	}
+	// Every buffer handed to the engine must fit into done once it comes
+	// back, otherwise the engine callback would block.
+	if len(m.owned)+len(m.done) >= cap(m.done) {
+		return ErrTooManyBuffers
+	}
+	m.owned[b.key()] = b
+	return nil
+}
+
+// take removes the buffer with the given address from the set of buffers
+// owned by the engine. It also returns the generation to pass to handBack.
+func (m *memoryState) take(key uintptr) (*Buffer, uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.owned[key]
+	if !ok {
+		return nil, m.generation
+	}
+	delete(m.owned, key)
+	return b, m.generation
+}
+
+// handBack queues a buffer taken from the engine for [TTS.Buffers], unless
+// the engine has released all buffers since it was taken, in which case the
+// buffer may already have been freed.
+func (m *memoryState) handBack(b *Buffer, generation uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if generation != m.generation {
+		return
+	}
+	// add makes sure that there is room for every buffer owned by the
+	// engine.
+	select {
+	case m.done <- b:
+	default:
+	}
+}
+
+// clear forgets all buffers once the engine has released them, including the
+// ones handed back but not yet received from [TTS.Buffers], since the
+// application may free them from now on.
+func (m *memoryState) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.owned = map[uintptr]*Buffer{}
+	m.generation++
+	for {
+		select {
+		case <-m.done:
+		default:
+			return
+		}
+	}
+}
+
+// OpenInMemory causes the text-to-speech system to enter into
+// speech-to-memory mode. In this mode, the speech samples are written in the
+// given format into the buffers supplied with [TTS.AddBuffer] each time
+// [TTS.Speak] is called, instead of being played or written to a file. Each
+// buffer the engine has filled is handed back through [TTS.Buffers]. The
+// text-to-speech system remains in speech-to-memory mode until
+// [TTS.CloseInMemory] is called.
+//
+// This function automatically resumes audio output if the text-to-speech system
+// is in a paused state by a previously issued [TTS.Pause] call.
+//
+// The text-to-speech system must be in the startup state, otherwise a
+// [*ModeError] is returned.
+func (t *TTS) OpenInMemory(format WaveFormat) error {
+	if err := t.state.enterExclusive("OpenInMemory", ModeStartup); err != nil {
+		return err
+	}
+	defer t.state.leaveExclusive()
+
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechOpenInMemory(t.handle, C.DWORD(format))
+	})
+	if err != nil {
+		return err
+	}
+	t.state.set(ModeInMemory)
+	t.paused.Store(false)
+	return nil
+}
+
+// AddBuffer adds a buffer to the list of buffers the engine writes speech
+// samples to in speech-to-memory mode. The buffer is owned by the engine until
+// it comes back through [TTS.Buffers] or [TTS.ReturnBuffer]; it must neither be
+// modified nor freed in the meantime.
+//
+// Buffers that have been handed back may be added again.
+func (t *TTS) AddBuffer(b *Buffer) error {
+	if err := t.state.enter("AddBuffer", ModeInMemory); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	b.reset()
+	if err := t.memory.add(b); err != nil {
+		return err
+	}
+	// This bypasses the worker thread of StartupLocked, which is blocked
+	// while SynthesizeTo waits in Sync for the engine to fill the buffers
+	// being handed back here.
+	if err := mmResultToError(C.TextToSpeechAddBuffer(t.handle, b.c)); err != nil {
+		t.memory.take(b.key())
+		return err
+	}
+	return nil
+}
+
+// ReturnBuffer takes back the buffer the engine is currently writing to, even
+// if it is only partially filled. This is typically called after [TTS.Sync]
+// to receive the remaining speech samples.
+//
+// If the engine is not currently writing to any buffer, nil is returned.
+func (t *TTS) ReturnBuffer() (*Buffer, error) {
+	if err := t.state.enter("ReturnBuffer", ModeInMemory); err != nil {
+		return nil, err
+	}
+	defer t.state.leave()
+
+	var c C.LPTTS_BUFFER_T
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechReturnBuffer(t.handle, &c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, nil
+	}
+	b, _ := t.memory.take(uintptr(unsafe.Pointer(c)))
+	return b, nil
+}
+
+// Buffers returns the channel through which the engine hands back the buffers
+// it has filled in speech-to-memory mode, in the order they were filled.
+// Buffers flushed by [TTS.Reset] are handed back through this channel as well.
+func (t *TTS) Buffers() <-chan *Buffer {
+	return t.memory.done
+}
+
+// CloseInMemory ends speech-to-memory mode and returns to the startup state.
+// Buffers still held by the engine are released by it and may be reused or
+// freed afterwards. Buffers that have been handed back but not yet received
+// from [TTS.Buffers] are dropped from the channel.
+//
+// [TTS.Reset] or [TTS.Sync] must be called between the last call to
+// [TTS.Speak] and CloseInMemory, since closing while the synthesizer is busy may
+// result in a deadlock. Otherwise, [ErrResetRequired] is returned.
+func (t *TTS) CloseInMemory() error {
+	if err := t.state.enterExclusive("CloseInMemory", ModeInMemory); err != nil {
+		return err
+	}
+	defer t.state.leaveExclusive()
+
+	if t.state.busy.Load() {
+		return ErrResetRequired
+	}
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechCloseInMemory(t.handle)
+	})
+	if err != nil {
+		return err
+	}
+	t.memory.clear()
+	t.state.set(ModeStartup)
+	return nil
+}
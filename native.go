@@ -12,7 +12,21 @@ package dectalkdapi
 #include <windows.h>
 #include <TTSAPI.H>
 
-const HWND ZeroHWND = NULL;
+static UINT BufferMessage(void) {
+	return RegisterWindowMessage("DECtalkBufferMessage");
+}
+
+static UINT IndexMessage(void) {
+	return RegisterWindowMessage("DECtalkIndexMessage");
+}
+
+static UINT StatusMessage(void) {
+	return RegisterWindowMessage("DECtalkStatusMessage");
+}
+
+static UINT ErrorMessage(void) {
+	return RegisterWindowMessage("DECtalkErrorMessage");
+}
 
 #else
 
@@ -22,70 +36,100 @@ const HWND ZeroHWND = NULL;
 // define constants that cgo can't use
 #define WAVERR_BADFORMAT 32
 
-// define structs that don't exist on linux
-typedef uint HWND;
-const HWND ZeroHWND = 0;
+static UINT BufferMessage(void) {
+	return TTS_MSG_BUFFER;
+}
 
-#endif
+static UINT IndexMessage(void) {
+	return TTS_MSG_INDEX_MARK;
+}
 
-// functions which have different signatures between linux and windows
-#if defined __linux__ || defined VXWORKS || defined _SPARC_SOLARIS_ || defined __osf__
-MMRESULT FixedTextToSpeechStartup(
-	HWND hWnd,
-	LPTTS_HANDLE_T * pphTTS,
-	UINT uiDeviceNumber,
-	DWORD dwDeviceOptions
-) {
-    // Linux code has a "New Audio Integration", hence the parameters are all over the place…
-	return TextToSpeechStartup(
-		pphTTS,
-		uiDeviceNumber,
-		dwDeviceOptions,
-		NULL,
-		hWnd // code mentioned "Backward compatibilty for TextToSpeechStartupEx" here
-	);
+static UINT StatusMessage(void) {
+	return TTS_MSG_STATUS;
 }
-#endif
-#ifdef WIN32
-MMRESULT FixedTextToSpeechStartup(HWND a, LPTTS_HANDLE_T *b, UINT c, DWORD d) {
-	return TextToSpeechStartup(a, b, c, d);
+
+static UINT ErrorMessage(void) {
+	return TTS_MSG_ERROR;
 }
+
 #endif
+
+// implemented in callback.go
+extern void dectalkCallback(LONG lParam1, LONG lParam2, DWORD dwCallbackParameter, UINT uiMsg);
 */
 import "C"
 
 import (
 	"errors"
+	"fmt"
+	"runtime/cgo"
+	"sync/atomic"
 	"unsafe"
+
+	"github.com/icedream/go-dectalkdapi/container"
+	"github.com/icedream/go-dectalkdapi/dsp"
+	"github.com/icedream/go-dectalkdapi/normalize"
 )
 
+// parseIntAsBool converts a BOOL returned by the engine, which is TRUE for any
+// value other than 0.
 func parseIntAsBool(value C.BOOL) bool {
-	return value == 0
+	return value != 0
 }
 
+// boolToInt converts a bool to the BOOL the engine expects.
 func boolToInt(value bool) C.BOOL {
 	if value {
-		return 0
+		return 1
 	}
-	return 1
+	return 0
 }
 
 const waveMapper = C.WAVE_MAPPER
 
+// Message IDs the engine passes to the callback, see callback.go.
+var (
+	bufferMessage = C.BufferMessage()
+	indexMessage  = C.IndexMessage()
+	statusMessage = C.StatusMessage()
+	errorMessage  = C.ErrorMessage()
+)
+
 type Speaker C.SPEAKER_T
 
 const (
-	Paul   = C.PAUL
-	Betty  = C.BETTY
-	Harry  = C.HARRY
-	Frank  = C.FRANK
-	Dennis = C.DENNIS
-	Kit    = C.KIT
-	Ursula = C.URSULA
-	Rita   = C.RITA
-	Wendy  = C.WENDY
+	Paul   Speaker = C.PAUL
+	Betty  Speaker = C.BETTY
+	Harry  Speaker = C.HARRY
+	Frank  Speaker = C.FRANK
+	Dennis Speaker = C.DENNIS
+	Kit    Speaker = C.KIT
+	Ursula Speaker = C.URSULA
+	Rita   Speaker = C.RITA
+	Wendy  Speaker = C.WENDY
 )
 
+var speakerNames = map[Speaker]string{
+	Paul:   "paul",
+	Betty:  "betty",
+	Harry:  "harry",
+	Frank:  "frank",
+	Dennis: "dennis",
+	Kit:    "kit",
+	Ursula: "ursula",
+	Rita:   "rita",
+	Wendy:  "wendy",
+}
+
+// String returns the name of the speaker as used by the Name inline command,
+// for example "paul".
+func (s Speaker) String() string {
+	if name, ok := speakerNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Speaker(%d)", int(s))
+}
+
 type Log C.DWORD
 
 const (
@@ -201,15 +245,53 @@ type WaveFormat C.DWORD
 
 const (
 	// Mono, 8-bit 11.025 kHz sample rate
-	WaveFormat1M08 = C.WAVE_FORMAT_1M08
+	WaveFormat1M08 WaveFormat = C.WAVE_FORMAT_1M08
 
 	// Mono, 16-bit 11.025 kHz sample rate
-	WaveFormat1M16 = C.WAVE_FORMAT_1M16
+	WaveFormat1M16 WaveFormat = C.WAVE_FORMAT_1M16
 
 	// Mono, 8-bit μ-law, 8 kHz sample rate
-	WaveFormat08M08 = C.WAVE_FORMAT_08M08
+	WaveFormat08M08 WaveFormat = C.WAVE_FORMAT_08M08
 )
 
+// SampleRate returns the number of samples per second of the format, or 0 for
+// unknown formats.
+func (f WaveFormat) SampleRate() int {
+	switch f {
+	case WaveFormat1M08, WaveFormat1M16:
+		return 11025
+	case WaveFormat08M08:
+		return 8000
+	}
+	return 0
+}
+
+// Format returns the description of the format used by the container package,
+// or the zero value for unknown formats.
+func (f WaveFormat) Format() container.Format {
+	switch f {
+	case WaveFormat1M08:
+		return container.Format1M08
+	case WaveFormat1M16:
+		return container.Format1M16
+	case WaveFormat08M08:
+		return container.Format08M08
+	}
+	return container.Format{}
+}
+
+// BytesPerSample returns the size of a single sample of the format in bytes,
+// or 0 for unknown formats.
+func (f WaveFormat) BytesPerSample() int {
+	switch f {
+	case WaveFormat1M08, WaveFormat08M08:
+		return 1
+	case WaveFormat1M16:
+		return 2
+	}
+	return 0
+}
+
 type TTSFlags C.DWORD
 
 const (
@@ -229,13 +311,13 @@ const (
 // TTSLanguage represents a language loaed into the DECtalk Multi-Language (ML)
 // engine.
 type TTSLanguage struct {
-	name   *C.char
+	name   string
 	handle C.uint
 }
 
 // Name returns the 2-character language ID.
 func (l *TTSLanguage) Name() string {
-	return C.GoString(l.name)
+	return l.name
 }
 
 // Close closes an instance for an installed language and attempts to unload it
@@ -244,26 +326,92 @@ func (l *TTSLanguage) Name() string {
 // Returns true when a language is successfully unloaded, or false when the
 // operation cannot be completed or more instances have the thread started.
 func (l *TTSLanguage) Close() bool {
-	return parseIntAsBool(C.TextToSpeechCloseLang(l.name))
+	nameC := C.CString(l.name)
+	defer C.free(unsafe.Pointer(nameC))
+
+	langMu.Lock()
+	defer langMu.Unlock()
+	if !parseIntAsBool(C.TextToSpeechCloseLang(nameC)) {
+		return false
+	}
+	selectedLang.CompareAndSwap(l, nil)
+	return true
 }
 
 type TTS struct {
 	handle C.LPTTS_HANDLE_T
-}
 
-// TODO - Add indicator to TTS for whether the engine is still active and check
-// it on calls that would otherwise segfault
+	// callback identifies this instance to the engine callback, see
+	// callback.go.
+	callback cgo.Handle
+
+	memory memoryState
+
+	// events is only set for instances started with StartupWithEvents.
+	events *eventQueue
+
+	// paused is set by Pause and cleared by all calls that resume audio
+	// output.
+	paused atomic.Bool
+
+	// lastError holds the MMResult code of the last error reported by the
+	// engine callback.
+	lastError atomic.Uint32
+
+	// state keeps calls that would crash or deadlock the engine from
+	// reaching it, see state.go.
+	state state
+
+	// worker is only set for instances started with StartupLocked.
+	worker *worker
+
+	// lang is the language the instance was started with, if known.
+	lang *TTSLanguage
+
+	// dictionary is the path of the user dictionary loaded through
+	// LoadUserDictionary.
+	dictionary atomic.Pointer[string]
+
+	// unrepresentable holds the Unrepresentable policy of the encoder used by
+	// Speak and Typing.
+	unrepresentable atomic.Int32
+
+	// normalizer rewrites the text passed to Speak, if set.
+	normalizer atomic.Pointer[normalize.Normalizer]
+
+	// processor post-processes the samples of SynthesizeTo, if set.
+	processor atomic.Pointer[dsp.Chain]
+
+	// dictionaryVersion is the version of the dictionary published by a
+	// DictionaryManager that has been loaded last.
+	dictionaryVersion atomic.Uint64
+}
 
 func Startup(deviceOptions DeviceOption) (*TTS, error) {
 	tts := new(TTS)
-	if err := tts.startup(C.ZeroHWND, deviceOptions); err != nil {
+	if err := tts.startup(deviceOptions); err != nil {
 		return nil, err
 	}
 	return tts, nil
 }
 
-func (t *TTS) startup(windowHandle C.HWND, deviceOptions DeviceOption) error {
-	return mmResultToError(C.FixedTextToSpeechStartup(windowHandle, &t.handle, waveMapper, C.DWORD(deviceOptions)))
+func (t *TTS) startup(deviceOptions DeviceOption) error {
+	if t.lang == nil {
+		t.lang = selectedLang.Load()
+	}
+	t.memory.init()
+	t.callback = cgo.NewHandle(t)
+	err := mmResultToError(C.TextToSpeechStartupEx(
+		&t.handle,
+		waveMapper,
+		C.DWORD(deviceOptions),
+		(*[0]byte)(C.dectalkCallback),
+		C.LONG(t.callback),
+	))
+	if err != nil {
+		t.callback.Delete()
+	}
+	return err
 }
 
 // UnloadUserDictionary unloads a user dictionary. You must unload any
@@ -272,7 +420,23 @@ func (t *TTS) startup(windowHandle C.HWND, deviceOptions DeviceOption) error {
 //
 // A user dictionary is created using the User Dictionary Build tool.
 func (t *TTS) UnloadUserDictionary() error {
-	return mmResultToError(C.TextToSpeechUnloadUserDictionary(t.handle))
+	if err := t.state.enter("UnloadUserDictionary"); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	return t.unloadUserDictionary()
+}
+
+func (t *TTS) unloadUserDictionary() error {
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechUnloadUserDictionary(t.handle)
+	})
+	if err != nil {
+		return err
+	}
+	t.dictionary.Store(nil)
+	return nil
 }
 
 // Version requests version information from DECtalk Software that allows a
@@ -294,6 +458,57 @@ func Version() (
 	return
 }
 
+// VersionInfo holds the version information returned by [VersionEx].
+type VersionInfo struct {
+	// StructSize is the size of the native structure in bytes.
+	StructSize uint32
+
+	// StructVersion is the version of the native structure.
+	StructVersion uint32
+
+	// DLLVersion is the numerically encoded DAPI version.
+	DLLVersion uint32
+
+	// DTalkVersion is the numerically encoded DECtalk version.
+	DTalkVersion uint32
+
+	// Features is the bitmask of features of DECtalk Software.
+	Features Features
+
+	verString string
+	language  string
+}
+
+// VerString returns the text information about the version.
+func (v *VersionInfo) VerString() string {
+	return v.verString
+}
+
+// Language returns the language of the DECtalk Software build.
+func (v *VersionInfo) Language() string {
+	return v.language
+}
+
+// VersionEx requests extended version information from DECtalk Software. In
+// contrast to [Version] it also returns the language and the features of the
+// build.
+func VersionEx() (*VersionInfo, error) {
+	var verC C.LPVERSION_INFO
+	C.TextToSpeechVersionEx(&verC)
+	if verC == nil {
+		return nil, &MMError{Code: Error}
+	}
+	return &VersionInfo{
+		StructSize:    uint32(verC.StructSize),
+		StructVersion: uint32(verC.StructVersion),
+		DLLVersion:    uint32(verC.DLLVersion),
+		DTalkVersion:  uint32(verC.DTalkVersion),
+		Features:      Features(verC.Features),
+		verString:     C.GoString(verC.VerString),
+		language:      C.GoString(verC.Language),
+	}, nil
+}
+
 // LoadUserDictionary loads a user-defined pronunciation dictionary into the
 // text-to-speech system.
 //
@@ -303,10 +518,39 @@ func Version() (
 // the text-to-speech system will automatically load a user dictionary, user.dic
 // (or udict_langcode.dic for Linux), at startup if it exists in the home
 // directory.
+//
+// The userdict package creates dictionaries in Go, without these tools.
 func (t *TTS) LoadUserDictionary(dictFile string) error {
+	if err := t.state.enter("LoadUserDictionary"); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	return t.loadUserDictionary(dictFile)
+}
+
+func (t *TTS) loadUserDictionary(dictFile string) error {
 	dictFileC := C.CString(dictFile)
 	defer C.free(unsafe.Pointer(dictFileC))
-	return mmResultToError(C.TextToSpeechLoadUserDictionary(t.handle, dictFileC))
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechLoadUserDictionary(t.handle, dictFileC)
+	})
+	if err != nil {
+		return err
+	}
+	t.dictionary.Store(&dictFile)
+	return nil
+}
+
+// UserDictionary returns the path of the user dictionary loaded with
+// [TTS.LoadUserDictionary], or an empty string if none has been loaded or it
+// has been unloaded since. A dictionary loaded automatically at startup is not
+// reported.
+func (t *TTS) UserDictionary() string {
+	if dictFile := t.dictionary.Load(); dictFile != nil {
+		return *dictFile
+	}
+	return ""
 }
 
 // OpenWaveOutFile opens the specified wave file and causes the text-to-speech
@@ -317,10 +561,26 @@ func (t *TTS) LoadUserDictionary(dictFile string) error {
 //
 // This function automatically resumes audio output if the text-to-speech system
 // is in a paused state by a previously issued [TTS.Pause] call.
+//
+// The text-to-speech system must be in the startup state, otherwise a
+// [*ModeError] is returned.
 func (t *TTS) OpenWaveOutFile(outFile string, format WaveFormat) error {
+	if err := t.state.enterExclusive("OpenWaveOutFile", ModeStartup); err != nil {
+		return err
+	}
+	defer t.state.leaveExclusive()
+
 	outFileC := C.CString(outFile)
 	defer C.free(unsafe.Pointer(outFileC))
-	return mmResultToError(C.TextToSpeechOpenWaveOutFile(t.handle, outFileC, C.DWORD(format)))
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechOpenWaveOutFile(t.handle, outFileC, C.DWORD(format))
+	})
+	if err != nil {
+		return err
+	}
+	t.state.set(ModeWaveFile)
+	t.paused.Store(false)
+	return nil
 }
 
 // CloseWaveOutFile closes a wave file opened by the [TTS.OpenWaveOutFile]
@@ -331,7 +591,19 @@ func (t *TTS) OpenWaveOutFile(outFile string, format WaveFormat) error {
 // The application must have called [TTS.OpenWaveOutFile] before calling
 // CloseWaveOutFile.
 func (t *TTS) CloseWaveOutFile() error {
-	return mmResultToError(C.TextToSpeechCloseWaveOutFile(t.handle))
+	if err := t.state.enterExclusive("CloseWaveOutFile", ModeWaveFile); err != nil {
+		return err
+	}
+	defer t.state.leaveExclusive()
+
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechCloseWaveOutFile(t.handle)
+	})
+	if err != nil {
+		return err
+	}
+	t.state.set(ModeStartup)
+	return nil
 }
 
 // OpenLogFile opens the specified log file and causes the text-to-speech system
@@ -349,10 +621,26 @@ func (t *TTS) CloseWaveOutFile() error {
 //
 // OpenLogFile automatically resumes audio output if the text-to-speech system
 // is in a paused state by a previously issued [TTS.Pause] call.
+//
+// The text-to-speech system must be in the startup state, otherwise a
+// [*ModeError] is returned.
 func (t *TTS) OpenLogFile(outFile string, log Log) error {
+	if err := t.state.enterExclusive("OpenLogFile", ModeStartup); err != nil {
+		return err
+	}
+	defer t.state.leaveExclusive()
+
 	outFileC := C.CString(outFile)
 	defer C.free(unsafe.Pointer(outFileC))
-	return mmResultToError(C.TextToSpeechOpenLogFile(t.handle, outFileC, C.DWORD(log)))
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechOpenLogFile(t.handle, outFileC, C.DWORD(log))
+	})
+	if err != nil {
+		return err
+	}
+	t.state.set(ModeLogFile)
+	t.paused.Store(false)
+	return nil
 }
 
 // CloseLogFile closes a log file opened by [TTS.OpenLogFile] and returns to the
@@ -363,10 +651,22 @@ func (t *TTS) OpenLogFile(outFile string, log Log) error {
 // CloseLogFile closes any open log file, even if it was opened with the Log
 // command.
 //
-// The application must have called [TTS.OpenLogFile] before calling
-// CloseLogFile.
+// The application must have called [TTS.OpenLogFile] or used the Log command
+// before calling CloseLogFile.
 func (t *TTS) CloseLogFile() error {
-	return mmResultToError(C.TextToSpeechCloseLogFile(t.handle))
+	if err := t.state.enterExclusive("CloseLogFile", ModeStartup, ModeLogFile); err != nil {
+		return err
+	}
+	defer t.state.leaveExclusive()
+
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechCloseLogFile(t.handle)
+	})
+	if err != nil {
+		return err
+	}
+	t.state.set(ModeStartup)
+	return nil
 }
 
 // Speak queues a null-terminated string to the text-to-speech system.
@@ -385,10 +685,38 @@ func (t *TTS) CloseLogFile() error {
 //	[:name paul] I am Paul. [:nb] I am Betty. [:volume set 50] The volume has
 //	been set to 50% of the maximum level. [:rate 120] I am speaking at 120 words
 //	per minute.
+//
+// The text is rewritten by the normalizer set with [TTS.SetNormalizer] and
+// converted from UTF-8 to the code page of the engine with [TTS.Encoder]
+// first, see [TTS.SetUnrepresentable]. If nothing is queued,
+// a new dictionary published by a [DictionaryManager] is loaded before.
 func (t *TTS) Speak(text string, flags TTSFlags) error {
-	textC := C.CString(text)
+	if err := t.state.enter("Speak"); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	if n := t.normalizer.Load(); n != nil {
+		if lang := t.normalizerLanguage(); lang != "" {
+			text = n.Normalize(lang, text)
+		}
+	}
+	encoded, err := t.Encoder().Encode(text)
+	if err != nil {
+		return err
+	}
+
+	if t.dictionaryPending() && t.idle() {
+		t.applyManagedDictionary()
+	}
+	if t.state.current() == ModeInMemory {
+		t.state.busy.Store(true)
+	}
+	textC := (*C.char)(C.CBytes(append(encoded, 0)))
 	defer C.free(unsafe.Pointer(textC))
-	return mmResultToError(C.TextToSpeechSpeak(t.handle, textC, C.DWORD(flags)))
+	return t.call(func() C.MMRESULT {
+		return C.TextToSpeechSpeak(t.handle, textC, C.DWORD(flags))
+	})
 }
 
 // StartLang checks whether the specified language is installed and, if so,
@@ -400,21 +728,100 @@ func StartLang(lang string) (*TTSLanguage, error) {
 	langC := C.CString(lang)
 	defer C.free(unsafe.Pointer(langC))
 
+	langMu.Lock()
 	ttsLang := C.TextToSpeechStartLang(langC)
+	langMu.Unlock()
 	if err := checkTTSLang(ttsLang); err != nil {
 		return nil, err
 	}
 
 	return &TTSLanguage{
-		name:   langC,
+		name:   lang,
 		handle: ttsLang,
 	}, nil
 }
 
+// LangEntry describes a language installed in the DECtalk Multi-Language (ML)
+// engine.
+type LangEntry struct {
+	code string
+	name string
+}
+
+// LangCode returns the 2-character language ID as accepted by [StartLang].
+func (e LangEntry) LangCode() string {
+	return e.code
+}
+
+// LangName returns the human-readable name of the language.
+func (e LangEntry) LangName() string {
+	return e.name
+}
+
+// LangEnum holds the information returned by [EnumLangs].
+type LangEnum struct {
+	// MultiLang is true when the engine supports multiple languages.
+	MultiLang bool
+
+	// Languages is the number of installed languages.
+	Languages uint32
+
+	// Entries holds one entry per installed language.
+	Entries []LangEntry
+}
+
+// EnumLangs retrieves information about what languages are available in the
+// system.
+func EnumLangs() (*LangEnum, error) {
+	var langsC C.LPLANG_ENUM
+	n := C.TextToSpeechEnumLangs(&langsC)
+	if n == 0 || langsC == nil {
+		return nil, ErrNoLanguages
+	}
+	defer C.free(unsafe.Pointer(langsC))
+
+	langs := &LangEnum{
+		MultiLang: langsC.MultiLang != 0,
+		Languages: uint32(langsC.Languages),
+		Entries:   make([]LangEntry, 0, int(langsC.Languages)),
+	}
+	for _, entry := range unsafe.Slice(&langsC.Entries[0], int(langsC.Languages)) {
+		langs.Entries = append(langs.Entries, LangEntry{
+			code: goStringN(entry.lang_code[:]),
+			name: goStringN(entry.lang_name[:]),
+		})
+	}
+	return langs, nil
+}
+
+// goStringN converts a fixed-size, possibly unterminated C string to Go.
+func goStringN(s []C.char) string {
+	b := make([]byte, 0, len(s))
+	for _, c := range s {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
+
 // SelectLang selects a loaded language for a program thread.
+//
+// As the Go scheduler moves goroutines between threads, this only has a
+// reliable effect on a goroutine locked to its thread with
+// [runtime.LockOSThread]. [StartupLocked] takes care of this.
+//
+// Instances started afterwards without a language, such as with [Startup],
+// report the language through [TTS.Language] and encode and normalize text
+// for it.
 func SelectLang(lang *TTSLanguage) (ok bool) {
 	okC := C.TextToSpeechSelectLang(nil, lang.handle)
-	return parseIntAsBool(okC)
+	if !parseIntAsBool(okC) {
+		return false
+	}
+	selectedLang.Store(lang)
+	return true
 }
 
 // // Do not use - first parameter is reserved.
@@ -426,9 +833,24 @@ func SelectLang(lang *TTSLanguage) (ok bool) {
 // Sync blocks until all previously queued text is processed.
 //
 // This function automatically resumes audio output if the text-to-speech system
-// is in a paused state by a previously issued [Pause] call.
+// is in a paused state by a previously issued [Pause] call. Afterwards, a new
+// dictionary published by a [DictionaryManager] is loaded.
 func (t *TTS) Sync() error {
-	return mmResultToError(C.TextToSpeechSync(t.handle))
+	if err := t.state.enter("Sync"); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechSync(t.handle)
+	})
+	if err != nil {
+		return err
+	}
+	t.state.busy.Store(false)
+	t.paused.Store(false)
+	t.applyManagedDictionary()
+	return nil
 }
 
 // Typing speaks a single letter as quickly as possible, aborting any previously
@@ -439,9 +861,26 @@ func (t *TTS) Sync() error {
 // Software. The function exists in non-Access32 versions, but is not fast.
 //
 // This function should be called only when the application is synthesizing
-// directly to an audio device (not to memory or to a file).
+// directly to an audio device (not to memory or to a file). It does nothing
+// after [TTS.Shutdown].
+//
+// The letter is converted to the code page of the engine with [TTS.Encoder].
+// Since the engine only types single characters, nothing is spoken if that
+// results in more than one character, such as a spelled out name, or in none,
+// or if it fails.
 func (t *TTS) Typing(letter rune) {
-	C.TextToSpeechTyping(t.handle, C.uchar(letter))
+	if err := t.state.enter("Typing"); err != nil {
+		return
+	}
+	defer t.state.leave()
+
+	c, ok := t.Encoder().typingCharacter(letter)
+	if !ok {
+		return
+	}
+	t.run(func() {
+		C.TextToSpeechTyping(t.handle, C.uchar(c))
+	})
 }
 
 // Shutdown shuts down the text-to-speech system and frees all its system
@@ -450,10 +889,56 @@ func (t *TTS) Typing(letter rune) {
 // Shutdown is called to close an application. Any user-defined dictionaries
 // that were previously loaded are unloaded. All previously queued text is
 // discarded, and the text-to-speech system immediately stops speaking.
+//
+// A wave file, log file or speech-to-memory mode that is still open is closed
+// first, since the engine would hang otherwise. Afterwards, all functions of
+// the instance return [ErrShutdown].
 func (t *TTS) Shutdown() error {
-	// TODO - check where a left-over wave file is open, otherwise the API will hang!
+	if !t.state.closing.CompareAndSwap(false, true) {
+		return ErrShutdown
+	}
 
-	return mmResultToError(C.TextToSpeechShutdown(t.handle))
+	// Discard queued text so that calls blocked inside the engine, such as
+	// Sync, return and let go of the state lock. This is not a full reset,
+	// the mode is left below so that the state stays in sync with the engine.
+	// It bypasses the worker thread of StartupLocked, which is the one
+	// blocked in Sync.
+	t.state.interrupt.Lock()
+	C.TextToSpeechReset(t.handle, boolToInt(false))
+	t.state.interrupt.Unlock()
+
+	t.state.lock.Lock()
+	defer t.state.lock.Unlock()
+
+	t.run(func() {
+		switch t.state.current() {
+		case ModeWaveFile:
+			C.TextToSpeechCloseWaveOutFile(t.handle)
+		case ModeLogFile:
+			C.TextToSpeechCloseLogFile(t.handle)
+		case ModeInMemory:
+			C.TextToSpeechCloseInMemory(t.handle)
+			t.memory.clear()
+		}
+	})
+	t.state.set(ModeStartup)
+
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechShutdown(t.handle)
+	})
+	if err != nil {
+		t.state.closing.Store(false)
+		return err
+	}
+	t.state.set(ModeShutdown)
+	if t.worker != nil {
+		t.worker.stop()
+	}
+	t.callback.Delete()
+	if t.events != nil {
+		t.events.close()
+	}
+	return nil
 }
 
 // Pause pauses text-to-speech audio output.
@@ -480,7 +965,19 @@ func (t *TTS) Shutdown() error {
 // Note that [TTS.Pause] will NOT resume audio output if the text-to-speech
 // system is paused by [TTS.Pause].
 func (t *TTS) Pause() error {
-	return mmResultToError(C.TextToSpeechPause(t.handle))
+	if err := t.state.enter("Pause"); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechPause(t.handle)
+	})
+	if err != nil {
+		return err
+	}
+	t.paused.Store(true)
+	return nil
 }
 
 // Resume resumes text-to-speech output after it was paused by calling
@@ -489,7 +986,19 @@ func (t *TTS) Pause() error {
 // This function affects only audio output and has no effect when writing log
 // files or wave files or when writing speech samples to memory.
 func (t *TTS) Resume() error {
-	return mmResultToError(C.TextToSpeechResume(t.handle))
+	if err := t.state.enter("Resume"); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechResume(t.handle)
+	})
+	if err != nil {
+		return err
+	}
+	t.paused.Store(false)
+	return nil
 }
 
 // Reset flushes all previously queued text from the text-to-speech system and
@@ -504,11 +1013,58 @@ func (t *TTS) Resume() error {
 //
 // [TTS.Reset] should be called before calling [TTS.CloseInMemory]. Failing to
 // do this in a situation where the synthesizer is busy may result in a
-// deadlock.
+// deadlock, which is why [TTS.CloseInMemory] refuses to do so.
+//
+// Reset may be called while another goroutine is blocked in [TTS.Sync]. The
+// queued text is discarded first, which lets Sync return, and only then are
+// the files of a full reset closed.
 func (t *TTS) Reset(fullReset bool) error {
-	// TODO - implement deadlock checks (CloseInMemory called before Reset?)
+	if err := t.interrupt(); err != nil {
+		return err
+	}
+	if !fullReset {
+		return nil
+	}
 
-	return mmResultToError(C.TextToSpeechReset(t.handle, boolToInt(fullReset)))
+	if err := t.state.enterExclusive("Reset"); err != nil {
+		return err
+	}
+	defer t.state.leaveExclusive()
+	return t.resetFully()
+}
+
+// resetFully closes the files of a special mode and returns to the startup
+// state. The state lock must be held exclusively.
+func (t *TTS) resetFully() error {
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechReset(t.handle, boolToInt(true))
+	})
+	if err != nil {
+		return err
+	}
+	t.state.busy.Store(false)
+	if t.state.current() == ModeInMemory {
+		t.memory.clear()
+	}
+	t.state.set(ModeStartup)
+	return nil
+}
+
+// interrupt discards all queued text. Like the reset in Shutdown, it takes
+// neither the state lock nor the worker thread of StartupLocked, both of
+// which a blocked Sync holds, so that it never waits for a Sync or for a call
+// that changes the mode queued behind one.
+func (t *TTS) interrupt() error {
+	t.state.interrupt.Lock()
+	defer t.state.interrupt.Unlock()
+	if t.state.closing.Load() {
+		return ErrShutdown
+	}
+	if err := mmResultToError(C.TextToSpeechReset(t.handle, boolToInt(false))); err != nil {
+		return err
+	}
+	t.state.busy.Store(false)
+	return nil
 }
 
 // GetRate returns the current setting of the speaking rate.
@@ -520,8 +1076,16 @@ func (t *TTS) Reset(fullReset bool) error {
 // is used without the [TTS.Sync] function. The speaking-rate change occurs on
 // clause boundaries.
 func (t *TTS) GetRate() (uint32, error) {
+	if err := t.state.enter("GetRate"); err != nil {
+		return 0, err
+	}
+	defer t.state.leave()
+
 	var rateC C.DWORD
-	if err := mmResultToError(C.TextToSpeechGetRate(t.handle, &rateC)); err != nil {
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechGetRate(t.handle, &rateC)
+	})
+	if err != nil {
 		return 0, err
 	}
 	return uint32(rateC), nil
@@ -532,7 +1096,14 @@ func (t *TTS) GetRate() (uint32, error) {
 // The speaking rate change is not effective until the next phrase boundary. All
 // the queued audio encountered before the phrase boundary is unaffected.
 func (t *TTS) SetRate(rate uint32) error {
-	return mmResultToError(C.TextToSpeechSetRate(t.handle, C.DWORD(rate)))
+	if err := t.state.enter("SetRate"); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	return t.call(func() C.MMRESULT {
+		return C.TextToSpeechSetRate(t.handle, C.DWORD(rate))
+	})
 }
 
 // GetSpeaker returns the value of the identifier for the last voice that has
@@ -541,8 +1112,15 @@ func (t *TTS) SetRate(rate uint32) error {
 // Note that even after calling #SetSpeaker(), #GetSpeaker() returns the value
 // for the previous speaking voice until the new voice actually speaks.
 func (t *TTS) GetSpeaker() (Speaker, error) {
+	if err := t.state.enter("GetSpeaker"); err != nil {
+		return 0, err
+	}
+	defer t.state.leave()
+
 	var speakerC C.SPEAKER_T
-	err := mmResultToError(C.TextToSpeechGetSpeaker(t.handle, &speakerC))
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechGetSpeaker(t.handle, &speakerC)
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -555,18 +1133,12 @@ func (t *TTS) GetSpeaker() (Speaker, error) {
 // The change in speaking voice is not effective until the next phrase boundary.
 // All queued audio encountered before the phrase boundary is unaffected.
 func (t *TTS) SetSpeaker(speaker Speaker) error {
-	return mmResultToError(C.TextToSpeechSetSpeaker(t.handle, C.SPEAKER_T(speaker)))
-}
-
-// TODO - MMRESULT #AddBuffer(LPTTS_HANDLE_T phTTS, LPTTS_BUFFER_T pTTSbuffer) Adds a shared-memory buffer allocated by the calling application to the memory buffer list.
-// TODO - MMRESULT #CloseInMemory(LPTTS_HANDLE_T phTTS) Returns the text-to-speech system to its startup state.
-// TODO - DWORD #EnumLangs(LPLANG_ENUM *langs) retrieves information about what languages are available in the system.
-// TODO - MMRESULT #GetCaps(LPTTS_CAPS_T lpTTScaps) Retrieves the capabilities of the text-to-speech system
-// TODO - DWORD #GetFeatures(void) Retrieves information, in the form of a bitmask, about the features of DECtalk Software. (maskable to the list supplied in the header file TTSFEAT.H.)
-// TODO - MMRESULT #GetRate(LPTTS_HANDLE_T phTTS, LPDWORD pdwRate) Returns the speaking rate of the text-to-speech system.
-// TODO - MMRESULT #GetStatus(LPTTS_HANDLE_T phTTS, LPDWORD dwIdentifier[ ], LPDWORD dwStatus[ ], DWORD dwNumberOfStatusValues) Gets the status of the text-to-speech system
-// TODO - MMRESULT #OpenInMemory(LPTTS_HANDLE_T phTTS, DWORD dwFormat) <requires TextToSpeechAddBuffer> Produces buffered speech samples in wave format whenever [Speak] function is called. The calling application is notified when memory buffer is filled.
-// TODO - MMRESULT #ReturnBuffer(LPTTS_HANDLE_T phTTS, LPTTS_BUFFER_T *ppTTSbuffer) Returns the current shared-memory buffer.
-// TODO - MMRESULT #StartupEx(LPTTS_HANDLE_T *phTTS, UINT uiDeviceNumber, DWORD dwDeviceOptions, VOID (*DtCallbackRoutine)(), LONG dwCallbackParameter) TextToSpeechStartup but with custom callback
-// TODO - ULONG TextToSpeechVersionEx(LPVERSION_INFO *ver)
-// TODO - struct LPVERSION_INFO
+	if err := t.state.enter("SetSpeaker"); err != nil {
+		return err
+	}
+	defer t.state.leave()
+
+	return t.call(func() C.MMRESULT {
+		return C.TextToSpeechSetSpeaker(t.handle, C.SPEAKER_T(speaker))
+	})
+}
@@ -0,0 +1,344 @@
+package normalize
+
+import (
+	"sort"
+	"strconv"
+)
+
+// scale is a power of ten spoken with its own word, such as a million.
+type scale struct {
+	power int
+
+	// one is the phrase for exactly one, such as "one million".
+	one string
+
+	// many follows any other count, such as "millions".
+	many string
+}
+
+// currency holds the names of a currency in a language.
+type currency struct {
+	one, many           string
+	minorOne, minorMany string
+}
+
+// symbols holds the words for characters of URLs and e-mail addresses.
+type symbols struct {
+	dot, slash, colon, dash, underscore, at string
+}
+
+// language holds everything the built-in rules need to know about a language.
+type language struct {
+	months [12]string
+
+	// date formats a date with the name of the month.
+	date func(day int, month string, year int) string
+
+	symbols symbols
+
+	// scales is sorted by descending power.
+	scales []scale
+
+	// group and decimal are the regular expressions matching the separators
+	// of written numbers.
+	group, decimal string
+
+	// point is the word read for the decimal separator.
+	point string
+
+	// and joins the major and minor units of an amount, and of joins a
+	// scale word to the name of the currency, if the language needs it.
+	and, of string
+
+	currencies map[rune]currency
+
+	emojiNames map[rune]string
+
+	// The rules, compiled by newLanguage.
+	urlRule, emailRule, dateRule, currencyRule, numberRule, emojiRule Rule
+}
+
+var englishScales = []scale{
+	{12, "one trillion", "trillion"},
+	{9, "one billion", "billion"},
+	{6, "one million", "million"},
+	{3, "one thousand", "thousand"},
+}
+
+var englishCurrencies = map[rune]currency{
+	'$': {"dollar", "dollars", "cent", "cents"},
+	'€': {"euro", "euros", "cent", "cents"},
+	'£': {"pound", "pounds", "penny", "pence"},
+	'¥': {"yen", "yen", "", ""},
+}
+
+var englishEmoji = map[rune]string{
+	'😀': "grinning face",
+	'🙂': "smiling face",
+	'😂': "face with tears of joy",
+	'😉': "winking face",
+	'😢': "crying face",
+	'👍': "thumbs up",
+	'👎': "thumbs down",
+	'👋': "waving hand",
+	'🙏': "folded hands",
+	'❤': "red heart",
+	'🎉': "party popper",
+	'🔥': "fire",
+	'✅': "check mark",
+	'❌': "cross mark",
+	'⭐': "star",
+}
+
+var englishMonths = [12]string{
+	"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December",
+}
+
+var englishSymbols = symbols{"dot", "slash", "colon", "dash", "underscore", "at"}
+
+var spanishScales = []scale{
+	{12, "un billón", "billones"},
+	{6, "un millón", "millones"},
+	{3, "mil", "mil"},
+}
+
+var spanishMonths = [12]string{
+	"enero", "febrero", "marzo", "abril", "mayo", "junio",
+	"julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre",
+}
+
+var spanishSymbols = symbols{"punto", "barra", "dos puntos", "guion", "guion bajo", "arroba"}
+
+var spanishEmoji = map[rune]string{
+	'😀': "cara sonriente",
+	'🙂': "cara ligeramente sonriente",
+	'😂': "cara llorando de risa",
+	'😉': "cara guiñando un ojo",
+	'😢': "cara llorando",
+	'👍': "pulgar hacia arriba",
+	'👎': "pulgar hacia abajo",
+	'👋': "mano saludando",
+	'🙏': "manos juntas",
+	'❤': "corazón rojo",
+	'🎉': "confeti",
+	'🔥': "fuego",
+	'✅': "marca de verificación",
+	'❌': "cruz",
+	'⭐': "estrella",
+}
+
+var spanishCurrencies = map[rune]currency{
+	'$': {"dólar", "dólares", "centavo", "centavos"},
+	'€': {"euro", "euros", "céntimo", "céntimos"},
+	'£': {"libra", "libras", "penique", "peniques"},
+	'¥': {"yen", "yenes", "", ""},
+}
+
+func spanishDate(day int, month string, year int) string {
+	return strconv.Itoa(day) + " de " + month + " de " + strconv.Itoa(year)
+}
+
+// languages holds the built-in rules of all languages of the engine.
+var languages = map[string]*language{
+	"us": newLanguage(&language{
+		months: englishMonths,
+		date: func(day int, month string, year int) string {
+			return month + " " + strconv.Itoa(day) + ", " + strconv.Itoa(year)
+		},
+		symbols:    englishSymbols,
+		scales:     englishScales,
+		group:      `,`,
+		decimal:    `\.`,
+		point:      "point",
+		and:        "and",
+		currencies: englishCurrencies,
+		emojiNames: englishEmoji,
+	}),
+	"uk": newLanguage(&language{
+		months: englishMonths,
+		date: func(day int, month string, year int) string {
+			return strconv.Itoa(day) + " " + month + " " + strconv.Itoa(year)
+		},
+		symbols:    englishSymbols,
+		scales:     englishScales,
+		group:      `,`,
+		decimal:    `\.`,
+		point:      "point",
+		and:        "and",
+		currencies: englishCurrencies,
+		emojiNames: englishEmoji,
+	}),
+	"gr": newLanguage(&language{
+		months: [12]string{
+			"Januar", "Februar", "März", "April", "Mai", "Juni",
+			"Juli", "August", "September", "Oktober", "November", "Dezember",
+		},
+		date: func(day int, month string, year int) string {
+			return strconv.Itoa(day) + ". " + month + " " + strconv.Itoa(year)
+		},
+		symbols: symbols{"Punkt", "Schrägstrich", "Doppelpunkt", "Bindestrich", "Unterstrich", "at"},
+		scales: []scale{
+			{12, "eine Billion", "Billionen"},
+			{9, "eine Milliarde", "Milliarden"},
+			{6, "eine Million", "Millionen"},
+			{3, "tausend", "tausend"},
+		},
+		group:   `\.`,
+		decimal: `,`,
+		point:   "Komma",
+		and:     "und",
+		currencies: map[rune]currency{
+			'$': {"Dollar", "Dollar", "Cent", "Cent"},
+			'€': {"Euro", "Euro", "Cent", "Cent"},
+			'£': {"Pfund", "Pfund", "Penny", "Pence"},
+			'¥': {"Yen", "Yen", "", ""},
+		},
+		emojiNames: map[rune]string{
+			'😀': "grinsendes Gesicht",
+			'🙂': "lächelndes Gesicht",
+			'😂': "Gesicht mit Freudentränen",
+			'😉': "zwinkerndes Gesicht",
+			'😢': "weinendes Gesicht",
+			'👍': "Daumen hoch",
+			'👎': "Daumen runter",
+			'👋': "winkende Hand",
+			'🙏': "gefaltete Hände",
+			'❤': "rotes Herz",
+			'🎉': "Konfetti",
+			'🔥': "Feuer",
+			'✅': "Häkchen",
+			'❌': "Kreuz",
+			'⭐': "Stern",
+		},
+	}),
+	"sp": newLanguage(&language{
+		months:     spanishMonths,
+		date:       spanishDate,
+		symbols:    spanishSymbols,
+		scales:     spanishScales,
+		group:      `\.`,
+		decimal:    `,`,
+		point:      "coma",
+		and:        "con",
+		of:         "de",
+		currencies: spanishCurrencies,
+		emojiNames: spanishEmoji,
+	}),
+	"la": newLanguage(&language{
+		months:     spanishMonths,
+		date:       spanishDate,
+		symbols:    spanishSymbols,
+		scales:     spanishScales,
+		group:      `,`,
+		decimal:    `\.`,
+		point:      "punto",
+		and:        "con",
+		of:         "de",
+		currencies: spanishCurrencies,
+		emojiNames: spanishEmoji,
+	}),
+	"fr": newLanguage(&language{
+		months: [12]string{
+			"janvier", "février", "mars", "avril", "mai", "juin",
+			"juillet", "août", "septembre", "octobre", "novembre", "décembre",
+		},
+		date: func(day int, month string, year int) string {
+			d := strconv.Itoa(day)
+			if day == 1 {
+				d = "1er"
+			}
+			return d + " " + month + " " + strconv.Itoa(year)
+		},
+		symbols: symbols{"point", "barre oblique", "deux points", "tiret", "tiret bas", "arobase"},
+		scales: []scale{
+			{12, "un billion", "billions"},
+			{9, "un milliard", "milliards"},
+			{6, "un million", "millions"},
+			{3, "mille", "mille"},
+		},
+		group:   `[ \x{a0}\x{202f}.]`,
+		decimal: `,`,
+		point:   "virgule",
+		and:     "et",
+		of:      "de",
+		currencies: map[rune]currency{
+			'$': {"dollar", "dollars", "cent", "cents"},
+			'€': {"euro", "euros", "centime", "centimes"},
+			'£': {"livre", "livres", "penny", "pence"},
+			'¥': {"yen", "yens", "", ""},
+		},
+		emojiNames: map[rune]string{
+			'😀': "visage souriant",
+			'🙂': "visage légèrement souriant",
+			'😂': "visage riant aux larmes",
+			'😉': "visage faisant un clin d'oeil",
+			'😢': "visage qui pleure",
+			'👍': "pouce levé",
+			'👎': "pouce baissé",
+			'👋': "main qui salue",
+			'🙏': "mains jointes",
+			'❤': "coeur rouge",
+			'🎉': "confettis",
+			'🔥': "feu",
+			'✅': "coche",
+			'❌': "croix",
+			'⭐': "étoile",
+		},
+	}),
+	"it": newLanguage(&language{
+		months: [12]string{
+			"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno",
+			"luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre",
+		},
+		date: func(day int, month string, year int) string {
+			return strconv.Itoa(day) + " " + month + " " + strconv.Itoa(year)
+		},
+		symbols: symbols{"punto", "barra", "due punti", "trattino", "trattino basso", "chiocciola"},
+		scales: []scale{
+			{12, "un bilione", "bilioni"},
+			{9, "un miliardo", "miliardi"},
+			{6, "un milione", "milioni"},
+			{3, "mille", "mila"},
+		},
+		group:   `\.`,
+		decimal: `,`,
+		point:   "virgola",
+		and:     "e",
+		of:      "di",
+		currencies: map[rune]currency{
+			'$': {"dollaro", "dollari", "centesimo", "centesimi"},
+			'€': {"euro", "euro", "centesimo", "centesimi"},
+			'£': {"sterlina", "sterline", "penny", "pence"},
+			'¥': {"yen", "yen", "", ""},
+		},
+		emojiNames: map[rune]string{
+			'😀': "faccina sorridente",
+			'🙂': "faccina con sorriso accennato",
+			'😂': "faccina con lacrime di gioia",
+			'😉': "faccina che fa l'occhiolino",
+			'😢': "faccina che piange",
+			'👍': "pollice in su",
+			'👎': "pollice verso",
+			'👋': "mano che saluta",
+			'🙏': "mani giunte",
+			'❤': "cuore rosso",
+			'🎉': "coriandoli",
+			'🔥': "fuoco",
+			'✅': "segno di spunta",
+			'❌': "croce",
+			'⭐': "stella",
+		},
+	}),
+}
+
+// Languages returns the IDs of all languages with built-in rules, in
+// alphabetical order.
+func Languages() []string {
+	langs := make([]string, 0, len(languages))
+	for lang := range languages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
@@ -0,0 +1,161 @@
+// Package normalize rewrites text before it is passed to
+// [dectalkdapi.TTS.Speak], expanding constructs that the engine reads badly,
+// such as URLs, ISO dates, large currency amounts and emoji, into words of the
+// language being spoken.
+//
+// A [Normalizer] runs a pipeline of rules per language, keyed on the
+// 2-character language ID returned by [dectalkdapi.TTSLanguage.Name]:
+//
+//	n := normalize.New()
+//	n.Register("us", "acronyms", normalize.ReplaceAll(regexp.MustCompile(`\bDECtalk\b`), "deck talk"))
+//	text := n.Normalize("us", "Visit https://example.com/docs on 2024-03-05.")
+//	// "Visit example dot com slash docs on March 5, 2024."
+//
+// Inline commands in brackets are never rewritten. The package does not
+// depend on the engine, so rules can be tested without it; use
+// [dectalkdapi.TTS.SetNormalizer] to apply a normalizer to everything an
+// instance speaks.
+package normalize
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Rule rewrites text of a single language. It is only given text outside of
+// inline commands.
+type Rule func(text string) string
+
+// Replace returns a rule that replaces every match of re with the result of
+// replace, which is given the submatches as returned by
+// [regexp.Regexp.FindStringSubmatch].
+func Replace(re *regexp.Regexp, replace func(submatches []string) string) Rule {
+	return func(text string) string {
+		return re.ReplaceAllStringFunc(text, func(match string) string {
+			return replace(re.FindStringSubmatch(match))
+		})
+	}
+}
+
+// ReplaceAll returns a rule that replaces every match of re with repl, which
+// may refer to submatches as in [regexp.Regexp.ReplaceAllString].
+func ReplaceAll(re *regexp.Regexp, repl string) Rule {
+	return func(text string) string {
+		return re.ReplaceAllString(text, repl)
+	}
+}
+
+// Names of the built-in rules, in the order they run.
+const (
+	RuleURL      = "url"
+	RuleEmail    = "email"
+	RuleDate     = "date"
+	RuleCurrency = "currency"
+	RuleNumber   = "number"
+	RuleEmoji    = "emoji"
+)
+
+type entry struct {
+	lang string
+	name string
+	rule Rule
+}
+
+// Normalizer runs rules registered per language in the order of their
+// registration. It is safe for concurrent use.
+type Normalizer struct {
+	mu    sync.RWMutex
+	rules []entry
+}
+
+// New returns a normalizer with the built-in rules registered for all
+// languages of the engine (us, uk, gr, sp, la, fr and it).
+func New() *Normalizer {
+	n := new(Normalizer)
+	for _, lang := range Languages() {
+		l := languages[lang]
+		n.Register(lang, RuleURL, l.urlRule)
+		n.Register(lang, RuleEmail, l.emailRule)
+		n.Register(lang, RuleDate, l.dateRule)
+		n.Register(lang, RuleCurrency, l.currencyRule)
+		n.Register(lang, RuleNumber, l.numberRule)
+		n.Register(lang, RuleEmoji, l.emojiRule)
+	}
+	return n
+}
+
+// Register adds a rule for a language, or for all languages if lang is empty.
+// A rule registered before with the same language and name is replaced in
+// place, otherwise the rule runs after all rules registered before.
+func (n *Normalizer) Register(lang, name string, rule Rule) {
+	lang = strings.ToLower(lang)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i, e := range n.rules {
+		if e.lang == lang && e.name == name {
+			n.rules[i].rule = rule
+			return
+		}
+	}
+	n.rules = append(n.rules, entry{lang: lang, name: name, rule: rule})
+}
+
+// Remove removes the rule registered with the same language and name, such as
+// a built-in rule.
+func (n *Normalizer) Remove(lang, name string) {
+	lang = strings.ToLower(lang)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i, e := range n.rules {
+		if e.lang == lang && e.name == name {
+			n.rules = append(n.rules[:i], n.rules[i+1:]...)
+			return
+		}
+	}
+}
+
+// Normalize runs the rules of a language on text, leaving inline commands in
+// brackets untouched. An empty language is handled like "us".
+func (n *Normalizer) Normalize(lang, text string) string {
+	lang = strings.ToLower(lang)
+	if lang == "" {
+		lang = "us"
+	}
+	n.mu.RLock()
+	var rules []Rule
+	for _, e := range n.rules {
+		if e.lang == "" || e.lang == lang {
+			rules = append(rules, e.rule)
+		}
+	}
+	n.mu.RUnlock()
+	if len(rules) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	for text != "" {
+		start := strings.IndexByte(text, '[')
+		if start < 0 {
+			start = len(text)
+		}
+		plain := text[:start]
+		for _, rule := range rules {
+			plain = rule(plain)
+		}
+		b.WriteString(plain)
+		text = text[start:]
+		if text == "" {
+			break
+		}
+
+		end := strings.IndexByte(text, ']')
+		if end < 0 {
+			end = len(text) - 1
+		}
+		b.WriteString(text[:end+1])
+		text = text[end+1:]
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package normalize_test
+
+import (
+	"github.com/icedream/go-dectalkdapi/normalize"
+	"regexp"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		lang, text, want string
+	}{
+		{"us", "See https://www.example.com/docs/?utm=1.", "See w w w dot example dot com slash docs."},
+		{"us", "Mail jane.doe@example.org now", "Mail jane dot doe at example dot org now"},
+		{"us", "Due 2024-03-05.", "Due March 5, 2024."},
+		{"uk", "Due 2024-03-05.", "Due 5 March 2024."},
+		{"gr", "Am 2024-03-05", "Am 5. März 2024"},
+		{"sp", "El 2024-03-05", "El 5 de marzo de 2024"},
+		{"fr", "Le 2024-03-01", "Le 1er mars 2024"},
+		{"us", "It costs $1,250,000.", "It costs 1250000 dollars."},
+		{"us", "Only $3.50 or $1.", "Only 3 dollars and 50 cents or 1 dollar."},
+		{"us", "A $2.5bn deal", "A 2 point 5 billion dollars deal"},
+		{"us", "Raised $4 million", "Raised 4 million dollars"},
+		{"us", "Just £0.99", "Just 99 pence"},
+		{"gr", "Kostet 1.250.000 €", "Kostet 1250000 Euro"},
+		{"gr", "Nur 3,50 €", "Nur 3 Euro und 50 Cent"},
+		{"sp", "Cuesta 2,5 Mrd. €", "Cuesta 2500 millones de euros"},
+		{"fr", "Il coûte 5 000 €", "Il coûte 5000 euros"},
+		{"it", "Costa 1.000.000 €", "Costa 1000000 di euro"},
+		{"us", "It costs $1,234.56", "It costs 1234 dollars and 56 cents"},
+		{"fr", "Il coûte 12 345,6 €", "Il coûte 12345 euros et 60 centimes"},
+		{"sp", "Cuesta 2.000.000 €", "Cuesta 2000000 de euros"},
+		{"us", "Raised $1M", "Raised one million dollars"},
+		{"us", "Owes $1,000,000,000,000,000,000", "Owes $1,000,000,000,000,000,000"},
+		{"us", "We had 1,000,000,000,000,000 visitors", "We had 1,000,000,000,000,000 visitors"},
+		{"us", "We had 12,345,678 visitors", "We had 12345678 visitors"},
+		{"us", "Great job👍!", "Great job thumbs up!"},
+		{"us", "Nice 🦄 one 👍🏽 ok", "Nice  one thumbs up ok"},
+		{"gr", "Super 👍 gemacht", "Super Daumen hoch gemacht"},
+		{"us", "Keep [:rate 1,000] and [hx'ehlow] but 1,000", "Keep [:rate 1,000] and [hx'ehlow] but 1000"},
+		{"", "Due 2024-03-05", "Due March 5, 2024"},
+	}
+	n := normalize.New()
+	for _, test := range tests {
+		if got := n.Normalize(test.lang, test.text); got != test.want {
+			t.Errorf("Normalize(%q, %q) = %q, want %q", test.lang, test.text, got, test.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	n := normalize.New()
+	n.Register("us", "acronyms", normalize.ReplaceAll(regexp.MustCompile(`\bDECtalk\b`), "deck talk"))
+	n.Register("", "shout", normalize.ReplaceAll(regexp.MustCompile(`!+`), "!"))
+	n.Remove("us", normalize.RuleDate)
+
+	if got, want := n.Normalize("us", "DECtalk 2024-03-05!!!"), "deck talk 2024-03-05!"; got != want {
+		t.Errorf("Normalize() = %q, want %q", got, want)
+	}
+	if got, want := n.Normalize("gr", "DECtalk 2024-03-05!!!"), "DECtalk 5. März 2024!"; got != want {
+		t.Errorf("Normalize() = %q, want %q", got, want)
+	}
+
+	n.Register("us", normalize.RuleEmoji, func(text string) string { return text })
+	if got, want := n.Normalize("us", "Hi 👍"), "Hi 👍"; got != want {
+		t.Errorf("Normalize() with replaced rule = %q, want %q", got, want)
+	}
+}
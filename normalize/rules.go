@@ -0,0 +1,300 @@
+package normalize
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	urlPattern   = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'\[\]]*[^\s<>"'\[\].,;:!?)]`)
+	emailPattern = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+\b`)
+	datePattern  = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	schemePrefix = regexp.MustCompile(`(?i)^https?://`)
+)
+
+// abbreviations maps the abbreviated scales following amounts, such as "$5M",
+// to their power of ten.
+var abbreviations = map[string]int{
+	"k":   3,
+	"K":   3,
+	"M":   6,
+	"mn":  6,
+	"Mio": 6,
+	"B":   9,
+	"bn":  9,
+	"Mrd": 9,
+	"tn":  12,
+}
+
+const currencySymbols = `[$€£¥]`
+
+// newLanguage compiles the built-in rules of a language.
+func newLanguage(l *language) *language {
+	// Scale words following amounts, such as "$5 million".
+	words := make(map[string]int)
+	var alternatives []string
+	for _, s := range l.scales {
+		one := strings.Fields(s.one)
+		for _, w := range []string{one[len(one)-1], s.many} {
+			if _, ok := words[strings.ToLower(w)]; !ok {
+				words[strings.ToLower(w)] = s.power
+				alternatives = append(alternatives, regexp.QuoteMeta(w))
+			}
+		}
+	}
+	var abbr []string
+	for a := range abbreviations {
+		abbr = append(abbr, a)
+	}
+	sort.Strings(abbr)
+	scaleSuffix := `(?:\s?(` + strings.Join(abbr, "|") + `)\b\.?|\s((?i:` + strings.Join(alternatives, "|") + `))\b)?`
+	amount := `(\d{1,3}(?:` + l.group + `\d{3})+|\d+)(?:` + l.decimal + `(\d+))?`
+	prefixed := regexp.MustCompile(`(` + currencySymbols + `)\s?` + amount + scaleSuffix)
+	suffixed := regexp.MustCompile(`\b` + amount + scaleSuffix + `\s?(` + currencySymbols + `)`)
+	grouped := regexp.MustCompile(`\b(\d{1,3}(?:` + l.group + `\d{3})+)(?:` + l.decimal + `(\d+))?\b`)
+	group := regexp.MustCompile(l.group)
+
+	power := func(abbr, word string) int {
+		if abbr != "" {
+			return abbreviations[abbr]
+		}
+		return words[strings.ToLower(word)]
+	}
+	l.urlRule = Replace(urlPattern, func(m []string) string {
+		url := schemePrefix.ReplaceAllString(m[0], "")
+		if i := strings.IndexAny(url, "?#"); i >= 0 {
+			url = url[:i]
+		}
+		return l.spell(strings.TrimRight(url, "/"))
+	})
+	l.emailRule = Replace(emailPattern, func(m []string) string {
+		return l.spell(m[0])
+	})
+	l.dateRule = Replace(datePattern, func(m []string) string {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return m[0]
+		}
+		return l.date(day, l.months[month-1], year)
+	})
+	l.currencyRule = func(text string) string {
+		text = Replace(prefixed, func(m []string) string {
+			c, ok := l.currencies[[]rune(m[1])[0]]
+			if !ok {
+				return m[0]
+			}
+			spoken, ok := l.amount(c, group.ReplaceAllString(m[2], ""), m[3], power(m[4], m[5]))
+			if !ok {
+				return m[0]
+			}
+			return spoken
+		})(text)
+		return Replace(suffixed, func(m []string) string {
+			c, ok := l.currencies[[]rune(m[5])[0]]
+			if !ok {
+				return m[0]
+			}
+			spoken, ok := l.amount(c, group.ReplaceAllString(m[1], ""), m[2], power(m[3], m[4]))
+			if !ok {
+				return m[0]
+			}
+			return spoken
+		})(text)
+	}
+	l.numberRule = Replace(grouped, func(m []string) string {
+		spoken, _, ok := l.number(group.ReplaceAllString(m[1], ""))
+		if !ok {
+			return m[0]
+		}
+		if m[2] != "" {
+			spoken += " " + l.point + " " + m[2]
+		}
+		return spoken
+	})
+	l.emojiRule = l.replaceEmoji
+	return l
+}
+
+// spell reads a URL or e-mail address, naming its separators and spelling
+// out "www".
+func (l *language) spell(s string) string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		if strings.EqualFold(w, "www") {
+			w = "w w w"
+		}
+		words = append(words, w)
+		word.Reset()
+	}
+	for _, r := range s {
+		var name string
+		switch r {
+		case '.':
+			name = l.symbols.dot
+		case '/':
+			name = l.symbols.slash
+		case ':':
+			name = l.symbols.colon
+		case '-':
+			name = l.symbols.dash
+		case '_':
+			name = l.symbols.underscore
+		case '@':
+			name = l.symbols.at
+		default:
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				word.WriteRune(r)
+			} else {
+				flush()
+			}
+			continue
+		}
+		flush()
+		words = append(words, name)
+	}
+	flush()
+	return strings.Join(words, " ")
+}
+
+// number reads an integer given as digits, leaving the digits to the engine
+// and only stripping leading zeros. It also returns the power of the largest
+// scale the number is a whole multiple of, such as 6 for "2000000", or 0. ok
+// is false for numbers too large for the scales of the language, which are
+// left as written.
+func (l *language) number(digits string) (spoken string, last int, ok bool) {
+	if len(digits) > l.scales[0].power+3 {
+		return "", 0, false
+	}
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0", 0, true
+	}
+	zeros := len(digits) - len(strings.TrimRight(digits, "0"))
+	for _, s := range l.scales {
+		if s.power <= zeros {
+			last = s.power
+			break
+		}
+	}
+	return digits, last, true
+}
+
+// amount reads an amount of money. The integer and fraction are given as
+// digits, and power is the power of ten of a scale following the amount, such
+// as 6 for "$1.5M". ok is false if the amount is too large for the scales of
+// the language.
+func (l *language) amount(c currency, integer, fraction string, power int) (spoken string, ok bool) {
+	var s scale
+	if power > 0 {
+		// Shift the decimal point to the largest scale of the language
+		// that fits, so that "2.5bn" becomes 2500 millions in Spanish.
+		for _, s = range l.scales {
+			if s.power > power {
+				continue
+			}
+			for i := 0; i < power-s.power; i++ {
+				if fraction != "" {
+					integer, fraction = integer+fraction[:1], fraction[1:]
+				} else {
+					integer += "0"
+				}
+			}
+			power = s.power
+			break
+		}
+		fraction = strings.TrimRight(fraction, "0")
+	}
+
+	spoken, last, ok := l.number(integer)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case power > 0 && spoken == "1" && fraction == "":
+		spoken, last = s.one, power
+	case power > 0:
+		if fraction != "" {
+			spoken += " " + l.point + " " + fraction
+		}
+		spoken, last = spoken+" "+s.many, power
+	case fraction != "" && (len(fraction) > 2 || c.minorOne == ""):
+		spoken, last = spoken+" "+l.point+" "+fraction, 0
+	}
+
+	name := c.many
+	if spoken == "1" {
+		name = c.one
+	}
+	if last >= 6 && l.of != "" {
+		name = l.of + " " + name
+	}
+	major := spoken + " " + name
+	if power > 0 || fraction == "" || len(fraction) > 2 || c.minorOne == "" {
+		return major, true
+	}
+
+	if len(fraction) == 1 {
+		fraction += "0"
+	}
+	cents, _ := strconv.Atoi(fraction)
+	if cents == 0 {
+		return major, true
+	}
+	minor := strconv.Itoa(cents) + " " + c.minorMany
+	if cents == 1 {
+		minor = "1 " + c.minorOne
+	}
+	if strings.Trim(integer, "0") == "" {
+		return minor, true
+	}
+	return major + " " + l.and + " " + minor, true
+}
+
+// isEmoji reports whether r is an emoji, or a character only used to modify
+// emoji such as a skin tone or a variation selector.
+func isEmoji(r rune) bool {
+	return r >= 0x1f000 && r <= 0x1faff ||
+		r >= 0x2600 && r <= 0x27bf ||
+		r >= 0x2b00 && r <= 0x2bff ||
+		r >= 0xe0020 && r <= 0xe007f ||
+		r == 0xfe0f || r == 0x200d
+}
+
+// replaceEmoji replaces known emoji with their names and removes all others,
+// which the engine would otherwise read as garbage.
+func (l *language) replaceEmoji(text string) string {
+	if strings.IndexFunc(text, isEmoji) < 0 {
+		return text
+	}
+	var b strings.Builder
+	space := false
+	for _, r := range text {
+		if name, ok := l.emojiNames[r]; ok {
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), " ") {
+				b.WriteByte(' ')
+			}
+			b.WriteString(name)
+			space = true
+			continue
+		}
+		if isEmoji(r) {
+			continue
+		}
+		if space && !unicode.IsSpace(r) && !unicode.IsPunct(r) {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,332 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Stress is the stress of a phoneme or syllable.
+type Stress int
+
+const (
+	// The phoneme is not stressed.
+	Unstressed Stress = iota
+
+	// Primary stress, written as ' in arpabet.
+	PrimaryStress
+
+	// Secondary stress, written as ` in arpabet.
+	SecondaryStress
+
+	// Emphatic stress, written as " in arpabet.
+	EmphaticStress
+)
+
+// stressMarks maps the arpabet stress marks to their stress.
+var stressMarks = map[rune]Stress{
+	'\'': PrimaryStress,
+	'`':  SecondaryStress,
+	'"':  EmphaticStress,
+}
+
+// rank orders stresses by strength, which differs from the order of their
+// values.
+func (s Stress) rank() int {
+	switch s {
+	case SecondaryStress:
+		return 1
+	case PrimaryStress:
+		return 2
+	case EmphaticStress:
+		return 3
+	}
+	return 0
+}
+
+func (s Stress) mark() string {
+	switch s {
+	case PrimaryStress:
+		return "'"
+	case SecondaryStress:
+		return "`"
+	case EmphaticStress:
+		return `"`
+	}
+	return ""
+}
+
+var stressNames = map[Stress]string{
+	Unstressed:      "unstressed",
+	PrimaryStress:   "primary",
+	SecondaryStress: "secondary",
+	EmphaticStress:  "emphatic",
+}
+
+func (s Stress) String() string {
+	if name, ok := stressNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Stress(%d)", int(s))
+}
+
+// Phoneme is a single phoneme as written by the engine in log-file mode.
+type Phoneme struct {
+	// Symbol is the arpabet symbol of the phoneme, for example "ow".
+	Symbol string
+
+	// Stress is the stress mark written right before the phoneme.
+	Stress Stress
+
+	// Word is the index of the word the phoneme belongs to, counting from 0.
+	Word int
+
+	// Syllable is the index of the syllable within the word, counting from
+	// 0. It is only known for output of the [Syllables] log.
+	Syllable int
+}
+
+// String returns the phoneme in arpabet, including its stress mark.
+func (p Phoneme) String() string {
+	return p.Stress.mark() + p.Symbol
+}
+
+// Syllable is a group of phonemes as returned by [TTS.Syllabify].
+type Syllable struct {
+	Phonemes []Phoneme
+
+	// Stress is the strongest stress of any phoneme in the syllable.
+	Stress Stress
+
+	// Word is the index of the word the syllable belongs to, counting from
+	// 0.
+	Word int
+}
+
+// String returns the syllable in arpabet, including its stress mark.
+func (s Syllable) String() string {
+	var b strings.Builder
+	for _, p := range s.Phonemes {
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// arpabetLetters holds the single-letter arpabet symbols.
+var arpabetLetters = map[rune]bool{
+	'b': true, 'd': true, 'f': true, 'g': true, 'k': true, 'l': true,
+	'm': true, 'n': true, 'p': true, 'q': true, 'r': true, 's': true,
+	't': true, 'v': true, 'w': true, 'y': true, 'z': true, '_': true,
+}
+
+// arpabetDigraphs holds the two-letter arpabet symbols.
+var arpabetDigraphs = map[string]bool{
+	// vowels
+	"aa": true, "ae": true, "ah": true, "ao": true, "aw": true, "ax": true,
+	"ay": true, "eh": true, "ey": true, "ih": true, "ix": true, "iy": true,
+	"ow": true, "oy": true, "rr": true, "uh": true, "uw": true, "yu": true,
+	"ar": true, "er": true, "ir": true, "or": true, "ur": true,
+
+	// consonants
+	"ch": true, "dh": true, "dx": true, "el": true, "em": true, "en": true,
+	"hx": true, "jh": true, "lx": true, "nx": true, "rx": true, "sh": true,
+	"th": true, "tx": true, "zh": true,
+}
+
+// ParsePhonemes parses arpabet as written by the engine in log-file mode with
+// the [Phonemes] or [Syllables] flag. Words are separated by white space or
+// punctuation, syllables by hyphens, and a stress mark applies to the phoneme
+// right after it. Brackets are ignored, so phoneme blocks as accepted by
+// [TTS.Speak] are parsed as well.
+//
+// Letters between these separators are split into the symbols of the arpabet
+// of the engine, see [segmentArpabet].
+func ParsePhonemes(log string) []Phoneme {
+	var phonemes []Phoneme
+	word, syllable := 0, 0
+	stress := Unstressed
+	inWord := false
+	var letters []rune
+
+	flush := func() {
+		for _, symbol := range segmentArpabet(letters) {
+			phonemes = append(phonemes, Phoneme{
+				Symbol:   symbol,
+				Stress:   stress,
+				Word:     word,
+				Syllable: syllable,
+			})
+			stress = Unstressed
+			inWord = true
+		}
+		letters = letters[:0]
+	}
+	endWord := func() {
+		flush()
+		if inWord {
+			word++
+			inWord = false
+		}
+		syllable = 0
+		stress = Unstressed
+	}
+
+	for _, r := range strings.ToLower(log) {
+		if s, ok := stressMarks[r]; ok {
+			flush()
+			stress = s
+			continue
+		}
+		switch {
+		case r == '[' || r == ']':
+			flush()
+		case r == '-':
+			flush()
+			if inWord {
+				syllable++
+			}
+		case r == '_' || (r >= 'a' && r <= 'z') || r > 0x7f:
+			letters = append(letters, r)
+		default:
+			// White space, punctuation and anything else the engine
+			// writes between words.
+			endWord()
+		}
+	}
+	endWord()
+	return phonemes
+}
+
+// segmentArpabet splits letters written without separators into arpabet
+// symbols. Where they split in more than one way, the split into the fewest
+// symbols is taken, and among those the one with two-letter symbols last, so
+// that "yuw" is y uw rather than yu w. Letters that do not split into symbols
+// of the US English arpabet, such as those of other languages, are split
+// preferring two-letter symbols from the left.
+func segmentArpabet(letters []rune) []string {
+	n := len(letters)
+	if n == 0 {
+		return nil
+	}
+
+	// count[i] is the fewest symbols letters[i:] splits into, or -1, and
+	// size[i] the size of the first of them.
+	count := make([]int, n+1)
+	size := make([]int, n+1)
+	for i := n - 1; i >= 0; i-- {
+		count[i] = -1
+		for _, k := range []int{1, 2} {
+			if i+k > n || count[i+k] < 0 {
+				continue
+			}
+			if k == 1 && !arpabetLetters[letters[i]] || k == 2 && !arpabetDigraphs[string(letters[i:i+2])] {
+				continue
+			}
+			if count[i] < 0 || count[i+k]+1 < count[i] {
+				count[i], size[i] = count[i+k]+1, k
+			}
+		}
+	}
+
+	var symbols []string
+	for i := 0; i < n; {
+		k := size[i]
+		if count[0] < 0 {
+			k = 1
+			if i+1 < n && arpabetDigraphs[string(letters[i:i+2])] {
+				k = 2
+			}
+		}
+		symbols = append(symbols, string(letters[i:i+k]))
+		i += k
+	}
+	return symbols
+}
+
+// GroupSyllables groups phonemes into syllables by their word and syllable
+// index.
+func GroupSyllables(phonemes []Phoneme) []Syllable {
+	var syllables []Syllable
+	for i, p := range phonemes {
+		if i == 0 || p.Word != phonemes[i-1].Word || p.Syllable != phonemes[i-1].Syllable {
+			syllables = append(syllables, Syllable{Word: p.Word})
+		}
+		s := &syllables[len(syllables)-1]
+		s.Phonemes = append(s.Phonemes, p)
+		if p.Stress.rank() > s.Stress.rank() {
+			s.Stress = p.Stress
+		}
+	}
+	return syllables
+}
+
+// Phonemize returns the phonemes the engine would speak for text, without
+// producing any audio. It drives the log-file mode with the [Phonemes] flag
+// through a temporary file and parses its contents with [ParsePhonemes].
+//
+// The text-to-speech system must be in the startup state, otherwise a
+// [*ModeError] is returned. Like [TTS.Speak], text may contain inline
+// commands.
+func (t *TTS) Phonemize(text string) ([]Phoneme, error) {
+	log, err := t.logText(text, Phonemes)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePhonemes(log), nil
+}
+
+// Syllabify is like [TTS.Phonemize], but uses the [Syllables] flag and
+// returns the phonemes grouped into syllables.
+func (t *TTS) Syllabify(text string) ([]Syllable, error) {
+	log, err := t.logText(text, Syllables)
+	if err != nil {
+		return nil, err
+	}
+	return GroupSyllables(ParsePhonemes(log)), nil
+}
+
+// logText speaks text in log-file mode and returns what the engine logged.
+func (t *TTS) logText(text string, log Log) (contents string, err error) {
+	f, err := os.CreateTemp("", "dectalk-*.log")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := t.OpenLogFile(name, log); err != nil {
+		return "", err
+	}
+	defer func() {
+		// A full reset has already returned to the startup state.
+		if t.Mode() != ModeLogFile {
+			return
+		}
+		if closeErr := t.CloseLogFile(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err := t.Speak(text, Force); err != nil {
+		return "", err
+	}
+	if err := t.Sync(); err != nil {
+		return "", err
+	}
+
+	// The engine only flushes the log once it is closed.
+	if err := t.CloseLogFile(); err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
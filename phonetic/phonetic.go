@@ -0,0 +1,417 @@
+// Package phonetic converts pronunciations between IPA, X-SAMPA and the
+// arpabet variant DECtalk uses for phoneme input, as in [hx'ehlow], and for
+// the output of the Phonemes log.
+//
+// Every language of the engine has its own phoneme set, so conversions go
+// through the [Table] of a language:
+//
+//	table, _ := phonetic.Lookup("us")
+//	arpabet, err := table.FromIPA("həˈloʊ")   // "hxaxl'ow"
+//	ipa, err := table.ToIPA(arpabet)          // "həˈloʊ"
+//	command, err := table.Command("həˈloʊ")   // "[hxaxl'ow]"
+//
+// Phoneme commands are only interpreted by the engine while phoneme input is
+// enabled with [:phoneme arpabet speak on].
+package phonetic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrUnknownSymbol is wrapped by [*SymbolError].
+var ErrUnknownSymbol = errors.New("unknown phonetic symbol")
+
+// SymbolError is returned for a symbol that has no counterpart in the target
+// alphabet.
+type SymbolError struct {
+	// Offset is the byte offset of the symbol in the converted string.
+	Offset int
+
+	// Symbol is the symbol that could not be converted.
+	Symbol string
+
+	// Language is the language of the table used for the conversion, or
+	// empty for conversions between IPA and X-SAMPA.
+	Language string
+}
+
+func (e *SymbolError) Error() string {
+	if e.Language == "" {
+		return fmt.Sprintf("unknown phonetic symbol %q at offset %d", e.Symbol, e.Offset)
+	}
+	return fmt.Sprintf("unknown phonetic symbol %q at offset %d for language %q",
+		e.Symbol, e.Offset, e.Language)
+}
+
+func (e *SymbolError) Unwrap() error {
+	return ErrUnknownSymbol
+}
+
+// Phoneme is an entry of a [Table].
+type Phoneme struct {
+	// Arpabet is the DECtalk arpabet symbol, for example "ow".
+	Arpabet string
+
+	// IPA is the IPA transcription of the phoneme, for example "oʊ".
+	IPA string
+
+	// Vowel is true for phonemes that form the nucleus of a syllable and
+	// therefore carry stress.
+	Vowel bool
+}
+
+// Table holds the phoneme set of a language.
+type Table struct {
+	lang     string
+	phonemes []Phoneme
+
+	// fromIPA and fromArpabet map symbols to phonemes. A phoneme may have
+	// alternative IPA spellings, the first one in phonemes is used for
+	// output.
+	fromIPA     map[string]Phoneme
+	fromArpabet map[string]Phoneme
+
+	// maxIPA and maxArpabet are the lengths of the longest symbols in
+	// bytes.
+	maxIPA     int
+	maxArpabet int
+}
+
+func newTable(lang string, phonemes []Phoneme) *Table {
+	t := &Table{
+		lang:        lang,
+		phonemes:    phonemes,
+		fromIPA:     make(map[string]Phoneme),
+		fromArpabet: make(map[string]Phoneme),
+	}
+	for _, p := range phonemes {
+		if _, ok := t.fromIPA[p.IPA]; !ok {
+			t.fromIPA[p.IPA] = p
+		}
+		if _, ok := t.fromArpabet[p.Arpabet]; !ok {
+			t.fromArpabet[p.Arpabet] = p
+		}
+		if len(p.IPA) > t.maxIPA {
+			t.maxIPA = len(p.IPA)
+		}
+		if len(p.Arpabet) > t.maxArpabet {
+			t.maxArpabet = len(p.Arpabet)
+		}
+	}
+	return t
+}
+
+// Lookup returns the table for a 2-character language ID as accepted by
+// [dectalkdapi.StartLang] and reported by [dectalkdapi.LangEntry.LangCode],
+// such as "us" or "gr".
+func Lookup(lang string) (*Table, bool) {
+	t, ok := tables[strings.ToLower(lang)]
+	return t, ok
+}
+
+// tags maps language tags, in lower case, to language IDs. Tags not found here
+// are looked up by their primary language subtag.
+var tags = map[string]string{
+	"en":     "us",
+	"en-us":  "us",
+	"en-gb":  "uk",
+	"de":     "gr",
+	"es":     "sp",
+	"es-es":  "sp",
+	"es-419": "la",
+	"es-mx":  "la",
+	"fr":     "fr",
+	"it":     "it",
+}
+
+// LookupTag returns the table for a language tag such as "en-GB" or "es-419",
+// as used by the xml:lang attribute. Language IDs accepted by [Lookup] are
+// accepted as well.
+func LookupTag(tag string) (*Table, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	if t, ok := tables[tag]; ok {
+		return t, true
+	}
+	lang, ok := tags[tag]
+	if !ok {
+		primary, _, _ := strings.Cut(tag, "-")
+		if lang, ok = tags[primary]; !ok {
+			return nil, false
+		}
+	}
+	return Lookup(lang)
+}
+
+// Languages returns the IDs of all languages that have a table, in
+// alphabetical order.
+func Languages() []string {
+	langs := make([]string, 0, len(tables))
+	for lang := range tables {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Language returns the 2-character language ID of the table.
+func (t *Table) Language() string {
+	return t.lang
+}
+
+// Phonemes returns all entries of the table. Phonemes with alternative IPA
+// spellings appear more than once.
+func (t *Table) Phonemes() []Phoneme {
+	return append([]Phoneme(nil), t.phonemes...)
+}
+
+// IPA stress marks and their arpabet counterparts.
+const (
+	ipaPrimary   = 'ˈ'
+	ipaSecondary = 'ˌ'
+
+	arpabetPrimary   = '\''
+	arpabetSecondary = '`'
+	arpabetEmphatic  = '"'
+)
+
+// ipaNormalize removes tie bars and maps common alternative spellings to the
+// ones used by the tables.
+var ipaNormalize = strings.NewReplacer(
+	"\u0361", "",
+	"\u035c", "",
+	"g", "ɡ",
+	"'", "ˈ",
+)
+
+// ipaLength holds the length marks, which are ignored unless they are part of
+// a phoneme of the table or follow a consonant, which is then doubled.
+var ipaLength = map[rune]bool{
+	'ː': true,
+	'ˑ': true,
+}
+
+// FromIPA converts an IPA transcription to arpabet. Spaces separate words,
+// syllable boundaries (".") become hyphens, and stress marks are moved from the
+// start of the syllable to its vowel, where the engine expects them.
+//
+// The offset of a [*SymbolError] refers to ipa after tie bars have been
+// removed.
+func (t *Table) FromIPA(ipa string) (string, error) {
+	ipa = ipaNormalize.Replace(ipa)
+
+	var b strings.Builder
+	var stress rune
+	var last *Phoneme
+	for i := 0; i < len(ipa); {
+		r, size := utf8.DecodeRuneInString(ipa[i:])
+		switch {
+		case r == ipaPrimary:
+			stress = arpabetPrimary
+		case r == ipaSecondary:
+			stress = arpabetSecondary
+		case r == '.':
+			b.WriteByte('-')
+			last = nil
+		case unicode.IsSpace(r):
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), " ") {
+				b.WriteByte(' ')
+			}
+			stress = 0
+			last = nil
+		default:
+			symbol, p, ok := t.matchIPA(ipa[i:])
+			if !ok {
+				if !ipaLength[r] {
+					return "", &SymbolError{Offset: i, Symbol: string(r), Language: t.lang}
+				}
+				if last != nil && !last.Vowel {
+					b.WriteString(last.Arpabet)
+				}
+				break
+			}
+			if stress != 0 && p.Vowel {
+				b.WriteRune(stress)
+				stress = 0
+			}
+			b.WriteString(p.Arpabet)
+			last = &p
+			size = len(symbol)
+		}
+		i += size
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// matchIPA returns the longest IPA symbol of the table at the start of s. An
+// r-colored vowel, such as "ɪɹ", is not used if a vowel follows, since the "ɹ"
+// then starts the next syllable.
+func (t *Table) matchIPA(s string) (string, Phoneme, bool) {
+	symbol, p, ok := longestMatch(s, t.maxIPA, t.fromIPA)
+	if ok && p.Vowel && len(symbol) > len("ɹ") && strings.HasSuffix(symbol, "ɹ") {
+		if _, next, ok := longestMatch(s[len(symbol):], t.maxIPA, t.fromIPA); ok && next.Vowel {
+			return longestMatch(s, len(symbol)-len("ɹ"), t.fromIPA)
+		}
+	}
+	return symbol, p, ok
+}
+
+// Command converts an IPA transcription like [Table.FromIPA] and wraps it in
+// brackets, ready to be passed to [dectalkdapi.TTS.Speak].
+func (t *Table) Command(ipa string) (string, error) {
+	arpabet, err := t.FromIPA(ipa)
+	if err != nil {
+		return "", err
+	}
+	return "[" + arpabet + "]", nil
+}
+
+// ToIPA converts arpabet, with or without the brackets of a phoneme command,
+// to IPA. Stress marks are moved from the vowel to the start of its syllable.
+// Emphatic stress becomes primary stress, since IPA has no counterpart.
+func (t *Table) ToIPA(arpabet string) (string, error) {
+	var words []string
+	start := -1
+	for i := 0; i <= len(arpabet); i++ {
+		if i < len(arpabet) && !isArpabetSeparator(arpabet[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			word, err := t.wordToIPA(arpabet[start:i], start)
+			if err != nil {
+				return "", err
+			}
+			words = append(words, word)
+			start = -1
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+func isArpabetSeparator(c byte) bool {
+	return c == '[' || c == ']' || c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// wordToIPA converts a single word of arpabet to IPA. offset is the position
+// of the word in the original string, for errors.
+func (t *Table) wordToIPA(word string, offset int) (string, error) {
+	// segments holds the IPA of each phoneme and boundary, so that stress
+	// marks can be inserted at the start of a syllable afterwards.
+	var segments []string
+	lastVowel, lastBoundary := -1, -1
+	var stress rune
+	lower := strings.ToLower(word)
+	for i := 0; i < len(lower); {
+		switch c := lower[i]; c {
+		case arpabetPrimary, arpabetEmphatic:
+			stress = ipaPrimary
+			i++
+			continue
+		case arpabetSecondary:
+			stress = ipaSecondary
+			i++
+			continue
+		case '-':
+			segments = append(segments, ".")
+			lastBoundary = len(segments) - 1
+			i++
+			continue
+		}
+
+		symbol, p, ok := longestMatch(lower[i:], t.maxArpabet, t.fromArpabet)
+		if !ok {
+			r, _ := utf8.DecodeRuneInString(lower[i:])
+			return "", &SymbolError{Offset: offset + i, Symbol: string(r), Language: t.lang}
+		}
+		if p.Vowel {
+			if stress != 0 {
+				at := syllableStart(segments, lastVowel, lastBoundary)
+				segments = append(segments[:at], append([]string{string(stress)}, segments[at:]...)...)
+				stress = 0
+			}
+			lastVowel = len(segments)
+		}
+		segments = append(segments, p.IPA)
+		i += len(symbol)
+	}
+	return strings.Join(segments, ""), nil
+}
+
+// syllableStart returns the index in segments at which the syllable of a vowel
+// about to be appended starts. A syllable boundary is respected if there is
+// one, and all consonants at the start of a word belong to the first
+// syllable. Otherwise the onset is the consonant right before the vowel,
+// extended by an obstruent before a liquid or glide and by "s" or "ʃ" before a
+// stop, as in "ɛkˈstɹɑ".
+func syllableStart(segments []string, lastVowel, lastBoundary int) int {
+	if lastBoundary > lastVowel {
+		return lastBoundary + 1
+	}
+	if lastVowel < 0 {
+		return 0
+	}
+	start := len(segments)
+	if start-1 > lastVowel {
+		start--
+	}
+	if start-1 > lastVowel && onsetLiquids[segments[start]] && onsetObstruents[segments[start-1]] {
+		start--
+	}
+	if start-1 > lastVowel && onsetStops[segments[start]] && onsetSibilants[segments[start-1]] {
+		start--
+	}
+	return start
+}
+
+var (
+	onsetLiquids    = map[string]bool{"ɹ": true, "r": true, "ɾ": true, "ʁ": true, "l": true, "w": true, "j": true}
+	onsetStops      = map[string]bool{"p": true, "b": true, "t": true, "d": true, "k": true, "ɡ": true}
+	onsetSibilants  = map[string]bool{"s": true, "ʃ": true}
+	onsetObstruents = map[string]bool{
+		"p": true, "b": true, "t": true, "d": true, "k": true, "ɡ": true,
+		"f": true, "v": true, "θ": true, "ʃ": true,
+	}
+)
+
+// longestMatch returns the longest prefix of s, of at most max bytes, that is
+// a key of symbols.
+func longestMatch(s string, max int, symbols map[string]Phoneme) (string, Phoneme, bool) {
+	if max > len(s) {
+		max = len(s)
+	}
+	for n := max; n > 0; n-- {
+		if !utf8.ValidString(s[:n]) {
+			continue
+		}
+		if p, ok := symbols[s[:n]]; ok {
+			return s[:n], p, true
+		}
+	}
+	return "", Phoneme{}, false
+}
+
+// FromXSAMPA converts an X-SAMPA transcription to arpabet like
+// [Table.FromIPA].
+func (t *Table) FromXSAMPA(xsampa string) (string, error) {
+	ipa, err := XSAMPAToIPA(xsampa)
+	if err != nil {
+		return "", err
+	}
+	return t.FromIPA(ipa)
+}
+
+// ToXSAMPA converts arpabet to X-SAMPA like [Table.ToIPA].
+func (t *Table) ToXSAMPA(arpabet string) (string, error) {
+	ipa, err := t.ToIPA(arpabet)
+	if err != nil {
+		return "", err
+	}
+	return IPAToXSAMPA(ipa)
+}
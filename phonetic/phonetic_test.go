@@ -0,0 +1,94 @@
+package phonetic_test
+
+import (
+	"errors"
+	"github.com/icedream/go-dectalkdapi/phonetic"
+	"testing"
+)
+
+func TestFromIPA(t *testing.T) {
+	tests := []struct {
+		lang string
+		ipa  string
+		want string
+	}{
+		{"us", "həˈloʊ ˈwɝld", "hxaxl'ow w'rrld"},
+		{"us", "ˈtʃɪɹ", "ch'ir"},
+		{"us", "ˈvɛɹi", "v'ehriy"},
+		{"us", "ˈbʌt͡ʃɚ", "b'ahchrr"},
+		{"uk", "ˈhɒt", "hx'aot"},
+		{"gr", "ˈʃtʁaːsə", "shtr'aasax"},
+		{"sp", "ˈpeɾo", "p'ero"},
+		{"sp", "ˈpe.ro", "p'e-rro"},
+		{"it", "ˈbɛlːo", "b'ehllo"},
+		{"fr", "bɔ̃ˈʒuʁ", "bonzh'uwr"},
+	}
+	for _, test := range tests {
+		table, ok := phonetic.Lookup(test.lang)
+		if !ok {
+			t.Fatalf("Lookup(%q) failed", test.lang)
+		}
+		got, err := table.FromIPA(test.ipa)
+		if err != nil {
+			t.Errorf("%s: FromIPA(%q) failed: %v", test.lang, test.ipa, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: FromIPA(%q) = %q, want %q", test.lang, test.ipa, got, test.want)
+		}
+	}
+
+	table, _ := phonetic.Lookup("us")
+	if _, err := table.FromIPA("ʘ"); !errors.Is(err, phonetic.ErrUnknownSymbol) {
+		t.Errorf("Expected ErrUnknownSymbol, got %v", err)
+	}
+}
+
+func TestToIPA(t *testing.T) {
+	table, _ := phonetic.Lookup("us")
+	tests := []struct {
+		arpabet string
+		want    string
+	}{
+		{"[hxaxl'ow w'rrld]", "həˈloʊ ˈwɝld"},
+		{"ehkstr'aa", "ɛkˈstɹɑ"},
+		{"k'ae-pax-bax-r`ax", "ˈkæ.pə.bə.ˌɹə"},
+	}
+	for _, test := range tests {
+		got, err := table.ToIPA(test.arpabet)
+		if err != nil {
+			t.Errorf("ToIPA(%q) failed: %v", test.arpabet, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ToIPA(%q) = %q, want %q", test.arpabet, got, test.want)
+		}
+	}
+}
+
+func TestXSAMPA(t *testing.T) {
+	ipa, err := phonetic.XSAMPAToIPA("h@\"loU \"w3`ld")
+	if err != nil {
+		t.Fatalf("XSAMPAToIPA() failed: %v", err)
+	}
+	if want := "həˈloʊ ˈwɝld"; ipa != want {
+		t.Errorf("XSAMPAToIPA() = %q, want %q", ipa, want)
+	}
+
+	xsampa, err := phonetic.IPAToXSAMPA(ipa)
+	if err != nil {
+		t.Fatalf("IPAToXSAMPA() failed: %v", err)
+	}
+	if want := "h@\"loU \"w3`ld"; xsampa != want {
+		t.Errorf("IPAToXSAMPA() = %q, want %q", xsampa, want)
+	}
+
+	table, _ := phonetic.Lookup("us")
+	command, err := table.Command(ipa)
+	if err != nil {
+		t.Fatalf("Command() failed: %v", err)
+	}
+	if want := "[hxaxl'ow w'rrld]"; command != want {
+		t.Errorf("Command() = %q, want %q", command, want)
+	}
+}
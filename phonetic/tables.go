@@ -0,0 +1,259 @@
+package phonetic
+
+// tables holds the phoneme sets of all languages of the engine, keyed by the
+// 2-character language ID.
+var tables = map[string]*Table{
+	"us": newTable("us", english),
+	"uk": newTable("uk", append(britishOverrides, english...)),
+	"gr": newTable("gr", german),
+	"sp": newTable("sp", spanish),
+	"la": newTable("la", append(latinAmericanOverrides, spanish...)),
+	"fr": newTable("fr", french),
+	"it": newTable("it", italian),
+}
+
+// vowel and consonant keep the tables below short.
+func vowel(arpabet, ipa string) Phoneme {
+	return Phoneme{Arpabet: arpabet, IPA: ipa, Vowel: true}
+}
+
+func consonant(arpabet, ipa string) Phoneme {
+	return Phoneme{Arpabet: arpabet, IPA: ipa}
+}
+
+// english is the phoneme set of American English. Entries that repeat an
+// arpabet symbol are alternative IPA spellings.
+var english = []Phoneme{
+	vowel("iy", "i"), vowel("iy", "iː"),
+	vowel("ih", "ɪ"),
+	vowel("ey", "eɪ"), vowel("ey", "e"),
+	vowel("eh", "ɛ"),
+	vowel("ae", "æ"),
+	vowel("aa", "ɑ"), vowel("aa", "ɑː"), vowel("aa", "ɒ"),
+	vowel("ay", "aɪ"),
+	vowel("aw", "aʊ"),
+	vowel("ah", "ʌ"),
+	vowel("ao", "ɔ"), vowel("ao", "ɔː"),
+	vowel("ow", "oʊ"), vowel("ow", "o"),
+	vowel("oy", "ɔɪ"),
+	vowel("uh", "ʊ"),
+	vowel("uw", "u"), vowel("uw", "uː"),
+	vowel("rr", "ɝ"), vowel("rr", "ɚ"), vowel("rr", "ɜː"), vowel("rr", "ɜ"),
+	vowel("yu", "ju"), vowel("yu", "juː"),
+	vowel("ax", "ə"),
+	vowel("ix", "ɨ"),
+	vowel("ir", "ɪɹ"),
+	vowel("er", "ɛɹ"),
+	vowel("ar", "ɑɹ"),
+	vowel("or", "ɔɹ"),
+	vowel("ur", "ʊɹ"),
+	vowel("el", "l̩"),
+	vowel("em", "m̩"),
+	vowel("en", "n̩"),
+
+	consonant("p", "p"),
+	consonant("b", "b"),
+	consonant("t", "t"),
+	consonant("d", "d"),
+	consonant("k", "k"),
+	consonant("g", "ɡ"),
+	consonant("dx", "ɾ"),
+	consonant("q", "ʔ"),
+	consonant("ch", "tʃ"), consonant("ch", "ʧ"),
+	consonant("jh", "dʒ"), consonant("jh", "ʤ"),
+	consonant("f", "f"),
+	consonant("v", "v"),
+	consonant("th", "θ"),
+	consonant("dh", "ð"),
+	consonant("s", "s"),
+	consonant("z", "z"),
+	consonant("sh", "ʃ"),
+	consonant("zh", "ʒ"),
+	consonant("hx", "h"),
+	consonant("m", "m"),
+	consonant("n", "n"),
+	consonant("nx", "ŋ"),
+	consonant("l", "l"),
+	consonant("lx", "ɫ"),
+	consonant("r", "ɹ"), consonant("r", "r"),
+	consonant("w", "w"),
+	consonant("y", "j"),
+}
+
+// britishOverrides take precedence over english for British English, which is
+// not rhotic and has the rounded "ɒ".
+var britishOverrides = []Phoneme{
+	vowel("ao", "ɒ"),
+	vowel("ow", "əʊ"),
+	vowel("ir", "ɪə"),
+	vowel("er", "eə"), vowel("er", "ɛə"),
+	vowel("ur", "ʊə"),
+}
+
+var german = []Phoneme{
+	vowel("iy", "iː"), vowel("iy", "i"),
+	vowel("ih", "ɪ"),
+	vowel("yy", "yː"), vowel("yy", "y"),
+	vowel("yh", "ʏ"),
+	vowel("ey", "eː"), vowel("ey", "e"),
+	vowel("eh", "ɛ"),
+	vowel("ea", "ɛː"),
+	vowel("oe", "øː"), vowel("oe", "ø"),
+	vowel("oh", "œ"),
+	vowel("aa", "aː"),
+	vowel("ah", "a"),
+	vowel("ow", "oː"), vowel("ow", "o"),
+	vowel("ao", "ɔ"),
+	vowel("uw", "uː"), vowel("uw", "u"),
+	vowel("uh", "ʊ"),
+	vowel("ax", "ə"),
+	vowel("ex", "ɐ"),
+	vowel("ay", "aɪ"),
+	vowel("aw", "aʊ"),
+	vowel("oy", "ɔʏ"), vowel("oy", "ɔɪ"),
+
+	consonant("p", "p"),
+	consonant("b", "b"),
+	consonant("t", "t"),
+	consonant("d", "d"),
+	consonant("k", "k"),
+	consonant("g", "ɡ"),
+	consonant("q", "ʔ"),
+	consonant("pf", "pf"),
+	consonant("ts", "ts"),
+	consonant("ch", "tʃ"),
+	consonant("jh", "dʒ"),
+	consonant("f", "f"),
+	consonant("v", "v"),
+	consonant("s", "s"),
+	consonant("z", "z"),
+	consonant("sh", "ʃ"),
+	consonant("zh", "ʒ"),
+	consonant("cx", "ç"),
+	consonant("kx", "x"),
+	consonant("hx", "h"),
+	consonant("m", "m"),
+	consonant("n", "n"),
+	consonant("nx", "ŋ"),
+	consonant("l", "l"),
+	consonant("r", "ʁ"), consonant("r", "r"), consonant("r", "ʀ"),
+	consonant("y", "j"),
+}
+
+var spanish = []Phoneme{
+	vowel("a", "a"),
+	vowel("e", "e"),
+	vowel("i", "i"),
+	vowel("o", "o"),
+	vowel("u", "u"),
+
+	consonant("y", "j"), consonant("y", "i̯"),
+	consonant("w", "w"), consonant("w", "u̯"),
+	consonant("p", "p"),
+	consonant("b", "b"),
+	consonant("bh", "β"),
+	consonant("t", "t"),
+	consonant("d", "d"),
+	consonant("dh", "ð"),
+	consonant("k", "k"),
+	consonant("g", "ɡ"),
+	consonant("gh", "ɣ"),
+	consonant("f", "f"),
+	consonant("th", "θ"),
+	consonant("s", "s"),
+	consonant("z", "z"),
+	consonant("x", "x"),
+	consonant("ch", "tʃ"),
+	consonant("jj", "ʝ"),
+	consonant("m", "m"),
+	consonant("n", "n"),
+	consonant("nh", "ɲ"),
+	consonant("nx", "ŋ"),
+	consonant("l", "l"),
+	consonant("ll", "ʎ"),
+	consonant("r", "ɾ"),
+	consonant("rr", "r"),
+}
+
+// latinAmericanOverrides take precedence over spanish for Latin American
+// Spanish, which has no "θ" (seseo) and merges "ʎ" into "ʝ" (yeísmo).
+var latinAmericanOverrides = []Phoneme{
+	consonant("s", "θ"),
+	consonant("jj", "ʎ"),
+}
+
+var french = []Phoneme{
+	vowel("iy", "i"),
+	vowel("ey", "e"),
+	vowel("eh", "ɛ"),
+	vowel("aa", "a"),
+	vowel("ah", "ɑ"),
+	vowel("ao", "ɔ"),
+	vowel("ow", "o"),
+	vowel("uw", "u"),
+	vowel("ux", "y"),
+	vowel("eu", "ø"),
+	vowel("oe", "œ"),
+	vowel("ax", "ə"),
+	vowel("in", "ɛ̃"),
+	vowel("an", "ɑ̃"),
+	vowel("on", "ɔ̃"),
+	vowel("un", "œ̃"),
+
+	consonant("y", "j"),
+	consonant("w", "w"),
+	consonant("hw", "ɥ"),
+	consonant("p", "p"),
+	consonant("b", "b"),
+	consonant("t", "t"),
+	consonant("d", "d"),
+	consonant("k", "k"),
+	consonant("g", "ɡ"),
+	consonant("f", "f"),
+	consonant("v", "v"),
+	consonant("s", "s"),
+	consonant("z", "z"),
+	consonant("sh", "ʃ"),
+	consonant("zh", "ʒ"),
+	consonant("m", "m"),
+	consonant("n", "n"),
+	consonant("nh", "ɲ"),
+	consonant("nx", "ŋ"),
+	consonant("l", "l"),
+	consonant("r", "ʁ"), consonant("r", "r"),
+}
+
+var italian = []Phoneme{
+	vowel("i", "i"),
+	vowel("e", "e"),
+	vowel("eh", "ɛ"),
+	vowel("a", "a"),
+	vowel("oh", "ɔ"),
+	vowel("o", "o"),
+	vowel("u", "u"),
+
+	consonant("y", "j"),
+	consonant("w", "w"),
+	consonant("p", "p"),
+	consonant("b", "b"),
+	consonant("t", "t"),
+	consonant("d", "d"),
+	consonant("k", "k"),
+	consonant("g", "ɡ"),
+	consonant("ts", "ts"),
+	consonant("dz", "dz"),
+	consonant("ch", "tʃ"),
+	consonant("jh", "dʒ"),
+	consonant("f", "f"),
+	consonant("v", "v"),
+	consonant("s", "s"),
+	consonant("z", "z"),
+	consonant("sh", "ʃ"),
+	consonant("m", "m"),
+	consonant("n", "n"),
+	consonant("nh", "ɲ"),
+	consonant("nx", "ŋ"),
+	consonant("l", "l"),
+	consonant("lj", "ʎ"),
+	consonant("r", "r"), consonant("r", "ɾ"),
+}
@@ -0,0 +1,109 @@
+package phonetic
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// xsampa holds pairs of X-SAMPA and IPA symbols. For IPA symbols that appear
+// more than once, the first pair is used when converting to X-SAMPA.
+var xsampa = [][2]string{
+	// lower case
+	{"a", "a"}, {"b", "b"}, {"b_<", "ɓ"}, {"c", "c"}, {"d", "d"}, {"d`", "ɖ"},
+	{"d_<", "ɗ"}, {"e", "e"}, {"f", "f"}, {"g", "ɡ"}, {"g_<", "ɠ"}, {"h", "h"},
+	{"h\\", "ɦ"}, {"i", "i"}, {"j", "j"}, {"j\\", "ʝ"}, {"k", "k"}, {"l", "l"},
+	{"l`", "ɭ"}, {"l\\", "ɺ"}, {"m", "m"}, {"n", "n"}, {"n`", "ɳ"}, {"o", "o"},
+	{"p", "p"}, {"p\\", "ɸ"}, {"q", "q"}, {"r", "r"}, {"r`", "ɽ"}, {"r\\", "ɹ"},
+	{"r\\`", "ɻ"}, {"s", "s"}, {"s`", "ʂ"}, {"s\\", "ɕ"}, {"t", "t"}, {"t`", "ʈ"},
+	{"u", "u"}, {"v", "v"}, {"v\\", "ʋ"}, {"w", "w"}, {"x", "x"}, {"x\\", "ɧ"},
+	{"y", "y"}, {"z", "z"}, {"z`", "ʐ"}, {"z\\", "ʑ"},
+
+	// upper case
+	{"A", "ɑ"}, {"B", "β"}, {"B\\", "ʙ"}, {"C", "ç"}, {"D", "ð"}, {"E", "ɛ"},
+	{"F", "ɱ"}, {"G", "ɣ"}, {"G\\", "ɢ"}, {"H", "ɥ"}, {"H\\", "ʜ"}, {"I", "ɪ"},
+	{"I\\", "ᵻ"}, {"J", "ɲ"}, {"J\\", "ɟ"}, {"K", "ɬ"}, {"K\\", "ɮ"}, {"L", "ʎ"},
+	{"L\\", "ʟ"}, {"M", "ɯ"}, {"M\\", "ɰ"}, {"N", "ŋ"}, {"N\\", "ɴ"}, {"O", "ɔ"},
+	{"O\\", "ʘ"}, {"P", "ʋ"}, {"Q", "ɒ"}, {"R", "ʁ"}, {"R\\", "ʀ"}, {"S", "ʃ"},
+	{"T", "θ"}, {"U", "ʊ"}, {"U\\", "ᵿ"}, {"V", "ʌ"}, {"W", "ʍ"}, {"X", "χ"},
+	{"X\\", "ħ"}, {"Y", "ʏ"}, {"Z", "ʒ"},
+
+	// other symbols
+	{"@", "ə"}, {"@\\", "ɘ"}, {"@`", "ɚ"}, {"{", "æ"}, {"}", "ʉ"}, {"1", "ɨ"},
+	{"2", "ø"}, {"3", "ɜ"}, {"3\\", "ɞ"}, {"3`", "ɝ"}, {"4", "ɾ"}, {"5", "ɫ"},
+	{"6", "ɐ"}, {"7", "ɤ"}, {"8", "ɵ"}, {"9", "œ"}, {"&", "ɶ"}, {"?", "ʔ"},
+	{"?\\", "ʕ"},
+
+	// suprasegmentals and diacritics
+	{`"`, "ˈ"}, {"%", "ˌ"}, {".", "."}, {":", "ː"}, {":\\", "ˑ"}, {"'", "ʲ"},
+	{"_j", "ʲ"}, {"_h", "ʰ"}, {"_w", "ʷ"}, {"~", "̃"}, {"_~", "̃"},
+	{"=", "̩"}, {"_=", "̩"}, {"_^", "̯"}, {"_0", "̥"},
+	{" ", " "},
+}
+
+var (
+	fromXSAMPA = make(map[string]string)
+	toXSAMPA   = make(map[string]string)
+
+	maxXSAMPA, maxIPA int
+)
+
+func init() {
+	for _, pair := range xsampa {
+		if _, ok := fromXSAMPA[pair[0]]; !ok {
+			fromXSAMPA[pair[0]] = pair[1]
+		}
+		if _, ok := toXSAMPA[pair[1]]; !ok {
+			toXSAMPA[pair[1]] = pair[0]
+		}
+		if len(pair[0]) > maxXSAMPA {
+			maxXSAMPA = len(pair[0])
+		}
+		if len(pair[1]) > maxIPA {
+			maxIPA = len(pair[1])
+		}
+	}
+	// Tie bars, which are dropped, and the plain "g" are common in IPA
+	// input.
+	toXSAMPA["g"] = "g"
+	toXSAMPA["\u0361"] = ""
+	toXSAMPA["\u035c"] = ""
+}
+
+// XSAMPAToIPA converts an X-SAMPA transcription to IPA. The separator "-" is
+// dropped.
+func XSAMPAToIPA(s string) (string, error) {
+	return convert(s, fromXSAMPA, maxXSAMPA, "-")
+}
+
+// IPAToXSAMPA converts an IPA transcription to X-SAMPA.
+func IPAToXSAMPA(s string) (string, error) {
+	return convert(s, toXSAMPA, maxIPA, "")
+}
+
+// convert replaces every symbol of s by the longest matching key of symbols.
+// Occurrences of skip are dropped.
+func convert(s string, symbols map[string]string, max int, skip string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if skip != "" && strings.HasPrefix(s[i:], skip) {
+			i += len(skip)
+			continue
+		}
+		n := max
+		if n > len(s)-i {
+			n = len(s) - i
+		}
+		for ; n > 0; n-- {
+			if to, ok := symbols[s[i:i+n]]; ok {
+				b.WriteString(to)
+				break
+			}
+		}
+		if n == 0 {
+			r, _ := utf8.DecodeRuneInString(s[i:])
+			return "", &SymbolError{Offset: i, Symbol: string(r)}
+		}
+		i += n
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,320 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// PoolConfig configures a [Pool].
+type PoolConfig struct {
+	// Size is the number of instances the pool tries to start. Fewer are
+	// started if the DECtalk license does not provide enough units.
+	Size int
+
+	// Language, if not nil, is selected for every instance, see
+	// [StartupLocked].
+	Language *TTSLanguage
+
+	// UserDictionary, if not empty, is loaded into every instance and
+	// restored after each job. It is ignored while a [DictionaryManager] is
+	// running.
+	UserDictionary string
+
+	// FailFast makes [Pool.Get] return [ErrPoolExhausted] right away instead
+	// of waiting when all instances are in use.
+	FailFast bool
+}
+
+// Pool hands out a fixed set of text-to-speech instances for synthesis jobs.
+// Each instance is started with [StartupLocked] and [DoNotUseAudioDevice], so
+// it can only write to files or memory, for example with [TTS.Synthesize].
+//
+// Between jobs, the state of an instance is reset to what it was right after
+// startup: queued text is flushed, special modes are left and the speaking
+// rate, speaker and user dictionary are restored.
+type Pool struct {
+	config PoolConfig
+
+	idle   chan *TTS
+	closed chan struct{}
+
+	// lost holds a token for every instance that failed to reset and could
+	// not be replaced, which Get uses to try starting it again.
+	lost chan struct{}
+
+	mu       sync.Mutex
+	defaults map[*TTS]poolDefaults
+
+	// taken holds the instances handed out by Get.
+	taken map[*TTS]bool
+}
+
+// poolDefaults holds the settings of an instance right after startup.
+type poolDefaults struct {
+	rate    uint32
+	speaker Speaker
+}
+
+// NewPool starts the instances of a new pool.
+//
+// If the DECtalk license runs out of units ([Allocated]) before config.Size
+// instances have been started, the pool is created with fewer instances, see
+// [Pool.Size]. An error is only returned if not a single instance could be
+// started.
+func NewPool(config PoolConfig) (*Pool, error) {
+	if config.Size < 1 {
+		config.Size = 1
+	}
+
+	p := &Pool{
+		config:   config,
+		idle:     make(chan *TTS, config.Size),
+		closed:   make(chan struct{}),
+		lost:     make(chan struct{}, config.Size),
+		defaults: map[*TTS]poolDefaults{},
+		taken:    map[*TTS]bool{},
+	}
+	for i := 0; i < config.Size; i++ {
+		t, err := p.start()
+		if err != nil {
+			var mmErr *MMError
+			if i > 0 && errors.As(err, &mmErr) && mmErr.Code == Allocated {
+				break
+			}
+			_ = p.Close()
+			return nil, err
+		}
+		p.idle <- t
+	}
+	return p, nil
+}
+
+func (p *Pool) start() (*TTS, error) {
+	t, err := StartupLocked(DoNotUseAudioDevice, p.config.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	var defaults poolDefaults
+	err = p.loadDictionary(t)
+	if err == nil {
+		defaults.rate, err = t.GetRate()
+	}
+	if err == nil {
+		defaults.speaker, err = t.GetSpeaker()
+	}
+	if err != nil {
+		_ = t.Shutdown()
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.defaults[t] = defaults
+	p.mu.Unlock()
+	return t, nil
+}
+
+func (p *Pool) loadDictionary(t *TTS) error {
+	if managed.Load() != nil || t.UserDictionary() == p.config.UserDictionary {
+		return nil
+	}
+	if t.UserDictionary() != "" {
+		if err := t.UnloadUserDictionary(); err != nil {
+			return err
+		}
+	}
+	if p.config.UserDictionary == "" {
+		return nil
+	}
+	return t.LoadUserDictionary(p.config.UserDictionary)
+}
+
+// reset restores the state of an instance after a job.
+func (p *Pool) reset(t *TTS) error {
+	p.mu.Lock()
+	defaults := p.defaults[t]
+	p.mu.Unlock()
+
+	if err := t.Reset(true); err != nil {
+		return err
+	}
+	if t.paused.Load() {
+		if err := t.Resume(); err != nil {
+			return err
+		}
+	}
+	if err := t.SetRate(defaults.rate); err != nil {
+		return err
+	}
+	if err := t.SetSpeaker(defaults.speaker); err != nil {
+		return err
+	}
+	return p.loadDictionary(t)
+}
+
+func (p *Pool) remove(t *TTS) error {
+	p.mu.Lock()
+	delete(p.defaults, t)
+	p.mu.Unlock()
+	return t.Shutdown()
+}
+
+// Size returns the number of instances in the pool.
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.defaults)
+}
+
+// Get takes an instance out of the pool. If all instances are in use, it waits
+// until one is returned with [Pool.Put] or ctx is done, unless
+// [PoolConfig.FailFast] is set.
+//
+// Instances that could not be replaced by Put are started again here; if that
+// fails, the error is returned.
+func (p *Pool) Get(ctx context.Context) (*TTS, error) {
+	select {
+	case <-p.closed:
+		return nil, ErrPoolClosed
+	case t := <-p.idle:
+		return p.take(t), nil
+	default:
+	}
+
+	select {
+	case <-p.lost:
+		return p.replace()
+	default:
+	}
+
+	if p.config.FailFast {
+		return nil, ErrPoolExhausted
+	}
+
+	select {
+	case <-p.closed:
+		return nil, ErrPoolClosed
+	case t := <-p.idle:
+		return p.take(t), nil
+	case <-p.lost:
+		return p.replace()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *Pool) take(t *TTS) *TTS {
+	p.mu.Lock()
+	p.taken[t] = true
+	p.mu.Unlock()
+	return t
+}
+
+// replace starts an instance for a token received from lost, which is put
+// back if that fails.
+func (p *Pool) replace() (*TTS, error) {
+	select {
+	case <-p.closed:
+		p.lost <- struct{}{}
+		return nil, ErrPoolClosed
+	default:
+	}
+	t, err := p.start()
+	if err != nil {
+		p.lost <- struct{}{}
+		return nil, err
+	}
+	return p.take(t), nil
+}
+
+// Put resets an instance taken with [Pool.Get] and returns it to the pool. An
+// instance that fails to reset is shut down and replaced with a new one. If
+// that fails too, the error is returned and the next call to [Pool.Get] tries
+// again.
+//
+// Instances that have not been taken from the pool are rejected with
+// [ErrNotFromPool].
+func (p *Pool) Put(t *TTS) error {
+	p.mu.Lock()
+	if !p.taken[t] {
+		p.mu.Unlock()
+		return ErrNotFromPool
+	}
+	delete(p.taken, t)
+	p.mu.Unlock()
+
+	select {
+	case <-p.closed:
+		return p.remove(t)
+	default:
+	}
+
+	if err := p.reset(t); err != nil {
+		_ = p.remove(t)
+		if t, err = p.start(); err != nil {
+			p.lost <- struct{}{}
+			return err
+		}
+	}
+
+	// Every instance has room in idle, so this never blocks.
+	p.idle <- t
+
+	// Close may have emptied idle in the meantime.
+	select {
+	case <-p.closed:
+		p.drain()
+	default:
+	}
+	return nil
+}
+
+// Do runs f with an instance from the pool and returns the instance
+// afterwards. An error of [Pool.Put] is returned if f succeeds.
+func (p *Pool) Do(ctx context.Context, f func(*TTS) error) (err error) {
+	t, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if putErr := p.Put(t); err == nil {
+			err = putErr
+		}
+	}()
+	return f(t)
+}
+
+// Close shuts down all idle instances. Instances that are in use are shut down
+// once they are returned with [Pool.Put].
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	select {
+	case <-p.closed:
+		p.mu.Unlock()
+		return ErrPoolClosed
+	default:
+	}
+	close(p.closed)
+	p.mu.Unlock()
+
+	return p.drain()
+}
+
+// drain shuts down all idle instances.
+func (p *Pool) drain() error {
+	var err error
+	for {
+		select {
+		case t := <-p.idle:
+			if removeErr := p.remove(t); err == nil {
+				err = removeErr
+			}
+		default:
+			return err
+		}
+	}
+}
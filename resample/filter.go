@@ -0,0 +1,118 @@
+package resample
+
+import "math"
+
+// maxTable is the largest number of coefficients precomputed for all phases
+// of a filter. Ratios of unusual rates have more phases, whose coefficients
+// are computed for every output sample instead.
+const maxTable = 1 << 18
+
+// filter is a windowed-sinc low-pass filter split into one phase for every
+// output sample between two input samples, converting by the ratio up/down.
+type filter struct {
+	up, down int64
+
+	// half is the number of input samples used on either side of an output
+	// sample, which is also the latency of the filter in input samples.
+	half int
+
+	// cutoff is the cutoff frequency relative to the Nyquist frequency of
+	// the input, beta the shape of the Kaiser window.
+	cutoff float64
+	beta   float64
+
+	// table holds the coefficients of all phases, if it fits into maxTable.
+	table []float64
+}
+
+func newFilter(fromRate, toRate int, p preset) *filter {
+	g := gcd(int64(fromRate), int64(toRate))
+	f := &filter{
+		up:     int64(toRate) / g,
+		down:   int64(fromRate) / g,
+		cutoff: p.cutoff,
+		beta:   p.beta,
+	}
+	// When decimating, the cutoff has to move below the Nyquist frequency
+	// of the output, which widens the filter by the same factor.
+	if f.down > f.up {
+		f.cutoff *= float64(f.up) / float64(f.down)
+	}
+	f.half = int(math.Ceil(float64(p.zeroCrossings) * p.cutoff / f.cutoff))
+
+	taps := 2 * f.half
+	if f.up*int64(taps) <= maxTable {
+		f.table = make([]float64, 0, int(f.up)*taps)
+		for phase := int64(0); phase < f.up; phase++ {
+			f.table = f.compute(f.table, phase)
+		}
+	}
+	return f
+}
+
+// taps returns the number of coefficients of each phase.
+func (f *filter) taps() int {
+	return 2 * f.half
+}
+
+// coefficients returns the coefficients of a phase, which apply to the input
+// samples from half-1 before to half after the input sample preceding the
+// output sample. buf may be used to compute them.
+func (f *filter) coefficients(phase int64, buf []float64) []float64 {
+	if f.table != nil {
+		taps := f.taps()
+		start := int(phase) * taps
+		return f.table[start : start+taps]
+	}
+	return f.compute(buf[:0], phase)
+}
+
+// compute appends the coefficients of a phase to dst. They are normalized to
+// a sum of 1, so that every phase passes silence offsets unchanged.
+func (f *filter) compute(dst []float64, phase int64) []float64 {
+	start := len(dst)
+	offset := float64(phase) / float64(f.up)
+	norm := besselI0(f.beta)
+	var sum float64
+	for i := 0; i < f.taps(); i++ {
+		t := float64(i-f.half+1) - offset
+		c := f.cutoff * sinc(f.cutoff*t)
+		if x := t / float64(f.half); x > -1 && x < 1 {
+			c *= besselI0(f.beta*math.Sqrt(1-x*x)) / norm
+		} else {
+			c = 0
+		}
+		dst = append(dst, c)
+		sum += c
+	}
+	for i := start; i < len(dst); i++ {
+		dst[i] /= sum
+	}
+	return dst
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	x *= math.Pi
+	return math.Sin(x) / x
+}
+
+// besselI0 is the modified Bessel function of the first kind of order zero,
+// as used by the Kaiser window.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1.0; term > sum*1e-12; k++ {
+		term *= (x / (2 * k)) * (x / (2 * k))
+		sum += term
+	}
+	return sum
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
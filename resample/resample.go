@@ -0,0 +1,254 @@
+// Package resample converts the samples produced by the engine, which only
+// synthesizes at 11.025 kHz or 8 kHz, to any other sample rate and encoding,
+// such as 48 kHz for video, 16 kHz for speech recognition or 44.1 kHz for
+// browsers.
+//
+// Samples are converted by a polyphase windowed-sinc filter while they are
+// written, so a [Writer] can be passed to [dectalkdapi.TTS.SynthesizeTo] or
+// placed in front of a [container.Writer]:
+//
+//	to := container.Format{Encoding: container.Float32, SampleRate: 48000, Channels: 1}
+//	w, err := resample.NewWriter(out, container.Format1M16, to, resample.Best)
+//	if err != nil {
+//		return err
+//	}
+//	if err := tts.SynthesizeTo(ctx, w, text, dectalkdapi.WaveFormat1M16); err != nil {
+//		return err
+//	}
+//	return w.Close()
+//
+// Like the container package, samples are passed in the layout of the engine:
+// little-endian, with 8-bit linear samples unsigned.
+package resample
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/icedream/go-dectalkdapi/container"
+)
+
+// Quality selects the trade-off between the quality of the filter, its
+// latency and the time it takes to convert. The latencies given apply when
+// increasing the sample rate; when decreasing it, the filter is longer by the
+// ratio of the rates.
+type Quality int
+
+const (
+	// Fast uses a short filter with a latency of 4 input samples, or about
+	// 0.4 ms at 11.025 kHz, which leaves some aliasing and dulls the highest
+	// frequencies. It suits live playback on slow devices.
+	Fast Quality = iota + 1
+
+	// Medium uses a filter with a latency of 16 input samples, or about
+	// 1.5 ms at 11.025 kHz.
+	Medium
+
+	// Best uses a filter with a latency of 32 input samples, or about 2.9 ms
+	// at 11.025 kHz, which keeps aliasing inaudible.
+	Best
+)
+
+func (q Quality) String() string {
+	switch q {
+	case Fast:
+		return "fast"
+	case Medium:
+		return "medium"
+	case Best:
+		return "best"
+	}
+	return fmt.Sprintf("Quality(%d)", int(q))
+}
+
+// preset holds the parameters of the filter for a quality.
+type preset struct {
+	// zeroCrossings is the number of zero crossings of the sinc function on
+	// either side when not decimating.
+	zeroCrossings int
+	cutoff        float64
+	beta          float64
+}
+
+var presets = map[Quality]preset{
+	Fast:   {zeroCrossings: 4, cutoff: 0.8, beta: 5},
+	Medium: {zeroCrossings: 16, cutoff: 0.9, beta: 8},
+	Best:   {zeroCrossings: 32, cutoff: 0.95, beta: 10},
+}
+
+// ErrUnknownQuality is returned by [NewWriter] for qualities other than
+// [Fast], [Medium] and [Best].
+var ErrUnknownQuality = errors.New("unknown resampling quality")
+
+// Writer converts samples from one format to another while writing them to
+// an underlying writer.
+type Writer struct {
+	w        io.Writer
+	from, to container.Format
+
+	// filter is nil if only the encoding changes.
+	filter *filter
+
+	// history holds the input samples still needed by the filter per
+	// channel, the first one being at index base of the input. Indices
+	// before the start of the input hold silence.
+	history [][]float64
+	base    int64
+
+	// read is the number of input frames, next the index of the next
+	// output frame.
+	read int64
+	next int64
+
+	partial []byte
+	samples []float64
+	out     []float64
+	coefs   []float64
+	buf     []byte
+	closed  bool
+}
+
+// NewWriter returns a writer converting samples from one format to another,
+// which must have the same number of channels, and writing them to w.
+func NewWriter(w io.Writer, from, to container.Format, q Quality) (*Writer, error) {
+	p, ok := presets[q]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownQuality, q)
+	}
+	for _, f := range []container.Format{from, to} {
+		if !f.Valid() {
+			return nil, fmt.Errorf("%w: %v", container.ErrUnsupportedFormat, f)
+		}
+	}
+	if from.Channels != to.Channels {
+		return nil, fmt.Errorf("%w: converting %d to %d channels", container.ErrUnsupportedFormat, from.Channels, to.Channels)
+	}
+
+	rw := &Writer{
+		w:    w,
+		from: from,
+		to:   to,
+	}
+	if from.SampleRate != to.SampleRate {
+		rw.filter = newFilter(from.SampleRate, to.SampleRate, p)
+		rw.history = make([][]float64, from.Channels)
+		for c := range rw.history {
+			rw.history[c] = make([]float64, rw.filter.half-1)
+		}
+		rw.base = -int64(rw.filter.half - 1)
+		rw.coefs = make([]float64, 0, rw.filter.taps())
+	}
+	return rw, nil
+}
+
+// Write converts samples and writes the samples of the output that are
+// complete so far. Samples may be split across calls.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed resampling writer")
+	}
+	frameSize := w.from.FrameSize()
+	in := p
+	if len(w.partial) > 0 {
+		in = append(w.partial, p...)
+	}
+	whole := len(in) - len(in)%frameSize
+	w.samples = container.Decode(w.samples[:0], in[:whole], w.from.Encoding)
+	w.partial = append(w.partial[:0], in[whole:]...)
+
+	if w.filter == nil {
+		w.out = append(w.out[:0], w.samples...)
+	} else {
+		channels := len(w.history)
+		for i, x := range w.samples {
+			w.history[i%channels] = append(w.history[i%channels], x)
+		}
+		w.read += int64(len(w.samples) / channels)
+		w.filterAvailable()
+	}
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the remaining samples of the output, as if the input was
+// followed by silence. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if len(w.partial) > 0 {
+		return container.ErrPartialSample
+	}
+	if w.filter == nil {
+		return nil
+	}
+	for c := range w.history {
+		w.history[c] = append(w.history[c], make([]float64, w.filter.half)...)
+	}
+	w.filterAvailable()
+	return w.flush()
+}
+
+// filterAvailable computes the output frames whose input is available into
+// out, and drops the input no longer needed. Output frames end with the last
+// one falling within the input.
+func (w *Writer) filterAvailable() {
+	f := w.filter
+	available := w.base + int64(len(w.history[0]))
+	w.out = w.out[:0]
+	for ; w.next*f.down < w.read*f.up; w.next++ {
+		pos := w.next * f.down
+		n := pos / f.up
+		if n+int64(f.half) >= available {
+			break
+		}
+		coefs := f.coefficients(pos%f.up, w.coefs)
+		start := int(n - int64(f.half) + 1 - w.base)
+		for _, h := range w.history {
+			var y float64
+			for i, c := range coefs {
+				y += c * h[start+i]
+			}
+			w.out = append(w.out, y)
+		}
+	}
+
+	// Keep the input of the next output frame.
+	drop := w.next*f.down/f.up - int64(f.half) + 1 - w.base
+	if drop > 0 {
+		for c, h := range w.history {
+			w.history[c] = append(h[:0], h[drop:]...)
+		}
+		w.base += drop
+	}
+}
+
+func (w *Writer) flush() error {
+	if len(w.out) == 0 {
+		return nil
+	}
+	w.buf = container.Encode(w.buf[:0], w.out, w.to.Encoding)
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// Resample converts samples from one format to another at once.
+func Resample(p []byte, from, to container.Format, q Quality) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, from, to, q)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(p); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
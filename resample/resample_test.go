@@ -0,0 +1,107 @@
+package resample_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/icedream/go-dectalkdapi/container"
+	"github.com/icedream/go-dectalkdapi/resample"
+	"math"
+	"testing"
+)
+
+// sine returns one second of a 440 Hz tone at half the full scale.
+func sine(rate int) []float64 {
+	samples := make([]float64, rate)
+	for i := range samples {
+		samples[i] = 0.5 * math.Sin(2*math.Pi*440*float64(i)/float64(rate))
+	}
+	return samples
+}
+
+func TestResample(t *testing.T) {
+	from := container.Format1M16
+	in := container.Encode(nil, sine(from.SampleRate), from.Encoding)
+	for _, rate := range []int{8000, 16000, 22050, 44100, 48000} {
+		for q, maxErr := range map[resample.Quality]float64{
+			resample.Fast:   2e-3,
+			resample.Medium: 1e-4,
+			resample.Best:   5e-5,
+		} {
+			to := container.Format{Encoding: container.Float32, SampleRate: rate, Channels: 1}
+			out, err := resample.Resample(in, from, to, q)
+			if err != nil {
+				t.Fatalf("Resample(%d, %v) failed: %v", rate, q, err)
+			}
+			got := container.Decode(nil, out, to.Encoding)
+			if len(got) != rate {
+				t.Errorf("Resample(%d, %v): got %d samples, want %d", rate, q, len(got), rate)
+				continue
+			}
+
+			// Compare with the ideal tone away from the edges.
+			want := sine(rate)
+			var sum float64
+			for i := rate / 10; i < rate*9/10; i++ {
+				sum += (got[i] - want[i]) * (got[i] - want[i])
+			}
+			if rms := math.Sqrt(sum / float64(rate*8/10)); rms > maxErr {
+				t.Errorf("Resample(%d, %v): got an RMS error of %g, want at most %g", rate, q, rms, maxErr)
+			}
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	from := container.Format08M08
+	to := container.Format{Encoding: container.PCM24, SampleRate: 48000, Channels: 1}
+	in := container.Encode(nil, sine(from.SampleRate), from.Encoding)
+	want, err := resample.Resample(in, from, to, resample.Medium)
+	if err != nil {
+		t.Fatalf("Resample() failed: %v", err)
+	}
+
+	var b bytes.Buffer
+	w, err := resample.NewWriter(&b, from, to, resample.Medium)
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	for len(in) > 0 {
+		n := 777
+		if n > len(in) {
+			n = len(in)
+		}
+		if _, err := w.Write(in[:n]); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		in = in[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("got %d bytes written in chunks differing from %d bytes converted at once", b.Len(), len(want))
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	// Without a change of the rate, only the encoding is converted.
+	in := []byte{0x00, 0x80, 0xff, 0x7f, 0x34, 0x12}
+	to := container.Format{Encoding: container.PCM24, SampleRate: 11025, Channels: 1}
+	got, err := resample.Resample(in, container.Format1M16, to, resample.Fast)
+	if err != nil {
+		t.Fatalf("Resample() failed: %v", err)
+	}
+	if want := []byte{0x00, 0x00, 0x80, 0x00, 0xff, 0x7f, 0x00, 0x34, 0x12}; !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := resample.NewWriter(nil, container.Format1M16, container.Format1M16, 0); !errors.Is(err, resample.ErrUnknownQuality) {
+		t.Errorf("NewWriter() with quality 0: got %v, want ErrUnknownQuality", err)
+	}
+	stereo := container.Format{Encoding: container.PCM16, SampleRate: 48000, Channels: 2}
+	if _, err := resample.NewWriter(nil, container.Format1M16, stereo, resample.Best); !errors.Is(err, container.ErrUnsupportedFormat) {
+		t.Errorf("NewWriter() from mono to stereo: got %v, want ErrUnsupportedFormat", err)
+	}
+}
@@ -0,0 +1,278 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package script
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi"
+)
+
+// Text is plain text to be spoken. It is rendered as-is, so it must not
+// contain inline commands of its own.
+type Text string
+
+func (t Text) String() string {
+	return string(t)
+}
+
+// Name switches to one of the built-in speakers, for example [:name paul].
+type Name struct {
+	Speaker dectalkdapi.Speaker
+}
+
+func (n Name) String() string {
+	return fmt.Sprintf("[:name %s]", n.Speaker)
+}
+
+// Rate sets the speaking rate in words per minute, for example [:rate 120].
+// The engine accepts values from 75 to 600.
+type Rate struct {
+	WordsPerMinute int
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("[:rate %d]", r.WordsPerMinute)
+}
+
+// VolumeOp selects how [Volume] changes the volume.
+type VolumeOp string
+
+const (
+	// Set the volume to the given value.
+	VolumeSet VolumeOp = "set"
+
+	// Raise the volume by the given value.
+	VolumeUp VolumeOp = "up"
+
+	// Lower the volume by the given value.
+	VolumeDown VolumeOp = "down"
+)
+
+// Volume changes the volume, for example [:volume set 50]. The volume ranges
+// from 0 to 100.
+type Volume struct {
+	Op    VolumeOp
+	Value int
+}
+
+func (v Volume) String() string {
+	return fmt.Sprintf("[:volume %s %d]", v.Op, v.Value)
+}
+
+// VoiceParamName is the two-letter name of a voice parameter used with
+// [DefineVoice].
+type VoiceParamName string
+
+const (
+	// Sex of the voice, 0 for female and 1 for male.
+	Sex VoiceParamName = "sx"
+
+	// Head size in percent of the default.
+	HeadSize VoiceParamName = "hs"
+
+	// Average pitch in Hz.
+	AveragePitch VoiceParamName = "ap"
+
+	// Pitch range in percent of the default.
+	PitchRange VoiceParamName = "pr"
+
+	// Breathiness in dB.
+	Breathiness VoiceParamName = "br"
+
+	// Lax breathiness in percent.
+	LaxBreathiness VoiceParamName = "lx"
+
+	// Smoothness in percent.
+	Smoothness VoiceParamName = "sm"
+
+	// Richness in percent.
+	Richness VoiceParamName = "ri"
+
+	// Laryngealization in percent.
+	Laryngealization VoiceParamName = "la"
+
+	// Assertiveness in percent.
+	Assertiveness VoiceParamName = "as"
+
+	// Quickness in percent.
+	Quickness VoiceParamName = "qu"
+
+	// Baseline fall in Hz.
+	BaselineFall VoiceParamName = "bf"
+
+	// Hat rise in Hz.
+	HatRise VoiceParamName = "hr"
+
+	// Stress rise in Hz.
+	StressRise VoiceParamName = "sr"
+
+	// Gain of voicing in dB.
+	GainOfVoicing VoiceParamName = "gv"
+
+	// Gain of aspiration in dB.
+	GainOfAspiration VoiceParamName = "gh"
+
+	// Gain of frication in dB.
+	GainOfFrication VoiceParamName = "gf"
+
+	// Gain of nasalization in dB.
+	GainOfNasalization VoiceParamName = "gn"
+)
+
+// VoiceParam is a single voice parameter set with [DefineVoice].
+type VoiceParam struct {
+	Name  VoiceParamName
+	Value int
+}
+
+// DefineVoice changes parameters of the current voice, for example
+// [:dv ap 120 pr 100].
+type DefineVoice []VoiceParam
+
+func (d DefineVoice) String() string {
+	var b strings.Builder
+	b.WriteString("[:dv")
+	for _, param := range d {
+		fmt.Fprintf(&b, " %s %d", param.Name, param.Value)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// PhonemeInput enables or disables the interpretation of [Phonemes] blocks,
+// [:phoneme arpabet speak on] and [:phoneme off].
+type PhonemeInput struct {
+	On bool
+}
+
+func (p PhonemeInput) String() string {
+	if p.On {
+		return "[:phoneme arpabet speak on]"
+	}
+	return "[:phoneme off]"
+}
+
+// Phonemes is a block of arpabet phonemes, for example [hx'ehlow]. It is only
+// interpreted while [PhonemeInput] is enabled.
+type Phonemes string
+
+func (p Phonemes) String() string {
+	return "[" + string(p) + "]"
+}
+
+// Silence inserts a pause of the given duration in milliseconds, for example
+// [_<500>]. Like [Phonemes], it is only interpreted while [PhonemeInput] is
+// enabled.
+type Silence struct {
+	Duration int
+}
+
+func (s Silence) String() string {
+	return fmt.Sprintf("[_<%d>]", s.Duration)
+}
+
+// Tone plays a sine tone with the given frequency in Hz for the given duration
+// in milliseconds, for example [:tone 440 1000].
+type Tone struct {
+	Frequency int
+	Duration  int
+}
+
+func (t Tone) String() string {
+	return fmt.Sprintf("[:tone %d %d]", t.Frequency, t.Duration)
+}
+
+// Dial plays the DTMF tones of a telephone number, for example
+// [:dial 5551234]. Digits may contain 0-9, *, # and A-D.
+type Dial struct {
+	Digits string
+}
+
+func (d Dial) String() string {
+	return fmt.Sprintf("[:dial %s]", d.Digits)
+}
+
+// Index places an index mark that is reported once the audio output reaches
+// it, for example [:index mark 17]. See [dectalkdapi.IndexMarkEvent].
+type Index struct {
+	Value uint32
+}
+
+func (i Index) String() string {
+	return fmt.Sprintf("[:index mark %d]", i.Value)
+}
+
+// PunctuationMode selects how punctuation is spoken.
+type PunctuationMode string
+
+const (
+	// Punctuation is not spoken.
+	PunctuationNone PunctuationMode = "none"
+
+	// Punctuation is spoken where it matters for understanding.
+	PunctuationSome PunctuationMode = "some"
+
+	// All punctuation is spoken.
+	PunctuationAll PunctuationMode = "all"
+
+	// Punctuation is passed on without affecting intonation.
+	PunctuationPass PunctuationMode = "pass"
+)
+
+// Punctuation sets the punctuation mode, for example [:punct all].
+type Punctuation struct {
+	Mode PunctuationMode
+}
+
+func (p Punctuation) String() string {
+	return fmt.Sprintf("[:punct %s]", p.Mode)
+}
+
+// TextMode is a text processing mode toggled with [Mode].
+type TextMode string
+
+const (
+	// Speak text as in a citation.
+	ModeCitation TextMode = "citation"
+
+	// Read e-mail headers.
+	ModeEmail TextMode = "email"
+
+	// Use European number and date formats.
+	ModeEurope TextMode = "europe"
+
+	// Use the homophone dictionary.
+	ModeHomophone TextMode = "homophone"
+
+	// Speak words in Latin mode.
+	ModeLatin TextMode = "latin"
+
+	// Read mathematical expressions.
+	ModeMath TextMode = "math"
+
+	// Treat words as names.
+	ModeName TextMode = "name"
+
+	// Spell out every word.
+	ModeSpell TextMode = "spell"
+
+	// Read text as a table.
+	ModeTable TextMode = "table"
+)
+
+// Mode enables or disables a text processing mode, for example
+// [:mode spell on].
+type Mode struct {
+	Mode TextMode
+	On   bool
+}
+
+func (m Mode) String() string {
+	if m.On {
+		return fmt.Sprintf("[:mode %s on]", m.Mode)
+	}
+	return fmt.Sprintf("[:mode %s off]", m.Mode)
+}
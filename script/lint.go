@@ -0,0 +1,352 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package script
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi"
+)
+
+const (
+	// MinRate is the lowest speaking rate accepted by the engine, in words
+	// per minute.
+	MinRate = 75
+
+	// MaxRate is the highest speaking rate accepted by the engine, in words
+	// per minute.
+	MaxRate = 600
+)
+
+// Diagnostic is a problem found by [Parse] or [Lint].
+type Diagnostic struct {
+	Pos     Position
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Message
+}
+
+// LintError is returned by [Check] for a text with problems.
+type LintError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *LintError) Error() string {
+	lines := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Lint parses a text meant for [dectalkdapi.TTS.Speak] like [Parse] and
+// additionally reports unknown commands, malformed arguments and values out of
+// the range accepted by the engine.
+func Lint(text string) []Diagnostic {
+	tokens, diags := Parse(text)
+	for _, token := range tokens {
+		for _, c := range token.Commands {
+			for _, p := range checkCommand(c) {
+				diags = append(diags, Diagnostic{
+					Pos:     position(text, p.offset),
+					Message: p.message,
+				})
+			}
+		}
+	}
+	return diags
+}
+
+// Check lints a text like [Lint] and returns a [*LintError] holding all
+// problems, or nil if there are none. It is meant to be used in tests of
+// hand-written texts:
+//
+//	if err := script.Check(text); err != nil {
+//		t.Error(err)
+//	}
+func Check(text string) error {
+	if diags := Lint(text); len(diags) > 0 {
+		return &LintError{Diagnostics: diags}
+	}
+	return nil
+}
+
+// problem is a problem with a command, positioned by byte offset.
+type problem struct {
+	offset  int
+	message string
+}
+
+// commandSpec describes a known inline command.
+type commandSpec struct {
+	aliases []string
+	check   func(c Command, problems *[]problem)
+}
+
+var commands = map[string]commandSpec{
+	"name":    {aliases: []string{"n"}, check: checkName},
+	"rate":    {aliases: []string{"ra"}, check: checkRate},
+	"volume":  {aliases: []string{"vo", "vol"}, check: checkVolume},
+	"dv":      {check: checkDefineVoice},
+	"phoneme": {aliases: []string{"pho", "phon", "phonemes"}, check: checkPhoneme},
+	"tone":    {aliases: []string{"t", "to"}, check: checkTone},
+	"dial":    {check: checkDial},
+	"index":   {aliases: []string{"i", "in"}, check: checkIndex},
+	"punct":   {aliases: []string{"pu", "punctuation"}, check: checkPunct},
+	"mode":    {aliases: []string{"mo"}, check: checkMode},
+	"log":     {},
+	"sync":    {aliases: []string{"sy"}, check: checkNoArgs},
+	"comma":   {aliases: []string{"cp"}, check: checkPause},
+	"period":  {aliases: []string{"pp"}, check: checkPause},
+	"say":     {aliases: []string{"sa"}, check: checkSay},
+	"error":   {},
+	"skip":    {},
+}
+
+// canonicalNames maps every name and alias to the full command name.
+var canonicalNames = func() map[string]string {
+	names := map[string]string{}
+	for name, spec := range commands {
+		names[name] = name
+		for _, alias := range spec.aliases {
+			names[alias] = name
+		}
+	}
+	return names
+}()
+
+// speakers lists the built-in speakers accepted by [:name].
+var speakers = []dectalkdapi.Speaker{
+	dectalkdapi.Paul,
+	dectalkdapi.Betty,
+	dectalkdapi.Harry,
+	dectalkdapi.Frank,
+	dectalkdapi.Dennis,
+	dectalkdapi.Kit,
+	dectalkdapi.Ursula,
+	dectalkdapi.Rita,
+	dectalkdapi.Wendy,
+}
+
+// LookupSpeaker returns the speaker for a name accepted by [:name], either the
+// full name such as "paul" or its first letter.
+func LookupSpeaker(name string) (dectalkdapi.Speaker, bool) {
+	name = strings.ToLower(name)
+	for _, s := range speakers {
+		full := s.String()
+		if name == full || name == full[:1] {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// resolve fills in the canonical name of a command, expanding the short
+// speaker form such as [:np].
+func resolve(c *Command) {
+	if name, ok := canonicalNames[c.Name]; ok {
+		c.Canonical = name
+		return
+	}
+	if len(c.Name) == 2 && c.Name[0] == 'n' {
+		if _, ok := LookupSpeaker(c.Name[1:]); ok {
+			c.Canonical = "name"
+			c.Args = append([]Arg{{Offset: c.Offset + 2, Value: c.Name[1:]}}, c.Args...)
+		}
+	}
+}
+
+func checkCommand(c Command) []problem {
+	var problems []problem
+	if c.Name == "" {
+		return nil
+	}
+	if c.Canonical == "" {
+		return append(problems, problem{c.Offset, fmt.Sprintf("unknown command %q", c.Name)})
+	}
+	if check := commands[c.Canonical].check; check != nil {
+		check(c, &problems)
+	}
+	return problems
+}
+
+// wantArgs reports a problem unless the command has n arguments.
+func wantArgs(c Command, n int, problems *[]problem) bool {
+	if len(c.Args) == n {
+		return true
+	}
+	offset := c.Offset
+	if len(c.Args) > n {
+		offset = c.Args[n].Offset
+	}
+	*problems = append(*problems, problem{offset,
+		fmt.Sprintf("%s takes %d argument(s), got %d", c.Canonical, n, len(c.Args))})
+	return false
+}
+
+// intArg parses an integer argument within [lo, hi], reporting a problem if it
+// is malformed or out of range.
+func intArg(c Command, arg Arg, what string, lo, hi int64, problems *[]problem) (int64, bool) {
+	v, err := strconv.ParseInt(arg.Value, 10, 64)
+	if err != nil {
+		*problems = append(*problems, problem{arg.Offset,
+			fmt.Sprintf("invalid %s %q in %s, want an integer", what, arg.Value, c.Canonical)})
+		return 0, false
+	}
+	if v < lo || v > hi {
+		*problems = append(*problems, problem{arg.Offset,
+			fmt.Sprintf("%s %d in %s out of range %d-%d", what, v, c.Canonical, lo, hi)})
+		return v, false
+	}
+	return v, true
+}
+
+// oneOf reports a problem unless the argument is one of the given words.
+func oneOf(c Command, arg Arg, what string, words []string, problems *[]problem) bool {
+	value := strings.ToLower(arg.Value)
+	for _, w := range words {
+		if value == w {
+			return true
+		}
+	}
+	*problems = append(*problems, problem{arg.Offset,
+		fmt.Sprintf("unknown %s %q in %s, want one of %s", what, arg.Value, c.Canonical, strings.Join(words, ", "))})
+	return false
+}
+
+func checkNoArgs(c Command, problems *[]problem) {
+	wantArgs(c, 0, problems)
+}
+
+func checkName(c Command, problems *[]problem) {
+	if !wantArgs(c, 1, problems) {
+		return
+	}
+	if _, ok := LookupSpeaker(c.Args[0].Value); !ok {
+		*problems = append(*problems, problem{c.Args[0].Offset,
+			fmt.Sprintf("unknown speaker %q", c.Args[0].Value)})
+	}
+}
+
+func checkRate(c Command, problems *[]problem) {
+	if wantArgs(c, 1, problems) {
+		intArg(c, c.Args[0], "rate", MinRate, MaxRate, problems)
+	}
+}
+
+var volumeOps = []string{
+	string(VolumeSet), string(VolumeUp), string(VolumeDown),
+	"lset", "lup", "ldown", "rset", "rup", "rdown",
+}
+
+func checkVolume(c Command, problems *[]problem) {
+	if !wantArgs(c, 2, problems) {
+		return
+	}
+	oneOf(c, c.Args[0], "operation", volumeOps, problems)
+	intArg(c, c.Args[1], "volume", 0, 100, problems)
+}
+
+var voiceParams = []string{
+	string(Sex), string(HeadSize), string(AveragePitch), string(PitchRange),
+	string(Breathiness), string(LaxBreathiness), string(Smoothness),
+	string(Richness), string(Laryngealization), string(Assertiveness),
+	string(Quickness), string(BaselineFall), string(HatRise),
+	string(StressRise), string(GainOfVoicing), string(GainOfAspiration),
+	string(GainOfFrication), string(GainOfNasalization),
+	"f4", "b4", "f5", "b5", "nf", "g1", "g2", "g3", "g4", "g5",
+}
+
+func checkDefineVoice(c Command, problems *[]problem) {
+	for i := 0; i < len(c.Args); i += 2 {
+		oneOf(c, c.Args[i], "voice parameter", voiceParams, problems)
+		if i+1 == len(c.Args) {
+			*problems = append(*problems, problem{c.Args[i].Offset,
+				fmt.Sprintf("missing value for voice parameter %q", c.Args[i].Value)})
+			return
+		}
+		intArg(c, c.Args[i+1], "value", math.MinInt32, math.MaxInt32, problems)
+	}
+}
+
+func checkPhoneme(c Command, problems *[]problem) {
+	if len(c.Args) == 0 {
+		wantArgs(c, 1, problems)
+	}
+	for _, arg := range c.Args {
+		oneOf(c, arg, "argument", []string{"arpabet", "speak", "on", "off"}, problems)
+	}
+}
+
+func checkTone(c Command, problems *[]problem) {
+	if !wantArgs(c, 2, problems) {
+		return
+	}
+	intArg(c, c.Args[0], "frequency", 1, 11025/2, problems)
+	intArg(c, c.Args[1], "duration", 1, math.MaxInt32, problems)
+}
+
+func checkDial(c Command, problems *[]problem) {
+	if !wantArgs(c, 1, problems) {
+		return
+	}
+	arg := c.Args[0]
+	if i := strings.IndexFunc(arg.Value, func(r rune) bool {
+		return !strings.ContainsRune("0123456789*#ABCDabcd", r)
+	}); i >= 0 {
+		*problems = append(*problems, problem{arg.Offset + i,
+			fmt.Sprintf("invalid digit %q in dial", arg.Value[i:i+1])})
+	}
+}
+
+func checkIndex(c Command, problems *[]problem) {
+	if !wantArgs(c, 2, problems) {
+		return
+	}
+	oneOf(c, c.Args[0], "argument", []string{"mark", "m"}, problems)
+	intArg(c, c.Args[1], "index mark", 0, math.MaxUint32, problems)
+}
+
+var punctModes = []string{
+	string(PunctuationNone), string(PunctuationSome),
+	string(PunctuationAll), string(PunctuationPass),
+	"n", "s", "a", "p",
+}
+
+func checkPunct(c Command, problems *[]problem) {
+	if wantArgs(c, 1, problems) {
+		oneOf(c, c.Args[0], "punctuation mode", punctModes, problems)
+	}
+}
+
+var textModes = []string{
+	string(ModeCitation), string(ModeEmail), string(ModeEurope),
+	string(ModeHomophone), string(ModeLatin), string(ModeMath),
+	string(ModeName), string(ModeSpell), string(ModeTable),
+}
+
+func checkMode(c Command, problems *[]problem) {
+	if !wantArgs(c, 2, problems) {
+		return
+	}
+	oneOf(c, c.Args[0], "mode", textModes, problems)
+	oneOf(c, c.Args[1], "argument", []string{"on", "off"}, problems)
+}
+
+func checkPause(c Command, problems *[]problem) {
+	if wantArgs(c, 1, problems) {
+		intArg(c, c.Args[0], "pause", 0, math.MaxInt32, problems)
+	}
+}
+
+func checkSay(c Command, problems *[]problem) {
+	if wantArgs(c, 1, problems) {
+		oneOf(c, c.Args[0], "unit", []string{"clause", "word", "letter", "line"}, problems)
+	}
+}
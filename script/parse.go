@@ -0,0 +1,238 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package script
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind is the kind of a [Token].
+type TokenKind int
+
+const (
+	// TextToken is plain text outside of brackets.
+	TextToken TokenKind = iota
+
+	// CommandToken is a bracket holding one or more inline commands, such as
+	// [:rate 120] or [:np :ra 200].
+	CommandToken
+
+	// PhonemeToken is a bracket holding phonemes, such as [hx'ehlow].
+	PhonemeToken
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TextToken:
+		return "text"
+	case CommandToken:
+		return "command"
+	case PhonemeToken:
+		return "phonemes"
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// Token is a piece of text passed to [dectalkdapi.TTS.Speak]. Rendering all
+// tokens of a text with String in order gives back the original text, so a
+// token can also be used as a [Node].
+type Token struct {
+	Kind TokenKind
+
+	// Offset is the byte offset of the token in the parsed text.
+	Offset int
+
+	// Raw is the token exactly as it appears in the parsed text, including
+	// brackets.
+	Raw string
+
+	// Commands holds the inline commands of a CommandToken.
+	Commands []Command
+}
+
+func (t Token) String() string {
+	return t.Raw
+}
+
+// Command is a single inline command inside a bracket.
+type Command struct {
+	// Offset is the byte offset of the colon starting the command in the
+	// parsed text.
+	Offset int
+
+	// Name is the command name as written, in lower case and without the
+	// colon, for example "ra" in [:ra 200].
+	Name string
+
+	// Canonical is the full name of the command, for example "rate" for
+	// [:ra 200], or empty if the command is unknown.
+	Canonical string
+
+	// Args are the whitespace separated arguments of the command. For the
+	// short speaker form [:np], the speaker letter is the only argument.
+	Args []Arg
+}
+
+// String renders the command without brackets, for example ":rate 120".
+func (c Command) String() string {
+	var b strings.Builder
+	b.WriteString(":" + c.Name)
+	args := c.Args
+	if _, ok := canonicalNames[c.Name]; !ok && c.Canonical == "name" {
+		// The speaker letter of the short form is part of the name.
+		args = args[1:]
+	}
+	for _, arg := range args {
+		b.WriteString(" " + arg.Value)
+	}
+	return b.String()
+}
+
+// Arg is an argument of an inline command.
+type Arg struct {
+	// Offset is the byte offset of the argument in the parsed text.
+	Offset int
+
+	// Value is the argument as written.
+	Value string
+}
+
+// Position is a location in a parsed text.
+type Position struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+
+	// Line is the line number, starting at 1.
+	Line int
+
+	// Column is the column in characters, starting at 1.
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// position returns the position of the given byte offset in text.
+func position(text string, offset int) Position {
+	before := text[:offset]
+	line := strings.Count(before, "\n") + 1
+	if i := strings.LastIndexByte(before, '\n'); i >= 0 {
+		before = before[i+1:]
+	}
+	return Position{
+		Offset: offset,
+		Line:   line,
+		Column: utf8.RuneCountInString(before) + 1,
+	}
+}
+
+// Parse splits a text meant for [dectalkdapi.TTS.Speak] into plain text,
+// inline commands and phoneme blocks. It never fails, but reports syntax
+// problems such as unterminated brackets. Use [Lint] to also check the
+// commands themselves.
+func Parse(text string) ([]Token, []Diagnostic) {
+	var (
+		tokens []Token
+		diags  []Diagnostic
+	)
+	report := func(offset int, format string, args ...interface{}) {
+		diags = append(diags, Diagnostic{
+			Pos:     position(text, offset),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	for offset := 0; offset < len(text); {
+		start := strings.IndexByte(text[offset:], '[')
+		if start < 0 {
+			tokens = append(tokens, Token{Kind: TextToken, Offset: offset, Raw: text[offset:]})
+			break
+		}
+		start += offset
+		if start > offset {
+			tokens = append(tokens, Token{Kind: TextToken, Offset: offset, Raw: text[offset:start]})
+		}
+
+		end := strings.IndexByte(text[start+1:], ']')
+		if end < 0 {
+			report(start, "unterminated inline command")
+			end = len(text)
+		} else {
+			end += start + 1
+		}
+		if nested := strings.IndexByte(text[start+1:end], '['); nested >= 0 {
+			report(start+1+nested, "unexpected \"[\" inside inline command")
+		}
+
+		raw := text[start:min(end+1, len(text))]
+		token := Token{Kind: PhonemeToken, Offset: start, Raw: raw}
+		content := text[start+1 : end]
+		if strings.HasPrefix(strings.TrimLeftFunc(content, unicode.IsSpace), ":") {
+			token.Kind = CommandToken
+			token.Commands = parseCommands(content, start+1, report)
+		}
+		tokens = append(tokens, token)
+		offset = start + len(raw)
+	}
+	return tokens, diags
+}
+
+// parseCommands splits the content of a bracket into commands, each starting
+// with a colon.
+func parseCommands(content string, offset int, report func(int, string, ...interface{})) []Command {
+	var commands []Command
+	for _, field := range fields(content, offset) {
+		if strings.HasPrefix(field.Value, ":") {
+			name := strings.ToLower(field.Value[1:])
+			if name == "" {
+				report(field.Offset, "missing command name")
+			}
+			commands = append(commands, Command{
+				Offset: field.Offset,
+				Name:   name,
+			})
+			continue
+		}
+		c := &commands[len(commands)-1]
+		c.Args = append(c.Args, field)
+	}
+	for i := range commands {
+		resolve(&commands[i])
+	}
+	return commands
+}
+
+// fields splits s around whitespace like strings.Fields, keeping the offset of
+// each field.
+func fields(s string, offset int) []Arg {
+	var args []Arg
+	start := -1
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				args = append(args, Arg{Offset: offset + start, Value: s[start:i]})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		args = append(args, Arg{Offset: offset + start, Value: s[start:]})
+	}
+	return args
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
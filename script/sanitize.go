@@ -0,0 +1,149 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package script
+
+import (
+	"strconv"
+	"strings"
+)
+
+// literalReplacer replaces brackets, which are the only way to start inline
+// commands and phoneme blocks.
+var literalReplacer = strings.NewReplacer("[", "(", "]", ")")
+
+// Literal neutralizes all inline commands and phoneme blocks in untrusted text
+// by replacing square brackets with parentheses, so the engine speaks the text
+// as it is instead of interpreting it.
+func Literal(text string) string {
+	return literalReplacer.Replace(text)
+}
+
+// Rule decides whether an inline command is allowed by a [Policy]. It is only
+// called for commands that pass [Lint].
+type Rule func(c Command) bool
+
+// AllowAll allows a command with any valid arguments.
+func AllowAll() Rule {
+	return func(Command) bool {
+		return true
+	}
+}
+
+// AllowIntRange allows a command if its argument at index arg is an integer
+// within [lo, hi], for example AllowIntRange(0, 75, 300) for [:rate].
+func AllowIntRange(arg int, lo, hi int64) Rule {
+	return func(c Command) bool {
+		if arg >= len(c.Args) {
+			return false
+		}
+		v, err := strconv.ParseInt(c.Args[arg].Value, 10, 64)
+		return err == nil && v >= lo && v <= hi
+	}
+}
+
+// AllowWords allows a command if its argument at index arg is one of the given
+// words, for example AllowWords(0, "set") for [:volume].
+func AllowWords(arg int, words ...string) Rule {
+	return func(c Command) bool {
+		if arg >= len(c.Args) {
+			return false
+		}
+		value := strings.ToLower(c.Args[arg].Value)
+		for _, w := range words {
+			if value == w {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllowEvery combines rules so that a command is only allowed if all of them
+// allow it.
+func AllowEvery(rules ...Rule) Rule {
+	return func(c Command) bool {
+		for _, rule := range rules {
+			if !rule(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Policy is an allowlist of inline commands for untrusted text. Commands that
+// are not allowed, unknown or malformed are stripped from the text while plain
+// text is kept.
+type Policy struct {
+	// Commands maps full command names, as found in [Command.Canonical], to
+	// the rule deciding over them. Commands without a rule are stripped.
+	Commands map[string]Rule
+
+	// Phonemes allows phoneme blocks such as [hx'ehlow]. They are only
+	// interpreted by the engine while [:phoneme arpabet speak on] is in
+	// effect.
+	Phonemes bool
+}
+
+// DefaultPolicy returns a policy for text from the public. It allows switching
+// speakers, speaking rates from 75 to 300 words per minute, setting the volume,
+// punctuation modes and pauses, and strips everything else, such as [:log],
+// [:phoneme], [:dv] and [:tone].
+func DefaultPolicy() Policy {
+	return Policy{
+		Commands: map[string]Rule{
+			"name":   AllowAll(),
+			"rate":   AllowIntRange(0, MinRate, 300),
+			"volume": AllowAll(),
+			"punct":  AllowAll(),
+			"comma":  AllowIntRange(0, 0, 1000),
+			"period": AllowIntRange(0, 0, 2000),
+		},
+	}
+}
+
+// Allowed reports whether the policy allows the command.
+func (p Policy) Allowed(c Command) bool {
+	rule, ok := p.Commands[c.Canonical]
+	if !ok || len(checkCommand(c)) > 0 {
+		return false
+	}
+	for _, arg := range c.Args {
+		if strings.ContainsRune(arg.Value, '[') {
+			return false
+		}
+	}
+	return rule(c)
+}
+
+// Sanitize applies the policy to text. Brackets that keep at least one allowed
+// command are rewritten to contain only the allowed commands, everything else
+// between brackets is removed.
+func (p Policy) Sanitize(text string) string {
+	tokens, _ := Parse(text)
+
+	var b strings.Builder
+	for _, token := range tokens {
+		switch token.Kind {
+		case TextToken:
+			b.WriteString(token.Raw)
+		case PhonemeToken:
+			if p.Phonemes && strings.HasSuffix(token.Raw, "]") &&
+				!strings.ContainsRune(token.Raw[1:], '[') {
+				b.WriteString(token.Raw)
+			}
+		case CommandToken:
+			var allowed []string
+			for _, c := range token.Commands {
+				if p.Allowed(c) {
+					allowed = append(allowed, c.String())
+				}
+			}
+			if len(allowed) > 0 {
+				b.WriteString("[" + strings.Join(allowed, " ") + "]")
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+// Package script builds text for [dectalkdapi.TTS.Speak] from typed inline
+// commands instead of hand-written bracket strings.
+//
+// A script is a list of nodes, each of which renders to plain text or a single
+// inline command:
+//
+//	s := script.Script{
+//		script.Name{Speaker: dectalkdapi.Paul},
+//		script.Text("I am Paul. "),
+//		script.Volume{Op: script.VolumeSet, Value: 50},
+//		script.Rate{WordsPerMinute: 120},
+//		script.Text("I am speaking at 120 words per minute."),
+//	}
+//	tts.Speak(s.String(), dectalkdapi.Normal)
+//
+// The same can be written with a [Builder]:
+//
+//	text := new(script.Builder).
+//		Name(dectalkdapi.Paul).
+//		Text("I am Paul. ").
+//		Volume(script.VolumeSet, 50).
+//		Rate(120).
+//		Text("I am speaking at 120 words per minute.").
+//		String()
+package script
+
+import (
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi"
+)
+
+// Node is an element of a script, either plain text or an inline command.
+type Node interface {
+	// String renders the node exactly as accepted by
+	// [dectalkdapi.TTS.Speak].
+	String() string
+}
+
+// Script is a sequence of nodes.
+type Script []Node
+
+// String renders the whole script as accepted by [dectalkdapi.TTS.Speak].
+func (s Script) String() string {
+	var b strings.Builder
+	for _, node := range s {
+		b.WriteString(node.String())
+	}
+	return b.String()
+}
+
+// Builder builds a [Script] node by node. The zero value is ready to use.
+type Builder struct {
+	script Script
+}
+
+// Append adds arbitrary nodes to the script.
+func (b *Builder) Append(nodes ...Node) *Builder {
+	b.script = append(b.script, nodes...)
+	return b
+}
+
+// Text adds plain text, see [Text].
+func (b *Builder) Text(text string) *Builder {
+	return b.Append(Text(text))
+}
+
+// Name switches to a built-in speaker, see [Name].
+func (b *Builder) Name(speaker dectalkdapi.Speaker) *Builder {
+	return b.Append(Name{Speaker: speaker})
+}
+
+// Rate sets the speaking rate, see [Rate].
+func (b *Builder) Rate(wordsPerMinute int) *Builder {
+	return b.Append(Rate{WordsPerMinute: wordsPerMinute})
+}
+
+// Volume changes the volume, see [Volume].
+func (b *Builder) Volume(op VolumeOp, value int) *Builder {
+	return b.Append(Volume{Op: op, Value: value})
+}
+
+// DefineVoice changes parameters of the current voice, see [DefineVoice].
+func (b *Builder) DefineVoice(params ...VoiceParam) *Builder {
+	return b.Append(DefineVoice(params))
+}
+
+// PhonemeInput enables or disables phoneme input, see [PhonemeInput].
+func (b *Builder) PhonemeInput(on bool) *Builder {
+	return b.Append(PhonemeInput{On: on})
+}
+
+// Phonemes adds a block of arpabet phonemes, see [Phonemes].
+func (b *Builder) Phonemes(phonemes string) *Builder {
+	return b.Append(Phonemes(phonemes))
+}
+
+// Silence adds a pause, see [Silence].
+func (b *Builder) Silence(milliseconds int) *Builder {
+	return b.Append(Silence{Duration: milliseconds})
+}
+
+// Tone plays a tone, see [Tone].
+func (b *Builder) Tone(frequency, milliseconds int) *Builder {
+	return b.Append(Tone{Frequency: frequency, Duration: milliseconds})
+}
+
+// Dial plays DTMF tones, see [Dial].
+func (b *Builder) Dial(digits string) *Builder {
+	return b.Append(Dial{Digits: digits})
+}
+
+// Index adds an index mark, see [Index].
+func (b *Builder) Index(value uint32) *Builder {
+	return b.Append(Index{Value: value})
+}
+
+// Punctuation sets the punctuation mode, see [Punctuation].
+func (b *Builder) Punctuation(mode PunctuationMode) *Builder {
+	return b.Append(Punctuation{Mode: mode})
+}
+
+// Mode enables or disables a text processing mode, see [Mode].
+func (b *Builder) Mode(mode TextMode, on bool) *Builder {
+	return b.Append(Mode{Mode: mode, On: on})
+}
+
+// Script returns the nodes added so far.
+func (b *Builder) Script() Script {
+	return b.script
+}
+
+// String renders the nodes added so far as accepted by
+// [dectalkdapi.TTS.Speak].
+func (b *Builder) String() string {
+	return b.script.String()
+}
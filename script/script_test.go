@@ -0,0 +1,166 @@
+package script_test
+
+import (
+	"errors"
+	"github.com/icedream/go-dectalkdapi"
+	"github.com/icedream/go-dectalkdapi/script"
+	"strings"
+	"testing"
+)
+
+func TestNodes(t *testing.T) {
+	tests := []struct {
+		node script.Node
+		want string
+	}{
+		{script.Text("Hello."), "Hello."},
+		{script.Name{Speaker: dectalkdapi.Paul}, "[:name paul]"},
+		{script.Name{Speaker: dectalkdapi.Wendy}, "[:name wendy]"},
+		{script.Rate{WordsPerMinute: 120}, "[:rate 120]"},
+		{script.Volume{Op: script.VolumeSet, Value: 50}, "[:volume set 50]"},
+		{script.Volume{Op: script.VolumeDown, Value: 10}, "[:volume down 10]"},
+		{script.DefineVoice{{Name: script.AveragePitch, Value: 120}, {Name: script.PitchRange, Value: 100}}, "[:dv ap 120 pr 100]"},
+		{script.PhonemeInput{On: true}, "[:phoneme arpabet speak on]"},
+		{script.PhonemeInput{}, "[:phoneme off]"},
+		{script.Phonemes("hx'ehlow"), "[hx'ehlow]"},
+		{script.Silence{Duration: 500}, "[_<500>]"},
+		{script.Tone{Frequency: 440, Duration: 1000}, "[:tone 440 1000]"},
+		{script.Dial{Digits: "5551234"}, "[:dial 5551234]"},
+		{script.Index{Value: 17}, "[:index mark 17]"},
+		{script.Punctuation{Mode: script.PunctuationAll}, "[:punct all]"},
+		{script.Mode{Mode: script.ModeSpell, On: true}, "[:mode spell on]"},
+		{script.Mode{Mode: script.ModeMath}, "[:mode math off]"},
+	}
+	for _, test := range tests {
+		if got := test.node.String(); got != test.want {
+			t.Errorf("%#v.String() = %q, want %q", test.node, got, test.want)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	got := new(script.Builder).
+		Name(dectalkdapi.Paul).
+		Text("I am Paul. ").
+		Volume(script.VolumeSet, 50).
+		Rate(120).
+		Index(1).
+		Text("I am speaking at 120 words per minute.").
+		String()
+	want := "[:name paul]I am Paul. [:volume set 50][:rate 120][:index mark 1]I am speaking at 120 words per minute."
+	if got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	text := "[:np :ra 200]Hello [hx'ehlow]."
+	tokens, diags := script.Parse(text)
+	if len(diags) > 0 {
+		t.Fatalf("Parse() reported %v", diags)
+	}
+	if len(tokens) != 4 {
+		t.Fatalf("Expected 4 tokens, got %d: %+v", len(tokens), tokens)
+	}
+
+	kinds := []script.TokenKind{script.CommandToken, script.TextToken, script.PhonemeToken, script.TextToken}
+	var rendered string
+	for i, token := range tokens {
+		if token.Kind != kinds[i] {
+			t.Errorf("Token %d is %v, want %v", i, token.Kind, kinds[i])
+		}
+		rendered += token.String()
+	}
+	if rendered != text {
+		t.Errorf("Tokens render to %q, want %q", rendered, text)
+	}
+
+	commands := tokens[0].Commands
+	if len(commands) != 2 {
+		t.Fatalf("Expected 2 commands, got %+v", commands)
+	}
+	if c := commands[0]; c.Canonical != "name" || len(c.Args) != 1 || c.Args[0].Value != "p" {
+		t.Errorf("Unexpected first command %+v", c)
+	}
+	if c := commands[1]; c.Canonical != "rate" || c.Offset != 5 || len(c.Args) != 1 || c.Args[0].Offset != 9 {
+		t.Errorf("Unexpected second command %+v", c)
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"[:name paul] I am Paul. [:nb] I am Betty. [:volume set 50][:rate 120][:index mark 1]", nil},
+		{"[:tone 440 1000][:dial 555*1234#][:punct all][:mode spell on][:phoneme arpabet speak on]", nil},
+		{"[:rate 1200]", []string{"1:8: rate 1200 in rate out of range 75-600"}},
+		{"[:rate fast]", []string{`1:8: invalid rate "fast" in rate, want an integer`}},
+		{"Hello\n[:raet 200]", []string{`2:2: unknown command "raet"`}},
+		{"[:name bob]", []string{`1:8: unknown speaker "bob"`}},
+		{"[:volume set]", []string{"1:2: volume takes 2 argument(s), got 1"}},
+		{"[:dv ap 120 pr]", []string{`1:13: missing value for voice parameter "pr"`}},
+		{"[:dial 555-1234]", []string{`1:11: invalid digit "-" in dial`}},
+		{"Hello [:rate 200", []string{"1:7: unterminated inline command"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, d := range script.Lint(test.text) {
+			got = append(got, d.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("Lint(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := script.Check("[:rate 200] Hello."); err != nil {
+		t.Errorf("Check() failed: %v", err)
+	}
+	err := script.Check("[:rate 20] Hello.")
+	var lintErr *script.LintError
+	if !errors.As(err, &lintErr) || len(lintErr.Diagnostics) != 1 {
+		t.Errorf("Expected a LintError with 1 diagnostic, got %v", err)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	got := script.Literal("Hi [:log text on] and [:rate 600]")
+	want := "Hi (:log text on) and (:rate 600)"
+	if got != want {
+		t.Errorf("Literal() = %q, want %q", got, want)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := script.DefaultPolicy()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"[:np :ra 200]Hello.", "[:np :ra 200]Hello."},
+		{"[:name  betty] Hi", "[:name betty] Hi"},
+		{"[:rate 600]Fast", "Fast"},
+		{"[:np :log text on]Hi", "[:np]Hi"},
+		{"[:phoneme arpabet speak on][hx'ehlow]", ""},
+		{"[:raet 200]Typo", "Typo"},
+		{"Open [:name paul", "Open [:name paul]"},
+		{"[:name [:log text on]", ""},
+	}
+	for _, test := range tests {
+		if got := policy.Sanitize(test.text); got != test.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", test.text, got, test.want)
+		}
+		if err := script.Check(policy.Sanitize(test.text)); err != nil {
+			t.Errorf("Sanitize(%q) is not valid: %v", test.text, err)
+		}
+	}
+
+	policy.Phonemes = true
+	policy.Commands["phoneme"] = script.AllowAll()
+	text := "[:phoneme arpabet speak on][hx'ehlow]"
+	if got := policy.Sanitize(text); got != text {
+		t.Errorf("Sanitize(%q) = %q, want it unchanged", text, got)
+	}
+}
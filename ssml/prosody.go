@@ -0,0 +1,441 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package ssml
+
+import (
+	"encoding/xml"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/icedream/go-dectalkdapi"
+	"github.com/icedream/go-dectalkdapi/script"
+)
+
+// voiceState holds the settings text is spoken with.
+type voiceState struct {
+	speaker dectalkdapi.Speaker
+
+	// pitch is the average pitch in Hz.
+	pitch int
+
+	// rate is the speaking rate in words per minute.
+	rate int
+
+	// volume ranges from 0 to 100.
+	volume int
+}
+
+const (
+	defaultRate   = 180
+	defaultVolume = 100
+
+	minPitch = 50
+	maxPitch = 350
+)
+
+// defaultVoice is the voice an instance starts up with.
+var defaultVoice = voiceState{
+	speaker: dectalkdapi.Paul,
+	pitch:   defaultPitch(dectalkdapi.Paul),
+	rate:    defaultRate,
+	volume:  defaultVolume,
+}
+
+// unknownVoice differs from every voice, so that all settings are written.
+var unknownVoice = voiceState{speaker: ^dectalkdapi.Speaker(0), pitch: -1, rate: -1, volume: -1}
+
+// defaultPitch returns the average pitch of a built-in speaker in Hz.
+func defaultPitch(s dectalkdapi.Speaker) int {
+	switch s {
+	case dectalkdapi.Betty:
+		return 208
+	case dectalkdapi.Harry:
+		return 89
+	case dectalkdapi.Frank:
+		return 155
+	case dectalkdapi.Dennis:
+		return 110
+	case dectalkdapi.Kit:
+		return 306
+	case dectalkdapi.Ursula:
+		return 240
+	case dectalkdapi.Rita:
+		return 106
+	case dectalkdapi.Wendy:
+		return 200
+	default:
+		return 122
+	}
+}
+
+// Speakers by gender, in the order selected by the variant attribute of
+// <voice>.
+var (
+	maleSpeakers   = []dectalkdapi.Speaker{dectalkdapi.Paul, dectalkdapi.Harry, dectalkdapi.Frank, dectalkdapi.Dennis}
+	femaleSpeakers = []dectalkdapi.Speaker{dectalkdapi.Betty, dectalkdapi.Ursula, dectalkdapi.Rita, dectalkdapi.Wendy}
+)
+
+// voice selects a speaker by name, such as "paul" or "p", or else by gender,
+// age and variant. Children are spoken by Kit.
+func (t *translator) voice(start xml.StartElement) voiceState {
+	v := t.state
+	speaker, ok := t.speaker(start)
+	if !ok {
+		return v
+	}
+	v.speaker = speaker
+	v.pitch = defaultPitch(speaker)
+	return v
+}
+
+func (t *translator) speaker(start xml.StartElement) (dectalkdapi.Speaker, bool) {
+	if names, ok := attr(start, "name"); ok {
+		for _, name := range strings.Fields(names) {
+			if s, ok := script.LookupSpeaker(name); ok {
+				return s, true
+			}
+		}
+		t.warn("voice", "name", "no speaker named %q", names)
+	}
+
+	if value, ok := attr(start, "age"); ok {
+		age, err := strconv.Atoi(value)
+		if err != nil {
+			t.warn("voice", "age", "invalid age %q", value)
+		} else if age < 13 {
+			return dectalkdapi.Kit, true
+		}
+	}
+
+	gender, _ := attr(start, "gender")
+	var speakers []dectalkdapi.Speaker
+	switch gender {
+	case "male":
+		speakers = maleSpeakers
+	case "female":
+		speakers = femaleSpeakers
+	case "neutral":
+		return dectalkdapi.Kit, true
+	case "":
+	default:
+		t.warn("voice", "gender", "unknown gender %q", gender)
+	}
+
+	variant := 1
+	if value, ok := attr(start, "variant"); ok {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			variant = n
+		} else {
+			t.warn("voice", "variant", "invalid variant %q", value)
+		}
+	}
+	if speakers == nil {
+		if variant == 1 {
+			return 0, false
+		}
+		speakers = append(append([]dectalkdapi.Speaker(nil), maleSpeakers...), femaleSpeakers...)
+	}
+	return speakers[(variant-1)%len(speakers)], true
+}
+
+var (
+	rateLabels = map[string]int{
+		"x-slow":  90,
+		"slow":    130,
+		"medium":  defaultRate,
+		"fast":    250,
+		"x-fast":  350,
+		"default": defaultRate,
+	}
+
+	// pitchLabels holds factors for the default pitch of the speaker.
+	pitchLabels = map[string]float64{
+		"x-low":   0.7,
+		"low":     0.85,
+		"medium":  1,
+		"high":    1.15,
+		"x-high":  1.3,
+		"default": 1,
+	}
+
+	volumeLabels = map[string]int{
+		"silent":  0,
+		"x-soft":  20,
+		"soft":    40,
+		"medium":  60,
+		"loud":    80,
+		"x-loud":  100,
+		"default": defaultVolume,
+	}
+)
+
+// prosody changes the rate, pitch and volume. The contour, range and duration
+// attributes have no equivalent.
+func (t *translator) prosody(start xml.StartElement) voiceState {
+	v := t.state
+	for _, a := range start.Attr {
+		value := strings.TrimSpace(a.Value)
+		switch a.Name.Local {
+		case "rate":
+			if rate, ok := t.rate(value); ok {
+				v.rate = clamp(t, "rate", rate, script.MinRate, script.MaxRate)
+			}
+		case "pitch":
+			if pitch, ok := t.pitch(value); ok {
+				v.pitch = clamp(t, "pitch", pitch, minPitch, maxPitch)
+			}
+		case "volume":
+			if volume, ok := t.volume(value); ok {
+				v.volume = clamp(t, "volume", volume, 0, 100)
+			}
+		default:
+			t.warn("prosody", a.Name.Local, "not supported")
+		}
+	}
+	return v
+}
+
+func clamp(t *translator, attribute string, value, lo, hi int) int {
+	if value < lo || value > hi {
+		t.warn("prosody", attribute, "%d is out of the range from %d to %d", value, lo, hi)
+	}
+	if value < lo {
+		return lo
+	}
+	if value > hi {
+		return hi
+	}
+	return value
+}
+
+// relative splits a value such as "+10%" into its sign, number and unit. The
+// sign is 0 for absolute values.
+func relative(value string) (sign float64, n float64, unit string, ok bool) {
+	if value == "" {
+		return 0, 0, "", false
+	}
+	switch value[0] {
+	case '+':
+		sign, value = 1, value[1:]
+	case '-':
+		sign, value = -1, value[1:]
+	}
+	end := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end < 0 {
+		end = len(value)
+	}
+	n, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+	return sign, n, value[end:], true
+}
+
+// rate accepts labels, percentages of the current rate, relative changes in
+// percent and numbers multiplying the default rate.
+func (t *translator) rate(value string) (int, bool) {
+	if rate, ok := rateLabels[value]; ok {
+		return rate, true
+	}
+	sign, n, unit, ok := relative(value)
+	switch {
+	case ok && unit == "%" && sign == 0:
+		return int(math.Round(float64(t.state.rate) * n / 100)), true
+	case ok && unit == "%":
+		return int(math.Round(float64(t.state.rate) * (100 + sign*n) / 100)), true
+	case ok && unit == "" && sign == 0:
+		return int(math.Round(defaultRate * n)), true
+	}
+	t.warn("prosody", "rate", "invalid rate %q", value)
+	return 0, false
+}
+
+// pitch accepts labels, frequencies in Hz and relative changes in Hz, percent
+// or semitones.
+func (t *translator) pitch(value string) (int, bool) {
+	if factor, ok := pitchLabels[value]; ok {
+		return int(math.Round(float64(defaultPitch(t.state.speaker)) * factor)), true
+	}
+	current := float64(t.state.pitch)
+	sign, n, unit, ok := relative(value)
+	switch {
+	case ok && unit == "Hz" && sign == 0:
+		return int(math.Round(n)), true
+	case ok && unit == "Hz":
+		return int(math.Round(current + sign*n)), true
+	case ok && unit == "%" && sign == 0:
+		return int(math.Round(current * n / 100)), true
+	case ok && unit == "%":
+		return int(math.Round(current * (100 + sign*n) / 100)), true
+	case ok && unit == "st":
+		if sign == 0 {
+			sign = 1
+		}
+		return int(math.Round(current * math.Pow(2, sign*n/12))), true
+	}
+	t.warn("prosody", "pitch", "invalid pitch %q", value)
+	return 0, false
+}
+
+// volume accepts labels, relative changes in dB and numbers from 0 to 100.
+func (t *translator) volume(value string) (int, bool) {
+	if volume, ok := volumeLabels[value]; ok {
+		return volume, true
+	}
+	sign, n, unit, ok := relative(value)
+	switch {
+	case ok && unit == "dB":
+		if sign == 0 {
+			sign = 1
+		}
+		return int(math.Round(float64(t.state.volume) * math.Pow(10, sign*n/20))), true
+	case ok && unit == "" && sign == 0:
+		return int(math.Round(n)), true
+	case ok && unit == "":
+		return int(math.Round(float64(t.state.volume) + sign*n)), true
+	}
+	t.warn("prosody", "volume", "invalid volume %q", value)
+	return 0, false
+}
+
+// breakStrengths holds the pause of each strength in milliseconds.
+var breakStrengths = map[string]int{
+	"none":     0,
+	"x-weak":   50,
+	"weak":     150,
+	"medium":   300,
+	"strong":   600,
+	"x-strong": 1000,
+}
+
+func (t *translator) breakElement(start xml.StartElement) {
+	ms := breakStrengths["medium"]
+	if strength, ok := attr(start, "strength"); ok {
+		if n, ok := breakStrengths[strength]; ok {
+			ms = n
+		} else {
+			t.warn("break", "strength", "unknown strength %q", strength)
+		}
+	}
+	if value, ok := attr(start, "time"); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			t.warn("break", "time", "invalid time %q", value)
+		} else {
+			ms = int(d.Milliseconds())
+		}
+	}
+	if ms > 0 {
+		t.enablePhonemes()
+		t.append(script.Silence{Duration: ms})
+	}
+}
+
+// sayAs supports the interpret-as values characters, spell-out, digits,
+// telephone and date. Other text is spoken as-is.
+func (t *translator) sayAs(start xml.StartElement, text string) {
+	interpretAs, _ := attr(start, "interpret-as")
+	format, _ := attr(start, "format")
+	text = strings.TrimSpace(text)
+	switch interpretAs {
+	case "characters", "spell-out", "letters", "verbatim":
+		t.sync()
+		t.append(script.Mode{Mode: script.ModeSpell, On: true})
+		t.text(text)
+		t.append(script.Mode{Mode: script.ModeSpell})
+	case "digits":
+		t.text(spaced(text))
+	case "cardinal", "number":
+		if format == "digits" {
+			text = spaced(text)
+		}
+		t.text(text)
+	case "telephone":
+		t.text(telephone(text))
+	case "date":
+		t.text(t.date(text, format))
+	default:
+		t.warn("say-as", "interpret-as", "%q is not supported, speaking the text as-is", interpretAs)
+		t.text(text)
+	}
+}
+
+// spaced separates all characters except white space by spaces, so that
+// digits are read one by one.
+func spaced(s string) string {
+	return strings.Join(strings.Split(strings.Join(strings.Fields(s), ""), ""), " ")
+}
+
+// telephone reads every digit of a telephone number separately, pausing
+// between groups, for example "5 5 5, 1 2 3 4" for "555-1234".
+func telephone(s string) string {
+	groups := strings.FieldsFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == '+' || r == '*' || r == '#')
+	})
+	for i, g := range groups {
+		groups[i] = spaced(g)
+	}
+	return strings.Join(groups, ", ")
+}
+
+var months = []string{
+	"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December",
+}
+
+// date spells out the month of a date in the given format, such as "mdy",
+// for English. Without format, ISO dates such as "2024-03-05" are accepted.
+func (t *translator) date(text, format string) string {
+	if t.table != nil && t.table.Language() != "us" && t.table.Language() != "uk" {
+		t.warn("say-as", "interpret-as", "dates are only supported in English, speaking the text as-is")
+		return text
+	}
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	if format == "" {
+		format = "ymd"
+	}
+	if len(fields) != len(format) {
+		t.warn("say-as", "format", "date %q does not match format %q", text, format)
+		return text
+	}
+
+	var day, month, year string
+	for i, f := range format {
+		switch f {
+		case 'd':
+			day = strings.TrimLeft(fields[i], "0")
+		case 'm':
+			month = fields[i]
+		case 'y':
+			year = fields[i]
+		default:
+			t.warn("say-as", "format", "unknown date format %q", format)
+			return text
+		}
+	}
+	if month != "" {
+		m, err := strconv.Atoi(month)
+		if err != nil || m < 1 || m > 12 {
+			t.warn("say-as", "format", "invalid month in date %q", text)
+			return text
+		}
+		month = months[m-1]
+	}
+
+	var parts []string
+	if t.table != nil && t.table.Language() == "uk" {
+		parts = []string{day, month, year}
+	} else if day != "" && year != "" {
+		parts = []string{month, day + ",", year}
+	} else {
+		parts = []string{month, day, year}
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
@@ -0,0 +1,490 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+// Package ssml translates SSML 1.1 documents into text with inline commands
+// for [dectalkdapi.TTS.Speak].
+//
+// Voices, prosody, breaks, say-as, phonemes and marks are mapped to the
+// closest inline commands:
+//
+//	doc, err := ssml.TranslateString(`<speak xml:lang="en-US">
+//		<voice name="betty">Hello <break time="300ms"/> world.</voice>
+//	</speak>`)
+//	if err != nil {
+//		return err
+//	}
+//	for _, w := range doc.Warnings {
+//		log.Print(w)
+//	}
+//	tts.Speak(doc.String(), dectalkdapi.Normal)
+//
+// Anything that has no equivalent is reported as a [Warning] instead of
+// failing the translation, and the text it contains is spoken as usual.
+//
+// A language can not be switched within a single call to Speak. Documents
+// using <lang> or xml:lang are split into one [Segment] per language, each of
+// which is meant to be spoken by an instance started for that language with
+// [dectalkdapi.StartLang] and [dectalkdapi.StartupLocked].
+package ssml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi/phonetic"
+	"github.com/icedream/go-dectalkdapi/script"
+)
+
+// ErrNotSSML is returned by [Translate] for documents whose root element is
+// not <speak>.
+var ErrNotSSML = errors.New("not an SSML document")
+
+// Warning reports a part of a document that could not be translated exactly.
+type Warning struct {
+	// Line is the line of the element in the document, counting from 1.
+	Line int
+
+	// Element is the name of the element, such as "audio".
+	Element string
+
+	// Attribute is the name of the attribute the warning is about, if any.
+	Attribute string
+
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Attribute != "" {
+		return fmt.Sprintf("line %d: <%s %s>: %s", w.Line, w.Element, w.Attribute, w.Message)
+	}
+	return fmt.Sprintf("line %d: <%s>: %s", w.Line, w.Element, w.Message)
+}
+
+// Segment is a part of a document in a single language.
+type Segment struct {
+	// Lang is the 2-character language ID as accepted by
+	// [dectalkdapi.StartLang], or empty if the document does not specify a
+	// language.
+	Lang string
+
+	Script script.Script
+}
+
+// String renders the segment as accepted by [dectalkdapi.TTS.Speak].
+func (s Segment) String() string {
+	return s.Script.String()
+}
+
+// Document is the translation of an SSML document.
+type Document struct {
+	// Segments holds the parts of the document, starting a new one every
+	// time the language changes. Each segment sets the voice, rate, volume
+	// and pitch it starts with and turns phoneme input off again if it
+	// turned it on, so that segments of the same language can be spoken by
+	// the same instance.
+	Segments []Segment
+
+	// Marks holds the names of the <mark> elements in document order. The
+	// mark at index i is reported with the value i+1 by
+	// [dectalkdapi.IndexMarkEvent], see [Document.Mark].
+	Marks []string
+
+	Warnings []Warning
+}
+
+// String renders all segments as accepted by [dectalkdapi.TTS.Speak],
+// ignoring their language.
+func (d *Document) String() string {
+	var b strings.Builder
+	for _, s := range d.Segments {
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+// Mark returns the name of the <mark> element for the value of an index mark.
+func (d *Document) Mark(value uint32) (string, bool) {
+	if value == 0 || uint64(value) > uint64(len(d.Marks)) {
+		return "", false
+	}
+	return d.Marks[value-1], true
+}
+
+// TranslateString translates an SSML document given as a string, see
+// [Translate].
+func TranslateString(s string) (*Document, error) {
+	return Translate(strings.NewReader(s))
+}
+
+// Translate reads an SSML document and translates it into inline commands.
+// Only malformed XML and documents that are not SSML fail, everything else
+// that can not be translated is reported in [Document.Warnings].
+//
+// The translation assumes that the instance speaking the first segment of each
+// language has the settings it starts up with.
+func Translate(r io.Reader) (*Document, error) {
+	t := &translator{
+		dec: xml.NewDecoder(r),
+		doc: new(Document),
+	}
+	for {
+		tok, err := t.dec.Token()
+		if err == io.EOF {
+			return nil, ErrNotSSML
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "speak" {
+			return nil, fmt.Errorf("%w: root element is <%s>", ErrNotSSML, start.Name.Local)
+		}
+		if err := t.speak(start); err != nil {
+			return nil, err
+		}
+		t.endSegment()
+		return t.doc, nil
+	}
+}
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// attr returns the value of an attribute without namespace, or of the xml:lang
+// attribute if name is "xml:lang".
+func attr(start xml.StartElement, name string) (string, bool) {
+	space := ""
+	if name == "xml:lang" {
+		space, name = xmlNamespace, "lang"
+	}
+	for _, a := range start.Attr {
+		if a.Name.Local == name && a.Name.Space == space {
+			return strings.TrimSpace(a.Value), true
+		}
+	}
+	return "", false
+}
+
+type translator struct {
+	dec *xml.Decoder
+	doc *Document
+
+	// line is the line of the element being translated.
+	line int
+
+	// state is the voice the text is spoken with and emitted the voice the
+	// commands written to the current segment result in.
+	state   voiceState
+	emitted voiceState
+
+	// table converts phonemes for the current language, which is nil if
+	// it is unknown.
+	table *phonetic.Table
+
+	// phonemeInput is true once phoneme input has been enabled in the
+	// current segment.
+	phonemeInput bool
+}
+
+func (t *translator) warn(element, attribute, format string, args ...interface{}) {
+	t.doc.Warnings = append(t.doc.Warnings, Warning{
+		Line:      t.line,
+		Element:   element,
+		Attribute: attribute,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+func (t *translator) segment() *Segment {
+	if len(t.doc.Segments) == 0 {
+		t.doc.Segments = append(t.doc.Segments, Segment{})
+	}
+	return &t.doc.Segments[len(t.doc.Segments)-1]
+}
+
+func (t *translator) append(nodes ...script.Node) {
+	s := t.segment()
+	s.Script = append(s.Script, nodes...)
+}
+
+// setLang switches to another language, starting a new segment unless the
+// current one is still empty.
+func (t *translator) setLang(table *phonetic.Table) {
+	if table == t.table {
+		return
+	}
+	t.table = table
+	lang := ""
+	if table != nil {
+		lang = table.Language()
+	}
+	s := t.segment()
+	if len(s.Script) == 0 {
+		s.Lang = lang
+		return
+	}
+	t.endSegment()
+	t.doc.Segments = append(t.doc.Segments, Segment{Lang: lang})
+	// The instance speaking the new segment may have been left with any
+	// voice by an earlier segment.
+	t.emitted = unknownVoice
+}
+
+// sync writes the commands needed to speak with the current voice.
+func (t *translator) sync() {
+	s, e := t.state, t.emitted
+	if s.speaker != e.speaker {
+		t.append(script.Name{Speaker: s.speaker})
+		e.speaker, e.pitch = s.speaker, defaultPitch(s.speaker)
+	}
+	if s.pitch != e.pitch {
+		t.append(script.DefineVoice{{Name: script.AveragePitch, Value: s.pitch}})
+	}
+	if s.rate != e.rate {
+		t.append(script.Rate{WordsPerMinute: s.rate})
+	}
+	if s.volume != e.volume {
+		t.append(script.Volume{Op: script.VolumeSet, Value: s.volume})
+	}
+	t.emitted = s
+}
+
+func (t *translator) text(s string) {
+	s = collapseSpace(s)
+	if s == "" {
+		return
+	}
+	if strings.TrimSpace(s) != "" {
+		t.sync()
+	}
+	t.append(script.Text(script.Literal(s)))
+}
+
+// collapseSpace replaces every run of white space with a single space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// enablePhonemes enables phoneme input once per segment for [script.Phonemes]
+// and [script.Silence]. Text is escaped with [script.Literal], so it is not
+// affected.
+func (t *translator) enablePhonemes() {
+	t.sync()
+	if !t.phonemeInput {
+		t.append(script.PhonemeInput{On: true})
+		t.phonemeInput = true
+	}
+}
+
+// endSegment disables phoneme input at the end of a segment that enabled it,
+// so that brackets in text spoken later are not taken for phonemes.
+func (t *translator) endSegment() {
+	if t.phonemeInput {
+		t.append(script.PhonemeInput{On: false})
+		t.phonemeInput = false
+	}
+}
+
+func (t *translator) speak(start xml.StartElement) error {
+	t.line, _ = t.dec.InputPos()
+	t.state = defaultVoice
+	t.emitted = defaultVoice
+	if tag, ok := attr(start, "xml:lang"); ok {
+		table, ok := phonetic.LookupTag(tag)
+		if !ok {
+			t.warn("speak", "xml:lang", "unknown language %q", tag)
+		}
+		t.setLang(table)
+	}
+	return t.content()
+}
+
+// content translates everything up to the end of the current element.
+func (t *translator) content() error {
+	for {
+		tok, err := t.dec.Token()
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			t.text(string(tok))
+		case xml.StartElement:
+			t.line, _ = t.dec.InputPos()
+			if err := t.element(tok); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// container translates the content of an element with a different voice and,
+// if it has an xml:lang attribute, language, restoring both afterwards.
+func (t *translator) container(start xml.StartElement, voice voiceState) error {
+	parentVoice, parentTable := t.state, t.table
+	if tag, ok := attr(start, "xml:lang"); ok {
+		if table, ok := phonetic.LookupTag(tag); ok {
+			t.setLang(table)
+		} else {
+			t.warn(start.Name.Local, "xml:lang", "unknown language %q, keeping the current one", tag)
+		}
+	} else if start.Name.Local == "lang" {
+		t.warn("lang", "xml:lang", "missing language")
+	}
+
+	t.state = voice
+	err := t.content()
+	t.state = parentVoice
+	t.setLang(parentTable)
+	return err
+}
+
+// collect returns the text content of the current element, skipping nested
+// elements.
+func (t *translator) collect(element string) (string, error) {
+	var b strings.Builder
+	for {
+		tok, err := t.dec.Token()
+		if err == io.EOF {
+			return "", io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return "", err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			b.Write(tok)
+		case xml.StartElement:
+			t.line, _ = t.dec.InputPos()
+			t.warn(tok.Name.Local, "", "ignored inside <%s>", element)
+			if err := t.dec.Skip(); err != nil {
+				return "", err
+			}
+		case xml.EndElement:
+			return b.String(), nil
+		}
+	}
+}
+
+func (t *translator) element(start xml.StartElement) error {
+	name := start.Name.Local
+	switch name {
+	case "p", "s", "lang":
+		return t.container(start, t.state)
+	case "voice":
+		return t.container(start, t.voice(start))
+	case "prosody":
+		return t.container(start, t.prosody(start))
+	case "break":
+		t.breakElement(start)
+		return t.dec.Skip()
+	case "mark":
+		t.mark(start)
+		return t.dec.Skip()
+	case "say-as":
+		text, err := t.collect(name)
+		if err != nil {
+			return err
+		}
+		t.sayAs(start, text)
+		return nil
+	case "phoneme":
+		text, err := t.collect(name)
+		if err != nil {
+			return err
+		}
+		t.phoneme(start, text)
+		return nil
+	case "sub":
+		text, err := t.collect(name)
+		if err != nil {
+			return err
+		}
+		if alias, ok := attr(start, "alias"); ok {
+			text = alias
+		} else {
+			t.warn(name, "alias", "missing alias, speaking the content")
+		}
+		t.text(text)
+		return nil
+	case "desc", "meta", "metadata":
+		return t.dec.Skip()
+	case "lexicon":
+		t.warn(name, "", "not supported, load lexicons with the userdict package")
+		return t.dec.Skip()
+	case "audio":
+		t.warn(name, "", "not supported, speaking the fallback content")
+		return t.container(start, t.state)
+	default:
+		t.warn(name, "", "not supported, speaking the content")
+		return t.container(start, t.state)
+	}
+}
+
+func (t *translator) mark(start xml.StartElement) {
+	name, ok := attr(start, "name")
+	if !ok {
+		t.warn("mark", "name", "missing name")
+		return
+	}
+	t.doc.Marks = append(t.doc.Marks, name)
+	t.append(script.Index{Value: uint32(len(t.doc.Marks))})
+}
+
+func (t *translator) phoneme(start xml.StartElement, text string) {
+	ph, ok := attr(start, "ph")
+	if !ok {
+		t.warn("phoneme", "ph", "missing pronunciation, speaking the content")
+		t.text(text)
+		return
+	}
+	table := t.table
+	if table == nil {
+		table, _ = phonetic.Lookup("us")
+	}
+
+	alphabet, _ := attr(start, "alphabet")
+	var arpabet string
+	var err error
+	switch strings.ToLower(alphabet) {
+	case "", "ipa":
+		arpabet, err = table.FromIPA(ph)
+	case "x-sampa":
+		arpabet, err = table.FromXSAMPA(ph)
+	default:
+		err = fmt.Errorf("unknown alphabet %q", alphabet)
+	}
+	if err != nil {
+		t.warn("phoneme", "ph", "%v, speaking the content", err)
+		t.text(text)
+		return
+	}
+	t.enablePhonemes()
+	t.append(script.Phonemes(arpabet))
+}
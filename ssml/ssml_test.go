@@ -0,0 +1,105 @@
+package ssml_test
+
+import (
+	"errors"
+	"github.com/icedream/go-dectalkdapi/ssml"
+	"strings"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		ssml string
+		want string
+	}{
+		{`<speak>Hello [world]</speak>`, "Hello (world)"},
+		{`<speak><voice name="betty">Hi.</voice> Bye.</speak>`, "[:name betty]Hi.[:name paul] Bye."},
+		{`<speak><voice gender="female" variant="2">Hi.</voice></speak>`, "[:name ursula]Hi."},
+		{`<speak><prosody rate="fast" volume="-6dB">Hi.</prosody></speak>`, "[:rate 250][:volume set 50]Hi."},
+		{`<speak><prosody pitch="+12st">Hi.</prosody></speak>`, "[:dv ap 244]Hi."},
+		{`<speak>One<break time="500ms"/>two</speak>`, "One[:phoneme arpabet speak on][_<500>]two[:phoneme off]"},
+		{`<speak><say-as interpret-as="characters">abc</say-as></speak>`, "[:mode spell on]abc[:mode spell off]"},
+		{`<speak><say-as interpret-as="telephone">555-1234</say-as></speak>`, "5 5 5, 1 2 3 4"},
+		{`<speak><say-as interpret-as="date" format="mdy">03/05/2024</say-as></speak>`, "March 5, 2024"},
+		{`<speak xml:lang="en-US"><phoneme ph="təˈmɑtoʊ">tomato</phoneme></speak>`, "[:phoneme arpabet speak on][taxm'aatow][:phoneme off]"},
+		{`<speak><sub alias="World Wide Web Consortium">W3C</sub></speak>`, "World Wide Web Consortium"},
+		{`<speak>A<mark name="here"/>B</speak>`, "A[:index mark 1]B"},
+	}
+	for _, test := range tests {
+		doc, err := ssml.TranslateString(test.ssml)
+		if err != nil {
+			t.Errorf("TranslateString(%q) failed: %v", test.ssml, err)
+			continue
+		}
+		if got := doc.String(); got != test.want {
+			t.Errorf("TranslateString(%q) = %q, want %q", test.ssml, got, test.want)
+		}
+		if len(doc.Warnings) != 0 {
+			t.Errorf("TranslateString(%q) reported warnings: %v", test.ssml, doc.Warnings)
+		}
+	}
+}
+
+func TestTranslateLang(t *testing.T) {
+	doc, err := ssml.TranslateString(`<speak xml:lang="en-US">Hello <lang xml:lang="de-DE">Hallo</lang> hello</speak>`)
+	if err != nil {
+		t.Fatalf("TranslateString() failed: %v", err)
+	}
+	if len(doc.Segments) != 3 {
+		t.Fatalf("Expected 3 segments, got %v", doc.Segments)
+	}
+	for i, lang := range []string{"us", "gr", "us"} {
+		if doc.Segments[i].Lang != lang {
+			t.Errorf("Expected segment %d to be in %q, got %q", i, lang, doc.Segments[i].Lang)
+		}
+	}
+	if got := doc.Segments[1].String(); got != "[:name paul][:rate 180][:volume set 100]Hallo" {
+		t.Errorf("Expected the segment to set up the voice, got %q", got)
+	}
+
+	// Every segment turns phoneme input off again before the next one.
+	doc, err = ssml.TranslateString(`<speak xml:lang="en-US"><break time="1s"/>a [b] <lang xml:lang="de-DE">c [d]</lang></speak>`)
+	if err != nil {
+		t.Fatalf("TranslateString() failed: %v", err)
+	}
+	if got := doc.Segments[0].String(); !strings.HasSuffix(got, "[:phoneme off]") {
+		t.Errorf("Expected the segment to end with phoneme input off, got %q", got)
+	}
+	for _, segment := range doc.Segments[1:] {
+		if got := segment.String(); strings.Contains(got, "[:phoneme") {
+			t.Errorf("Expected the segment not to touch phoneme input, got %q", got)
+		}
+	}
+}
+
+func TestTranslateWarnings(t *testing.T) {
+	doc, err := ssml.TranslateString(`<speak>
+<audio src="beep.wav">beep</audio>
+<prosody contour="(0%,+20Hz)">up</prosody>
+<say-as interpret-as="time">12:00</say-as>
+<mark name="a"/><mark name="b"/>
+</speak>`)
+	if err != nil {
+		t.Fatalf("TranslateString() failed: %v", err)
+	}
+	want := []ssml.Warning{
+		{Line: 2, Element: "audio", Message: "not supported, speaking the fallback content"},
+		{Line: 3, Element: "prosody", Attribute: "contour", Message: "not supported"},
+		{Line: 4, Element: "say-as", Attribute: "interpret-as", Message: `"time" is not supported, speaking the text as-is`},
+	}
+	if len(doc.Warnings) != len(want) {
+		t.Fatalf("Expected %d warnings, got %v", len(want), doc.Warnings)
+	}
+	for i, w := range want {
+		if doc.Warnings[i] != w {
+			t.Errorf("Expected warning %v, got %v", w, doc.Warnings[i])
+		}
+	}
+	if name, ok := doc.Mark(2); !ok || name != "b" {
+		t.Errorf("Expected mark 2 to be named b, got %q", name)
+	}
+
+	if _, err := ssml.TranslateString(`<html>Hi</html>`); !errors.Is(err, ssml.ErrNotSSML) {
+		t.Errorf("Expected ErrNotSSML, got %v", err)
+	}
+}
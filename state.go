@@ -0,0 +1,179 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Mode is the state the text-to-speech system of a [TTS] instance is in.
+type Mode int32
+
+const (
+	// The startup state, speech samples are sent to the audio device or
+	// ignored depending on the device options given at startup.
+	ModeStartup Mode = iota
+
+	// Wave-file mode, see [TTS.OpenWaveOutFile].
+	ModeWaveFile
+
+	// Log-file mode, see [TTS.OpenLogFile].
+	ModeLogFile
+
+	// Speech-to-memory mode, see [TTS.OpenInMemory].
+	ModeInMemory
+
+	// The text-to-speech system has been shut down, see [TTS.Shutdown].
+	ModeShutdown
+)
+
+var modeNames = map[Mode]string{
+	ModeStartup:  "startup",
+	ModeWaveFile: "wave-file",
+	ModeLogFile:  "log-file",
+	ModeInMemory: "speech-to-memory",
+	ModeShutdown: "shut down",
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int32(m))
+}
+
+// ModeError is returned when a function is called while the text-to-speech
+// system is in a mode that does not allow it. It matches [ErrWrongMode] with
+// [errors.Is].
+type ModeError struct {
+	// Op is the name of the function that was called.
+	Op string
+
+	// Mode is the mode the text-to-speech system was in.
+	Mode Mode
+}
+
+func (e *ModeError) Error() string {
+	return fmt.Sprintf("%s: not allowed in %s mode", e.Op, e.Mode)
+}
+
+func (e *ModeError) Unwrap() error {
+	return ErrWrongMode
+}
+
+// state tracks the mode of the text-to-speech system so that calls which are
+// known to crash or deadlock the engine are refused before reaching it.
+type state struct {
+	// lock is held shared by every call into the engine and exclusively by
+	// calls that change the mode.
+	lock sync.RWMutex
+
+	// interrupt is held while queued text is discarded without lock, so
+	// that Shutdown can wait for it before the handle goes away.
+	interrupt sync.Mutex
+
+	mode atomic.Int32
+
+	// closing is set as soon as Shutdown starts so that new calls are
+	// refused without waiting for lock.
+	closing atomic.Bool
+
+	// resetting is set while a full reset left running by a cancelled
+	// context is pending, and closed once it is done.
+	resetting atomic.Pointer[chan struct{}]
+
+	// busy is set when text is queued in speech-to-memory mode and cleared
+	// once the engine is known to be idle.
+	busy atomic.Bool
+}
+
+func (s *state) current() Mode {
+	return Mode(s.mode.Load())
+}
+
+func (s *state) set(mode Mode) {
+	s.mode.Store(int32(mode))
+}
+
+// check returns an error unless the current mode is one of modes. An empty
+// list of modes allows every mode except ModeShutdown.
+func (s *state) check(op string, modes []Mode) error {
+	current := s.current()
+	if current == ModeShutdown {
+		return ErrShutdown
+	}
+	if len(modes) == 0 {
+		return nil
+	}
+	for _, mode := range modes {
+		if current == mode {
+			return nil
+		}
+	}
+	return &ModeError{Op: op, Mode: current}
+}
+
+// settle waits for a pending full reset, see resetting.
+func (s *state) settle() {
+	if p := s.resetting.Load(); p != nil {
+		<-*p
+	}
+}
+
+// enter must be called before calling into the engine and be paired with
+// leave if it succeeds.
+func (s *state) enter(op string, modes ...Mode) error {
+	if s.closing.Load() {
+		return ErrShutdown
+	}
+	s.settle()
+	s.lock.RLock()
+	if err := s.check(op, modes); err != nil {
+		s.lock.RUnlock()
+		return err
+	}
+	return nil
+}
+
+func (s *state) leave() {
+	s.lock.RUnlock()
+}
+
+// enterExclusive must be called before calls into the engine that change the
+// mode and be paired with leaveExclusive if it succeeds.
+func (s *state) enterExclusive(op string, modes ...Mode) error {
+	if s.closing.Load() {
+		return ErrShutdown
+	}
+	s.settle()
+	return s.lockExclusive(op, modes...)
+}
+
+// lockExclusive is enterExclusive without waiting for a pending full reset,
+// for the one carrying it out.
+func (s *state) lockExclusive(op string, modes ...Mode) error {
+	if s.closing.Load() {
+		return ErrShutdown
+	}
+	s.lock.Lock()
+	if err := s.check(op, modes); err != nil {
+		s.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *state) leaveExclusive() {
+	s.lock.Unlock()
+}
+
+// Mode returns the mode the text-to-speech system is currently in.
+//
+// Note that a log file opened with the Log inline command is not tracked.
+func (t *TTS) Mode() Mode {
+	t.state.settle()
+	return t.state.current()
+}
@@ -0,0 +1,132 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+/*
+#if defined WIN32
+#include <windows.h>
+#include <TTSAPI.H>
+#else
+#include <dtk/ttsapi.h>
+#endif
+*/
+import "C"
+
+// StatusIdentifier selects a status value to query with [TTS.GetStatus].
+type StatusIdentifier C.DWORD
+
+// statusGoSide marks status identifiers that are answered by this package
+// instead of the engine.
+const statusGoSide StatusIdentifier = 1 << 16
+
+const (
+	// Number of characters queued to the text-to-speech system that have not
+	// been processed yet.
+	StatusIdentifierInputCharacterCount StatusIdentifier = C.INPUT_CHARACTER_COUNT
+
+	// 1 while the text-to-speech system is speaking, 0 otherwise.
+	StatusIdentifierSpeaking StatusIdentifier = C.STATUS_SPEAKING
+
+	// ID of the wave output device in use.
+	StatusIdentifierWaveOutDeviceID StatusIdentifier = C.WAVE_OUT_DEVICE_ID
+
+	// 1 while audio output is paused by [TTS.Pause], 0 otherwise.
+	StatusIdentifierPaused = statusGoSide + iota
+
+	// 1 while the text-to-speech system is not speaking, 0 otherwise.
+	StatusIdentifierSilent
+
+	// Code of the last error the engine reported asynchronously, or
+	// [NoError].
+	StatusIdentifierError
+)
+
+// Status is a single status value returned by [TTS.GetStatus].
+type Status struct {
+	Identifier StatusIdentifier
+	Value      uint32
+}
+
+// GetStatus returns the first count status values of the text-to-speech
+// system selected by identifiers, in the same order.
+//
+// [StatusIdentifierPaused], [StatusIdentifierSilent] and
+// [StatusIdentifierError] are not known to the engine and are derived from the
+// state tracked by this package.
+func (t *TTS) GetStatus(identifiers []StatusIdentifier, count uint32) ([]Status, error) {
+	if err := t.state.enter("GetStatus"); err != nil {
+		return nil, err
+	}
+	defer t.state.leave()
+
+	if count == 0 || int(count) > len(identifiers) {
+		return nil, &MMError{Code: InvalidParam}
+	}
+	identifiers = identifiers[:count]
+
+	// Query the engine for everything it knows about, including the speaking
+	// status which StatusIdentifierSilent is derived from.
+	var engineIdentifiers []C.DWORD
+	engineIndex := make([]int, len(identifiers))
+	speakingIndex := -1
+	for i, identifier := range identifiers {
+		engineIndex[i] = -1
+		switch {
+		case identifier < statusGoSide:
+			engineIndex[i] = len(engineIdentifiers)
+			engineIdentifiers = append(engineIdentifiers, C.DWORD(identifier))
+		case identifier == StatusIdentifierSilent && speakingIndex < 0:
+			speakingIndex = len(engineIdentifiers)
+			engineIdentifiers = append(engineIdentifiers, C.DWORD(StatusIdentifierSpeaking))
+		}
+	}
+	engineValues := make([]C.DWORD, len(engineIdentifiers))
+	if len(engineIdentifiers) > 0 {
+		err := t.call(func() C.MMRESULT {
+			return C.TextToSpeechGetStatus(
+				t.handle,
+				&engineIdentifiers[0],
+				&engineValues[0],
+				C.DWORD(len(engineIdentifiers)),
+			)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status := make([]Status, len(identifiers))
+	for i, identifier := range identifiers {
+		status[i].Identifier = identifier
+		switch identifier {
+		case StatusIdentifierPaused:
+			if t.paused.Load() {
+				status[i].Value = 1
+			}
+		case StatusIdentifierSilent:
+			if engineValues[speakingIndex] == 0 {
+				status[i].Value = 1
+			}
+		case StatusIdentifierError:
+			status[i].Value = t.lastError.Load()
+		default:
+			if engineIndex[i] < 0 {
+				return nil, &MMError{Code: InvalidParam}
+			}
+			status[i].Value = uint32(engineValues[engineIndex[i]])
+		}
+	}
+	return status, nil
+}
+
+// idle reports whether the engine has neither unprocessed text queued nor is
+// speaking. It must only be called between state.enter and state.leave.
+func (t *TTS) idle() bool {
+	identifiers := [2]C.DWORD{C.INPUT_CHARACTER_COUNT, C.STATUS_SPEAKING}
+	var values [2]C.DWORD
+	err := t.call(func() C.MMRESULT {
+		return C.TextToSpeechGetStatus(t.handle, &identifiers[0], &values[0], C.DWORD(len(identifiers)))
+	})
+	return err == nil && values[0] == 0 && values[1] == 0
+}
@@ -0,0 +1,293 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/icedream/go-dectalkdapi/container"
+	"github.com/icedream/go-dectalkdapi/dsp"
+	"github.com/icedream/go-dectalkdapi/encode"
+	"github.com/icedream/go-dectalkdapi/resample"
+)
+
+const (
+	// synthesizeBuffers is the number of speech-to-memory buffers used by
+	// [TTS.SynthesizeTo].
+	synthesizeBuffers = 4
+
+	// synthesizeBufferLength is the size of each buffer used by
+	// [TTS.SynthesizeTo], which is about 1.5 seconds of 16-bit samples.
+	synthesizeBufferLength = 32768
+)
+
+// SynthesizeTo speaks text in speech-to-memory mode and writes the raw speech
+// samples in the given format to w. It returns once all of the text has been
+// spoken, w has failed or ctx is done, and always leaves the text-to-speech
+// system in the startup state.
+//
+// If ctx is done first, the text-to-speech system is reset fully with
+// [TTS.Reset] and ctx.Err() is returned.
+//
+// The text may contain inline commands just like the text passed to
+// [TTS.Speak]. If a chain has been set with [TTS.SetProcessor], the samples
+// are only written once all of the text has been spoken and processed.
+func (t *TTS) SynthesizeTo(ctx context.Context, w io.Writer, text string, format WaveFormat) error {
+	c := t.processor.Load()
+	if c == nil {
+		return t.synthesizeTo(ctx, w, text, format)
+	}
+	pw, err := dsp.NewWriter(w, format.Format(), c)
+	if err != nil {
+		return err
+	}
+	if err := t.synthesizeTo(ctx, pw, text, format); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// SetProcessor sets a chain that post-processes all samples synthesized by
+// [TTS.SynthesizeTo] and the functions built on it before they are written,
+// converted or encoded, such as one normalizing the loudness of every
+// utterance. A nil chain, the default, leaves the samples as they are.
+func (t *TTS) SetProcessor(c *dsp.Chain) {
+	t.processor.Store(c)
+}
+
+// synthesizeTo implements [TTS.SynthesizeTo] without post-processing.
+func (t *TTS) synthesizeTo(ctx context.Context, w io.Writer, text string, format WaveFormat) (err error) {
+	if err := t.OpenInMemory(format); err != nil {
+		return err
+	}
+
+	buffers := make([]*Buffer, 0, synthesizeBuffers)
+	defer func() {
+		// A full reset has already returned to the startup state.
+		var closeErr error
+		if t.Mode() == ModeInMemory {
+			closeErr = t.CloseInMemory()
+		}
+		for _, b := range buffers {
+			b.Free()
+		}
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	for i := 0; i < synthesizeBuffers; i++ {
+		b := NewBuffer(synthesizeBufferLength, 0, 0)
+		buffers = append(buffers, b)
+		if err := t.AddBuffer(b); err != nil {
+			return err
+		}
+	}
+
+	if err := t.Speak(text, Force); err != nil {
+		return err
+	}
+
+	var writeErr error
+	write := func(b *Buffer) {
+		if writeErr == nil && b.Len() > 0 {
+			_, writeErr = w.Write(b.Data())
+		}
+	}
+
+	synced := make(chan error, 1)
+	go func() {
+		synced <- t.Sync()
+	}()
+
+	// Resetting flushes the queued text, which also makes Sync return.
+	aborted := false
+	abort := func(fullReset bool) {
+		if !aborted {
+			aborted = true
+			_ = t.Reset(fullReset)
+		}
+	}
+
+	var syncErr error
+wait:
+	for {
+		select {
+		case b := <-t.Buffers():
+			write(b)
+			if writeErr != nil {
+				abort(false)
+			}
+			if !aborted {
+				if err := t.AddBuffer(b); err != nil {
+					writeErr = err
+					abort(false)
+				}
+			}
+		case syncErr = <-synced:
+			break wait
+		case <-ctx.Done():
+			abort(true)
+		}
+	}
+
+	// Buffers that were filled right before Sync returned.
+	t.drainBuffers(write)
+	if ctx.Err() != nil && aborted {
+		return ctx.Err()
+	}
+
+	// The last buffer is only partially filled and has to be requested
+	// explicitly.
+	if b, err := t.ReturnBuffer(); err == nil && b != nil {
+		write(b)
+	}
+
+	// Take back all remaining empty buffers so the engine is idle before
+	// leaving speech-to-memory mode.
+	resetErr := t.Reset(false)
+	t.drainBuffers(write)
+
+	switch {
+	case writeErr != nil:
+		return writeErr
+	case syncErr != nil:
+		return syncErr
+	}
+	return resetErr
+}
+
+// drainBuffers passes every buffer that has already been handed back by the
+// engine to f without waiting for more.
+func (t *TTS) drainBuffers(f func(*Buffer)) {
+	for {
+		select {
+		case b := <-t.Buffers():
+			f(b)
+		default:
+			return
+		}
+	}
+}
+
+// SynthesizeWAVTo speaks text like [TTS.SynthesizeTo] and writes the speech
+// samples to w as a complete WAV file. The container package writes other
+// containers and streams samples while they are synthesized.
+func (t *TTS) SynthesizeWAVTo(ctx context.Context, w io.Writer, text string, format WaveFormat) error {
+	var samples bytes.Buffer
+	if err := t.SynthesizeTo(ctx, &samples, text, format); err != nil {
+		return err
+	}
+	cw, err := container.NewWriter(w, container.WAV, format.Format(), int64(samples.Len()))
+	if err != nil {
+		return err
+	}
+	if _, err := samples.WriteTo(cw); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// SynthesizeResampledTo speaks text like [TTS.SynthesizeTo] and writes the
+// speech samples to w converted to any sample rate and encoding, such as
+// 48 kHz float samples for video. Unless format matches one of the formats of
+// the engine, the text is synthesized in [WaveFormat1M16] and resampled with
+// the given quality while it is spoken.
+func (t *TTS) SynthesizeResampledTo(ctx context.Context, w io.Writer, text string, format container.Format, quality resample.Quality) error {
+	for _, f := range []WaveFormat{WaveFormat1M16, WaveFormat1M08, WaveFormat08M08} {
+		if f.Format() == format {
+			return t.SynthesizeTo(ctx, w, text, f)
+		}
+	}
+	rw, err := resample.NewWriter(w, WaveFormat1M16.Format(), format, quality)
+	if err != nil {
+		return err
+	}
+	if err := t.SynthesizeTo(ctx, rw, text, WaveFormat1M16); err != nil {
+		return err
+	}
+	return rw.Close()
+}
+
+// SynthesizeEncodedTo speaks text like [TTS.SynthesizeTo] and writes the
+// speech to w compressed in the given format while it is spoken. The file is
+// tagged with the text, the current speaker and the version of the engine.
+func (t *TTS) SynthesizeEncodedTo(ctx context.Context, w io.Writer, text string, format encode.Format) error {
+	speaker, err := t.GetSpeaker()
+	if err != nil {
+		return err
+	}
+	version, _, _, _, _ := Version()
+	ew, err := encode.NewEncoder(w, format, WaveFormat1M16.Format(), encode.Tags{
+		Text:    text,
+		Speaker: speaker.String(),
+		Version: version,
+	})
+	if err != nil {
+		return err
+	}
+	if err := t.SynthesizeTo(ctx, ew, text, WaveFormat1M16); err != nil {
+		encode.Abort(ew)
+		return err
+	}
+	return ew.Close()
+}
+
+// SynthesizeFile speaks text and writes the speech to a file in the format
+// given by the extension of its name: WAV, AIFF or AU for uncompressed
+// [WaveFormat1M16] samples, or FLAC, Ogg Opus or MP3 as written by
+// [TTS.SynthesizeEncodedTo]. The file is removed if that fails.
+func (t *TTS) SynthesizeFile(ctx context.Context, name, text string) (err error) {
+	c, uncompressed := container.ContainerFor(name)
+	format, compressed := encode.FormatFor(name)
+	if !uncompressed && !compressed {
+		return fmt.Errorf("%w: %s", encode.ErrUnknownFormat, name)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(name)
+		}
+	}()
+	if compressed {
+		return t.SynthesizeEncodedTo(ctx, f, text, format)
+	}
+	cw, err := container.NewWriter(f, c, WaveFormat1M16.Format(), container.UnknownLength)
+	if err != nil {
+		return err
+	}
+	if err := t.SynthesizeTo(ctx, cw, text, WaveFormat1M16); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// Synthesize speaks text and returns the speech as a complete WAV file in
+// [WaveFormat1M16], without touching the file system.
+//
+// This replaces the sequence of [TTS.OpenWaveOutFile], [TTS.Speak],
+// [TTS.Sync] and [TTS.CloseWaveOutFile] followed by reading back the file.
+func (t *TTS) Synthesize(text string) ([]byte, error) {
+	return t.SynthesizeContext(context.Background(), text)
+}
+
+// SynthesizeContext is like [TTS.Synthesize], but resets the text-to-speech
+// system fully with [TTS.Reset] and returns ctx.Err() once ctx is done.
+func (t *TTS) SynthesizeContext(ctx context.Context, text string) ([]byte, error) {
+	var wav bytes.Buffer
+	if err := t.SynthesizeWAVTo(ctx, &wav, text, WaveFormat1M16); err != nil {
+		return nil, err
+	}
+	return wav.Bytes(), nil
+}
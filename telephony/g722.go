@@ -0,0 +1,234 @@
+package telephony
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Tables of ITU-T G.722 as used by the encoder at 64 kbit/s.
+var (
+	g722QMF  = [12]int{3, -11, 12, 32, -210, 951, 3876, -805, 362, -156, 53, -11}
+	g722Q6   = [32]int{0, 35, 72, 110, 150, 190, 233, 276, 323, 370, 422, 473, 530, 587, 650, 714, 786, 858, 940, 1023, 1121, 1219, 1339, 1458, 1612, 1765, 1980, 2195, 2557, 2919, 0, 0}
+	g722ILN  = [32]int{0, 63, 62, 31, 30, 29, 28, 27, 26, 25, 24, 23, 22, 21, 20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 0}
+	g722ILP  = [32]int{0, 61, 60, 59, 58, 57, 56, 55, 54, 53, 52, 51, 50, 49, 48, 47, 46, 45, 44, 43, 42, 41, 40, 39, 38, 37, 36, 35, 34, 33, 32, 0}
+	g722WL   = [8]int{-60, -30, 58, 172, 334, 538, 1198, 3042}
+	g722RL42 = [16]int{0, 7, 6, 5, 4, 3, 2, 1, 7, 6, 5, 4, 3, 2, 1, 0}
+	g722ILB  = [32]int{2048, 2093, 2139, 2186, 2233, 2282, 2332, 2383, 2435, 2489, 2543, 2599, 2656, 2714, 2774, 2834, 2896, 2960, 3025, 3091, 3158, 3228, 3298, 3371, 3444, 3520, 3597, 3676, 3756, 3838, 3922, 4008}
+	g722QM4  = [16]int{0, -20456, -12896, -8968, -6288, -4240, -2584, -1200, 20456, 12896, 8968, 6288, 4240, 2584, 1200, 0}
+	g722QM2  = [4]int{-7408, -1616, 7408, 1616}
+	g722IHN  = [3]int{0, 1, 0}
+	g722IHP  = [3]int{0, 3, 2}
+	g722WH   = [3]int{0, -214, 798}
+	g722RH2  = [4]int{2, 1, 2, 1}
+)
+
+// g722Band is the state of the adaptive predictor of one sub-band.
+type g722Band struct {
+	s, sp, sz int
+	r         [3]int
+	a, ap     [3]int
+	p         [3]int
+	d         [7]int
+	b, bp     [7]int
+	sg        [7]int
+	nb, det   int
+}
+
+// g722Encoder encodes 16-bit samples at 16 kHz into G.722 at 64 kbit/s, one
+// byte for every two samples.
+type g722Encoder struct {
+	w       io.Writer
+	x       [24]int
+	band    [2]g722Band
+	partial []byte
+	buf     []byte
+	out     []byte
+}
+
+func newG722Encoder(w io.Writer) *g722Encoder {
+	e := &g722Encoder{w: w}
+	e.band[0].det = 32
+	e.band[1].det = 8
+	return e
+}
+
+// Write encodes samples in the layout of the engine.
+func (e *g722Encoder) Write(p []byte) (int, error) {
+	e.buf = append(append(e.buf[:0], e.partial...), p...)
+	whole := len(e.buf) - len(e.buf)%4
+	e.out = e.out[:0]
+	for i := 0; i < whole; i += 4 {
+		e.out = append(e.out, e.encode(
+			int(int16(binary.LittleEndian.Uint16(e.buf[i:]))),
+			int(int16(binary.LittleEndian.Uint16(e.buf[i+2:]))),
+		))
+	}
+	e.partial = append(e.partial[:0], e.buf[whole:]...)
+	if _, err := e.w.Write(e.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close encodes a last odd sample followed by silence.
+func (e *g722Encoder) Close() error {
+	if len(e.partial) < 2 {
+		return nil
+	}
+	code := e.encode(int(int16(binary.LittleEndian.Uint16(e.partial))), 0)
+	e.partial = e.partial[:0]
+	_, err := e.w.Write([]byte{code})
+	return err
+}
+
+// encode splits two samples into the sub-bands with the transmit QMF and
+// quantizes both sub-bands.
+func (e *g722Encoder) encode(x0, x1 int) byte {
+	copy(e.x[:], e.x[2:])
+	e.x[22], e.x[23] = x0, x1
+	var sumEven, sumOdd int
+	for i := 0; i < 12; i++ {
+		sumOdd += e.x[2*i] * g722QMF[i]
+		sumEven += e.x[2*i+1] * g722QMF[11-i]
+	}
+	xLow := (sumEven + sumOdd) >> 14
+	xHigh := (sumEven - sumOdd) >> 14
+
+	// The lower sub-band, quantized to 6 bits.
+	low := &e.band[0]
+	el := saturate(xLow - low.s)
+	wd := el
+	if el < 0 {
+		wd = -(el + 1)
+	}
+	i := 1
+	for ; i < 30; i++ {
+		if wd < (g722Q6[i]*low.det)>>12 {
+			break
+		}
+	}
+	ilow := g722ILP[i]
+	if el < 0 {
+		ilow = g722ILN[i]
+	}
+	ril := ilow >> 2
+	dlow := (low.det * g722QM4[ril]) >> 15
+	low.nb = clamp((low.nb*127)>>7+g722WL[g722RL42[ril]], 0, 18432)
+	low.det = scale(low.nb, 8)
+	low.update(dlow)
+
+	// The higher sub-band, quantized to 2 bits.
+	high := &e.band[1]
+	eh := saturate(xHigh - high.s)
+	wd = eh
+	if eh < 0 {
+		wd = -(eh + 1)
+	}
+	mih := 1
+	if wd >= (564*high.det)>>12 {
+		mih = 2
+	}
+	ihigh := g722IHP[mih]
+	if eh < 0 {
+		ihigh = g722IHN[mih]
+	}
+	dhigh := (high.det * g722QM2[ihigh]) >> 15
+	high.nb = clamp((high.nb*127)>>7+g722WH[g722RH2[ihigh]], 0, 22528)
+	high.det = scale(high.nb, 10)
+	high.update(dhigh)
+
+	return byte(ihigh<<6 | ilow)
+}
+
+// scale returns the quantizer scale factor of a logarithmic one.
+func scale(nb, shift int) int {
+	wd1 := (nb >> 6) & 31
+	wd2 := shift - (nb >> 11)
+	if wd2 < 0 {
+		return (g722ILB[wd1] << -wd2) << 2
+	}
+	return (g722ILB[wd1] >> wd2) << 2
+}
+
+// update adapts the pole and zero predictor of a sub-band to the quantized
+// difference signal d and predicts the next sample.
+func (s *g722Band) update(d int) {
+	s.d[0] = d
+	s.r[0] = saturate(s.s + d)
+	s.p[0] = saturate(s.sz + d)
+
+	for i := 0; i < 3; i++ {
+		s.sg[i] = s.p[i] >> 15
+	}
+	wd1 := saturate(s.a[1] << 2)
+	wd2 := wd1
+	if s.sg[0] == s.sg[1] {
+		wd2 = -wd1
+	}
+	if wd2 > 32767 {
+		wd2 = 32767
+	}
+	wd3 := -128
+	if s.sg[0] == s.sg[2] {
+		wd3 = 128
+	}
+	wd3 += wd2 >> 7
+	wd3 += (s.a[2] * 32512) >> 15
+	s.ap[2] = clamp(wd3, -12288, 12288)
+
+	wd1 = -192
+	if s.sg[0] == s.sg[1] {
+		wd1 = 192
+	}
+	wd2 = (s.a[1] * 32640) >> 15
+	limit := saturate(15360 - s.ap[2])
+	s.ap[1] = clamp(saturate(wd1+wd2), -limit, limit)
+
+	wd1 = 128
+	if d == 0 {
+		wd1 = 0
+	}
+	s.sg[0] = d >> 15
+	for i := 1; i < 7; i++ {
+		s.sg[i] = s.d[i] >> 15
+		wd2 := -wd1
+		if s.sg[i] == s.sg[0] {
+			wd2 = wd1
+		}
+		s.bp[i] = saturate(wd2 + (s.b[i]*32640)>>15)
+	}
+
+	for i := 6; i > 0; i-- {
+		s.d[i] = s.d[i-1]
+		s.b[i] = s.bp[i]
+	}
+	for i := 2; i > 0; i-- {
+		s.r[i] = s.r[i-1]
+		s.p[i] = s.p[i-1]
+		s.a[i] = s.ap[i]
+	}
+
+	wd1 = (s.a[1] * saturate(s.r[1]+s.r[1])) >> 15
+	wd2 = (s.a[2] * saturate(s.r[2]+s.r[2])) >> 15
+	s.sp = saturate(wd1 + wd2)
+	s.sz = 0
+	for i := 6; i > 0; i-- {
+		s.sz += (s.b[i] * saturate(s.d[i]+s.d[i])) >> 15
+	}
+	s.sz = saturate(s.sz)
+	s.s = saturate(s.sp + s.sz)
+}
+
+func saturate(x int) int {
+	return clamp(x, -32768, 32767)
+}
+
+func clamp(x, min, max int) int {
+	switch {
+	case x < min:
+		return min
+	case x > max:
+		return max
+	}
+	return x
+}
@@ -0,0 +1,140 @@
+package telephony
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi/container"
+	"github.com/icedream/go-dectalkdapi/resample"
+)
+
+// Synthesizer speaks text into samples in a given format, as implemented by
+// [dectalkdapi.TTS].
+type Synthesizer interface {
+	SynthesizeResampledTo(ctx context.Context, w io.Writer, text string, format container.Format, quality resample.Quality) error
+}
+
+// Manifest describes a prompt set in JSON:
+//
+//	{
+//		"codecs": ["ulaw", "alaw", "sln16", "g722"],
+//		"prompts": {
+//			"main-menu": "For sales, press 1.",
+//			"digits/1": "one"
+//		}
+//	}
+//
+// Prompt names are slash-separated paths relative to the output directory,
+// without an extension. If no codecs are given, all of them are written.
+type Manifest struct {
+	Codecs  []Codec           `json:"codecs"`
+	Prompts map[string]string `json:"prompts"`
+}
+
+// PromptError is returned by [Manifest.Build] for a prompt that could not be
+// written.
+type PromptError struct {
+	Name string
+	Err  error
+}
+
+func (e *PromptError) Error() string {
+	return fmt.Sprintf("prompt %q: %v", e.Name, e.Err)
+}
+
+func (e *PromptError) Unwrap() error {
+	return e.Err
+}
+
+// ParseManifest reads a manifest and checks the names of its prompts.
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	m := new(Manifest)
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	if err := d.Decode(m); err != nil {
+		return nil, err
+	}
+	if len(m.Codecs) == 0 {
+		m.Codecs = Codecs()
+	}
+	for name := range m.Prompts {
+		if name == "" || path.IsAbs(name) || strings.Contains(name, `\`) ||
+			path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, &PromptError{Name: name, Err: errors.New("name is not a relative path")}
+		}
+	}
+	return m, nil
+}
+
+// ReadManifest reads a manifest from a file.
+func ReadManifest(name string) (*Manifest, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseManifest(f)
+}
+
+// Build speaks every prompt once and writes it into dir in every codec of the
+// manifest, creating directories as needed. Files that exist are replaced.
+// It stops at the first prompt failing with a [*PromptError].
+func (m *Manifest) Build(ctx context.Context, s Synthesizer, dir string) error {
+	names := make([]string, 0, len(m.Prompts))
+	for name := range m.Prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var samples bytes.Buffer
+	for _, name := range names {
+		samples.Reset()
+		if err := s.SynthesizeResampledTo(ctx, &samples, m.Prompts[name], container.Format1M16, resample.Best); err != nil {
+			return &PromptError{Name: name, Err: err}
+		}
+		for _, c := range m.Codecs {
+			file := filepath.Join(dir, filepath.FromSlash(name)+c.Extension())
+			if err := writeFile(file, c, samples.Bytes()); err != nil {
+				return &PromptError{Name: name, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// writeFile writes samples of the engine in [container.Format1M16] to a file
+// in a codec, removing the file if that fails.
+func writeFile(name string, c Codec, samples []byte) (err error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(name)
+		}
+	}()
+	w, err := NewWriter(f, c, container.Format1M16, resample.Best)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(samples); err != nil {
+		return err
+	}
+	return w.Close()
+}
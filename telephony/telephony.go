@@ -0,0 +1,171 @@
+// Package telephony converts the samples produced by the engine into the raw
+// codecs that PBXes such as Asterisk and FreeSWITCH play as IVR prompts:
+// G.711 μ-law and A-law, 8 kHz and 16 kHz signed linear, and G.722.
+//
+// A [Writer] converts samples of any format while they are written, and a
+// [Manifest] builds a whole prompt set, one file per prompt and codec named
+// with the extensions the PBX expects:
+//
+//	m, err := telephony.ReadManifest("prompts.json")
+//	if err != nil {
+//		return err
+//	}
+//	return m.Build(ctx, tts, "/var/lib/asterisk/sounds/en")
+package telephony
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi/container"
+	"github.com/icedream/go-dectalkdapi/resample"
+)
+
+// ErrUnknownCodec is returned for codecs other than the ones defined here.
+var ErrUnknownCodec = errors.New("unknown telephony codec")
+
+// Codec is a raw telephony codec, stored without any header.
+type Codec int
+
+const (
+	// ULaw is G.711 μ-law at 8 kHz.
+	ULaw Codec = iota + 1
+
+	// ALaw is G.711 A-law at 8 kHz.
+	ALaw
+
+	// SLIN is 16-bit signed linear PCM at 8 kHz in little-endian byte
+	// order.
+	SLIN
+
+	// SLIN16 is 16-bit signed linear PCM at 16 kHz in little-endian byte
+	// order.
+	SLIN16
+
+	// G722 is ITU-T G.722 at 64 kbit/s, encoding 16 kHz audio.
+	G722
+)
+
+// Codecs returns all codecs.
+func Codecs() []Codec {
+	return []Codec{ULaw, ALaw, SLIN, SLIN16, G722}
+}
+
+// codecExtensions holds the extensions of each codec, the first one being
+// the one written.
+var codecExtensions = map[Codec][]string{
+	ULaw:   {".ulaw", ".ul", ".mu"},
+	ALaw:   {".alaw", ".al", ".alw"},
+	SLIN:   {".sln", ".slin", ".raw"},
+	SLIN16: {".sln16"},
+	G722:   {".g722"},
+}
+
+func (c Codec) String() string {
+	if ext, ok := codecExtensions[c]; ok {
+		return ext[0][1:]
+	}
+	return fmt.Sprintf("Codec(%d)", int(c))
+}
+
+// Extension returns the file name extension that Asterisk and FreeSWITCH
+// expect for the codec, such as ".ulaw".
+func (c Codec) Extension() string {
+	if ext, ok := codecExtensions[c]; ok {
+		return ext[0]
+	}
+	return ""
+}
+
+// SampleRate returns the sample rate of the audio encoded by the codec.
+func (c Codec) SampleRate() int {
+	switch c {
+	case SLIN16, G722:
+		return 16000
+	}
+	return 8000
+}
+
+// CodecFor returns the codec matching the extension of a file name.
+func CodecFor(name string) (Codec, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, c := range Codecs() {
+		for _, e := range codecExtensions[c] {
+			if e == ext {
+				return c, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// MarshalText returns the name of the codec, which is its extension without
+// the dot.
+func (c Codec) MarshalText() ([]byte, error) {
+	if _, ok := codecExtensions[c]; !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownCodec, c)
+	}
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText parses the name or any extension of a codec, with or without
+// the dot.
+func (c *Codec) UnmarshalText(text []byte) error {
+	codec, ok := CodecFor("." + strings.TrimPrefix(string(text), "."))
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownCodec, text)
+	}
+	*c = codec
+	return nil
+}
+
+// Writer converts samples to a codec while writing them.
+type Writer struct {
+	resampler *resample.Writer
+	g722      *g722Encoder
+}
+
+// NewWriter returns a writer converting mono samples in the format in to a
+// codec, resampled with the given quality, and writing them to w.
+func NewWriter(w io.Writer, c Codec, in container.Format, quality resample.Quality) (*Writer, error) {
+	out := container.Format{Encoding: container.PCM16, SampleRate: c.SampleRate(), Channels: 1}
+	tw := new(Writer)
+	switch c {
+	case ULaw:
+		out.Encoding = container.MuLaw
+	case ALaw:
+		out.Encoding = container.ALaw
+	case SLIN, SLIN16:
+	case G722:
+		tw.g722 = newG722Encoder(w)
+		w = tw.g722
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownCodec, c)
+	}
+	rw, err := resample.NewWriter(w, in, out, quality)
+	if err != nil {
+		return nil, err
+	}
+	tw.resampler = rw
+	return tw, nil
+}
+
+// Write converts samples in the layout of the engine.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.resampler.Write(p)
+}
+
+// Close writes the remaining samples. It does not close the underlying
+// writer.
+func (w *Writer) Close() error {
+	if err := w.resampler.Close(); err != nil {
+		return err
+	}
+	if w.g722 != nil {
+		return w.g722.Close()
+	}
+	return nil
+}
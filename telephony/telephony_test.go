@@ -0,0 +1,305 @@
+package telephony_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/icedream/go-dectalkdapi/container"
+	"github.com/icedream/go-dectalkdapi/resample"
+	"github.com/icedream/go-dectalkdapi/telephony"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tone returns one second of a 440 Hz tone at half the full scale.
+func tone(rate int) []float64 {
+	samples := make([]float64, rate)
+	for i := range samples {
+		samples[i] = 0.5 * math.Sin(2*math.Pi*440*float64(i)/float64(rate))
+	}
+	return samples
+}
+
+func convert(t *testing.T, c telephony.Codec, samples []byte) []byte {
+	var b bytes.Buffer
+	w, err := telephony.NewWriter(&b, c, container.Format1M16, resample.Best)
+	if err != nil {
+		t.Fatalf("NewWriter(%v) failed: %v", c, err)
+	}
+	if _, err := w.Write(samples); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	return b.Bytes()
+}
+
+func TestWriter(t *testing.T) {
+	in := container.Encode(nil, tone(11025), container.PCM16)
+	tests := map[telephony.Codec]container.Encoding{
+		telephony.ULaw:   container.MuLaw,
+		telephony.ALaw:   container.ALaw,
+		telephony.SLIN:   container.PCM16,
+		telephony.SLIN16: container.PCM16,
+	}
+	for c, e := range tests {
+		got := container.Decode(nil, convert(t, c, in), e)
+		want := tone(c.SampleRate())
+		if len(got) != len(want) {
+			t.Errorf("%v: got %d samples, want %d", c, len(got), len(want))
+			continue
+		}
+		if snr := snr(got, want, 0); snr < 30 {
+			t.Errorf("%v: got an SNR of %.1f dB", c, snr)
+		}
+	}
+}
+
+func TestG722(t *testing.T) {
+	in := container.Encode(nil, tone(11025), container.PCM16)
+	out := convert(t, telephony.G722, in)
+	if len(out) != 8000 {
+		t.Fatalf("got %d bytes, want 8000 for one second at 64 kbit/s", len(out))
+	}
+
+	// The QMF of the encoder and decoder delay the audio.
+	got := decodeG722(out)
+	want := tone(16000)
+	best := 0.0
+	for delay := 0; delay < 64; delay++ {
+		best = math.Max(best, snr(got[delay:], want[:len(want)-delay], 1000))
+	}
+	if best < 30 {
+		t.Errorf("got an SNR of %.1f dB after decoding", best)
+	}
+}
+
+// snr returns the signal-to-noise ratio of got in dB, skipping skip samples
+// at either end.
+func snr(got, want []float64, skip int) float64 {
+	var signal, noise float64
+	for i := skip; i < len(want)-skip; i++ {
+		signal += want[i] * want[i]
+		noise += (got[i] - want[i]) * (got[i] - want[i])
+	}
+	return 10 * math.Log10(signal/noise)
+}
+
+func TestCodecFor(t *testing.T) {
+	tests := map[string]telephony.Codec{
+		"welcome.ulaw":  telephony.ULaw,
+		"welcome.al":    telephony.ALaw,
+		"welcome.sln":   telephony.SLIN,
+		"welcome.SLN16": telephony.SLIN16,
+		"welcome.g722":  telephony.G722,
+	}
+	for name, want := range tests {
+		if got, ok := telephony.CodecFor(name); !ok || got != want {
+			t.Errorf("CodecFor(%q) = %v, %v, want %v", name, got, ok, want)
+		}
+	}
+	if _, ok := telephony.CodecFor("welcome.wav"); ok {
+		t.Errorf("CodecFor(%q) succeeded", "welcome.wav")
+	}
+}
+
+// synthesizer speaks every text as a tone.
+type synthesizer struct {
+	texts []string
+}
+
+func (s *synthesizer) SynthesizeResampledTo(ctx context.Context, w io.Writer, text string, format container.Format, quality resample.Quality) error {
+	if format != container.Format1M16 {
+		return errors.New("unexpected format")
+	}
+	s.texts = append(s.texts, text)
+	_, err := w.Write(container.Encode(nil, tone(11025), container.PCM16))
+	return err
+}
+
+func TestManifest(t *testing.T) {
+	m, err := telephony.ParseManifest(strings.NewReader(`{
+		"codecs": ["ulaw", "sln16"],
+		"prompts": {"main-menu": "For sales, press 1.", "digits/1": "one"}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseManifest() failed: %v", err)
+	}
+	dir := t.TempDir()
+	s := new(synthesizer)
+	if err := m.Build(context.Background(), s, dir); err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	if len(s.texts) != 2 {
+		t.Errorf("got %d prompts spoken, want each spoken once", len(s.texts))
+	}
+	for name, size := range map[string]int64{
+		"main-menu.ulaw":  8000,
+		"main-menu.sln16": 32000,
+		"digits/1.ulaw":   8000,
+		"digits/1.sln16":  32000,
+	} {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("Stat(%q) failed: %v", name, err)
+		} else if info.Size() != size {
+			t.Errorf("%s: got %d bytes, want %d", name, info.Size(), size)
+		}
+	}
+
+	// All codecs are written by default.
+	m, err = telephony.ParseManifest(strings.NewReader(`{"prompts": {"a": "a"}}`))
+	if err != nil {
+		t.Fatalf("ParseManifest() failed: %v", err)
+	}
+	if len(m.Codecs) != len(telephony.Codecs()) {
+		t.Errorf("got codecs %v by default", m.Codecs)
+	}
+
+	for _, manifest := range []string{
+		`{"prompts": {"../escape": "a"}}`,
+		`{"prompts": {"/etc/passwd": "a"}}`,
+		`{"codecs": ["mp3"], "prompts": {}}`,
+	} {
+		if _, err := telephony.ParseManifest(strings.NewReader(manifest)); err == nil {
+			t.Errorf("ParseManifest(%s) succeeded", manifest)
+		}
+	}
+}
+
+// Tables of the G.722 decoder at 64 kbit/s.
+var (
+	qmf  = [12]int{3, -11, 12, 32, -210, 951, 3876, -805, 362, -156, 53, -11}
+	qm6  = [64]int{-136, -136, -136, -136, -24808, -21904, -19008, -16704, -14984, -13512, -12280, -11192, -10232, -9360, -8576, -7856, -7192, -6576, -6000, -5456, -4944, -4464, -4008, -3576, -3168, -2776, -2400, -2032, -1688, -1360, -1040, -728, 24808, 21904, 19008, 16704, 14984, 13512, 12280, 11192, 10232, 9360, 8576, 7856, 7192, 6576, 6000, 5456, 4944, 4464, 4008, 3576, 3168, 2776, 2400, 2032, 1688, 1360, 1040, 728, 432, 136, -432, -136}
+	qm4  = [16]int{0, -20456, -12896, -8968, -6288, -4240, -2584, -1200, 20456, 12896, 8968, 6288, 4240, 2584, 1200, 0}
+	qm2  = [4]int{-7408, -1616, 7408, 1616}
+	wl   = [8]int{-60, -30, 58, 172, 334, 538, 1198, 3042}
+	rl42 = [16]int{0, 7, 6, 5, 4, 3, 2, 1, 7, 6, 5, 4, 3, 2, 1, 0}
+	wh   = [3]int{0, -214, 798}
+	rh2  = [4]int{2, 1, 2, 1}
+	ilb  = [32]int{2048, 2093, 2139, 2186, 2233, 2282, 2332, 2383, 2435, 2489, 2543, 2599, 2656, 2714, 2774, 2834, 2896, 2960, 3025, 3091, 3158, 3228, 3298, 3371, 3444, 3520, 3597, 3676, 3756, 3838, 3922, 4008}
+)
+
+type band struct {
+	s, sp, sz int
+	r, a, ap  [3]int
+	p         [3]int
+	d, b, bp  [7]int
+	sg        [7]int
+	nb, det   int
+}
+
+// decodeG722 decodes G.722 at 64 kbit/s as in ITU-T G.722, written
+// independently of the encoder.
+func decodeG722(data []byte) []float64 {
+	bands := [2]band{{det: 32}, {det: 8}}
+	var x [24]int
+	var out []float64
+	for _, code := range data {
+		low, high := &bands[0], &bands[1]
+		ilow, ihigh := int(code&0x3f), int(code>>6)
+
+		rlow := limit(low.s+(low.det*qm6[ilow])>>15, -16384, 16383)
+		dlow := (low.det * qm4[ilow>>2]) >> 15
+		low.nb = limit((low.nb*127)>>7+wl[rl42[ilow>>2]], 0, 18432)
+		low.det = scaleFactor(low.nb, 8)
+		low.adapt(dlow)
+
+		dhigh := (high.det * qm2[ihigh]) >> 15
+		rhigh := limit(dhigh+high.s, -16384, 16383)
+		high.nb = limit((high.nb*127)>>7+wh[rh2[ihigh]], 0, 22528)
+		high.det = scaleFactor(high.nb, 10)
+		high.adapt(dhigh)
+
+		copy(x[:], x[2:])
+		x[22], x[23] = rlow+rhigh, rlow-rhigh
+		var out1, out2 int
+		for i := 0; i < 12; i++ {
+			out2 += x[2*i] * qmf[i]
+			out1 += x[2*i+1] * qmf[11-i]
+		}
+		out = append(out, float64(out1>>11)/32768, float64(out2>>11)/32768)
+	}
+	return out
+}
+
+func scaleFactor(nb, shift int) int {
+	i, s := (nb>>6)&31, shift-(nb>>11)
+	if s < 0 {
+		return ilb[i] << -s << 2
+	}
+	return ilb[i] >> s << 2
+}
+
+func (s *band) adapt(d int) {
+	sat := func(x int) int { return limit(x, -32768, 32767) }
+	s.d[0], s.r[0], s.p[0] = d, sat(s.s+d), sat(s.sz+d)
+
+	for i := 0; i < 3; i++ {
+		s.sg[i] = s.p[i] >> 15
+	}
+	wd1 := sat(s.a[1] << 2)
+	if s.sg[0] == s.sg[1] {
+		wd1 = -wd1
+	}
+	wd1 = limit(wd1, math.MinInt, 32767)
+	wd3 := wd1>>7 + (s.a[2]*32512)>>15
+	if s.sg[0] == s.sg[2] {
+		wd3 += 128
+	} else {
+		wd3 -= 128
+	}
+	s.ap[2] = limit(wd3, -12288, 12288)
+
+	wd1 = (s.a[1] * 32640) >> 15
+	if s.sg[0] == s.sg[1] {
+		wd1 += 192
+	} else {
+		wd1 -= 192
+	}
+	bound := sat(15360 - s.ap[2])
+	s.ap[1] = limit(sat(wd1), -bound, bound)
+
+	step := 128
+	if d == 0 {
+		step = 0
+	}
+	s.sg[0] = d >> 15
+	for i := 1; i < 7; i++ {
+		s.sg[i] = s.d[i] >> 15
+		if s.sg[i] != s.sg[0] {
+			s.bp[i] = sat((s.b[i]*32640)>>15 - step)
+		} else {
+			s.bp[i] = sat((s.b[i]*32640)>>15 + step)
+		}
+	}
+
+	copy(s.d[1:], s.d[:6])
+	copy(s.b[1:], s.bp[1:])
+	s.r[2], s.r[1] = s.r[1], s.r[0]
+	s.p[2], s.p[1] = s.p[1], s.p[0]
+	s.a[1], s.a[2] = s.ap[1], s.ap[2]
+
+	s.sp = sat((s.a[1]*sat(2*s.r[1]))>>15 + (s.a[2]*sat(2*s.r[2]))>>15)
+	s.sz = 0
+	for i := 1; i < 7; i++ {
+		s.sz += (s.b[i] * sat(2*s.d[i])) >> 15
+	}
+	s.sz = sat(s.sz)
+	s.s = sat(s.sp + s.sz)
+}
+
+func limit(x, min, max int) int {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
@@ -0,0 +1,58 @@
+package userdict
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Compiler compiles a dictionary source in the text format written by
+// [Dictionary.WriteText] into dictFile, in the format loaded by
+// [dectalkdapi.TTS.LoadUserDictionary]. That format is not documented and is
+// only written by the dictionary tools shipped with DECtalk, so a Compiler
+// usually runs one of them, see [Command].
+type Compiler func(source, dictFile string) error
+
+// Command returns a Compiler running an external program, such as the
+// userdict tool of DECtalk. The arguments "{source}" and "{dict}" are replaced
+// by the path of the source and of the file to write. The output of the
+// program is included in the error if it fails.
+func Command(name string, args ...string) Compiler {
+	return func(source, dictFile string) error {
+		r := strings.NewReplacer("{source}", source, "{dict}", dictFile)
+		argv := make([]string, len(args))
+		for i, arg := range args {
+			argv[i] = r.Replace(arg)
+		}
+		out, err := exec.Command(name, argv...).CombinedOutput()
+		if err != nil {
+			if out = bytes.TrimSpace(out); len(out) > 0 {
+				return fmt.Errorf("%s: %w: %s", name, err, out)
+			}
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+}
+
+// Compile writes the dictionary to a temporary source file and compiles it
+// into dictFile with compile.
+func (d *Dictionary) Compile(compile Compiler, dictFile string) error {
+	f, err := os.CreateTemp("", "dectalk-*.txt")
+	if err != nil {
+		return err
+	}
+	source := f.Name()
+	defer os.Remove(source)
+
+	err = d.WriteText(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return compile(source, dictFile)
+}
@@ -0,0 +1,38 @@
+package userdict
+
+import (
+	"os"
+)
+
+// Loader is implemented by [dectalkdapi.TTS].
+type Loader interface {
+	UnloadUserDictionary() error
+	LoadUserDictionary(dictFile string) error
+}
+
+// Load compiles the dictionary with compile into a temporary file and loads it
+// into the text-to-speech system in place of the dictionary loaded before, if
+// any. The file is removed once the engine has loaded it.
+//
+// Errors from unloading the previous dictionary are ignored, since the engine
+// reports one if no dictionary has been loaded yet.
+func (d *Dictionary) Load(tts Loader, compile Compiler) error {
+	// The file only reserves a unique name, it is overwritten below.
+	f, err := os.CreateTemp("", "dectalk-*.dic")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+
+	err = f.Close()
+	if err == nil {
+		err = d.Compile(compile, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	_ = tts.UnloadUserDictionary()
+	return tts.LoadUserDictionary(name)
+}
@@ -0,0 +1,178 @@
+package userdict
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/icedream/go-dectalkdapi/phonetic"
+)
+
+var (
+	// ErrUnknownLanguage is returned by [ParsePLS] if the language of a
+	// lexicon has no phonetic table.
+	ErrUnknownLanguage = errors.New("unknown lexicon language")
+
+	// ErrUnknownAlphabet is reported by [ParsePLS] for pronunciations in an
+	// alphabet other than IPA and X-SAMPA.
+	ErrUnknownAlphabet = errors.New("unknown phonetic alphabet")
+
+	// ErrNoPronunciation is reported by [ParsePLS] for lexemes that only
+	// have aliases, which can not be expressed in a user dictionary.
+	ErrNoPronunciation = errors.New("lexeme has no pronunciation")
+)
+
+// Unmapped describes a lexeme of a PLS lexicon that could not be converted to
+// a dictionary entry.
+type Unmapped struct {
+	// Lexeme is the position of the lexeme in the lexicon, counting from 1.
+	Lexeme int
+
+	Graphemes []string
+
+	// Err tells why the lexeme could not be converted, such as
+	// [ErrNoPronunciation] or a [*phonetic.SymbolError].
+	Err error
+}
+
+func (u Unmapped) Error() string {
+	return fmt.Sprintf("lexeme %d (%s): %v", u.Lexeme, strings.Join(u.Graphemes, ", "), u.Err)
+}
+
+func (u Unmapped) Unwrap() error {
+	return u.Err
+}
+
+// UnmappedError is returned by [ParseFile] together with the dictionary for a
+// PLS lexicon with lexemes that could not be converted.
+type UnmappedError struct {
+	Unmapped []Unmapped
+}
+
+func (e *UnmappedError) Error() string {
+	if len(e.Unmapped) == 1 {
+		return e.Unmapped[0].Error()
+	}
+	return fmt.Sprintf("%v, and %d more lexemes could not be converted", e.Unmapped[0], len(e.Unmapped)-1)
+}
+
+type plsLexicon struct {
+	XMLName  xml.Name    `xml:"lexicon"`
+	Alphabet string      `xml:"alphabet,attr"`
+	Lang     string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Lexemes  []plsLexeme `xml:"lexeme"`
+}
+
+type plsLexeme struct {
+	Role      string       `xml:"role,attr"`
+	Graphemes []string     `xml:"grapheme"`
+	Phonemes  []plsPhoneme `xml:"phoneme"`
+}
+
+type plsPhoneme struct {
+	Alphabet string `xml:"alphabet,attr"`
+	Prefer   string `xml:"prefer,attr"`
+	Text     string `xml:",chardata"`
+}
+
+// ParsePLS reads a lexicon in the W3C Pronunciation Lexicon Specification
+// format and converts its pronunciations from IPA or X-SAMPA to arpabet.
+//
+// The phonetic table is chosen by lang, which is either a language tag such
+// as "en-GB" or the name of a table of the phonetic package. If lang is empty,
+// the xml:lang attribute of the lexicon is used.
+//
+// Every grapheme of a lexeme becomes an entry. The preferred pronunciation is
+// used if it can be converted, otherwise the first one that can. Role names
+// matching flags, such as "noun" or "claws:noun", set the flags of the
+// entries. Lexemes that can not be converted are returned as unmapped instead
+// of failing the whole lexicon.
+func ParsePLS(r io.Reader, lang string) (*Dictionary, []Unmapped, error) {
+	var lexicon plsLexicon
+	if err := xml.NewDecoder(r).Decode(&lexicon); err != nil {
+		return nil, nil, err
+	}
+	if lang == "" {
+		lang = lexicon.Lang
+	}
+	table, ok := phonetic.LookupTag(lang)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownLanguage, lang)
+	}
+
+	d := new(Dictionary)
+	var unmapped []Unmapped
+	for i, lexeme := range lexicon.Lexemes {
+		failed := func(err error) {
+			unmapped = append(unmapped, Unmapped{Lexeme: i + 1, Graphemes: lexeme.Graphemes, Err: err})
+		}
+		arpabet, err := lexeme.arpabet(table, lexicon.Alphabet)
+		if err != nil {
+			failed(err)
+			continue
+		}
+		flags := lexeme.flags()
+		for _, grapheme := range lexeme.Graphemes {
+			if err := d.Add(Entry{Word: grapheme, Phonemes: arpabet, Flags: flags}); err != nil {
+				failed(err)
+			}
+		}
+	}
+	return d, unmapped, nil
+}
+
+// arpabet converts the preferred pronunciation that can be converted.
+func (l plsLexeme) arpabet(table *phonetic.Table, alphabet string) (string, error) {
+	if len(l.Phonemes) == 0 {
+		return "", ErrNoPronunciation
+	}
+	candidates := make([]plsPhoneme, 0, len(l.Phonemes))
+	for _, p := range l.Phonemes {
+		if p.Prefer == "true" {
+			candidates = append(candidates, p)
+		}
+	}
+	for _, p := range l.Phonemes {
+		if p.Prefer != "true" {
+			candidates = append(candidates, p)
+		}
+	}
+
+	var firstErr error
+	for _, p := range candidates {
+		if p.Alphabet == "" {
+			p.Alphabet = alphabet
+		}
+		var arpabet string
+		var err error
+		switch strings.ToLower(p.Alphabet) {
+		case "ipa":
+			arpabet, err = table.FromIPA(strings.TrimSpace(p.Text))
+		case "x-sampa":
+			arpabet, err = table.FromXSAMPA(strings.TrimSpace(p.Text))
+		default:
+			err = fmt.Errorf("%w: %q", ErrUnknownAlphabet, p.Alphabet)
+		}
+		if err == nil {
+			return arpabet, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", firstErr
+}
+
+func (l plsLexeme) flags() Flags {
+	var f Flags
+	for _, role := range strings.Fields(l.Role) {
+		if i := strings.LastIndexByte(role, ':'); i >= 0 {
+			role = role[i+1:]
+		}
+		flag, _ := parseFlag(role)
+		f |= flag
+	}
+	return f
+}
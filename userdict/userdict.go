@@ -0,0 +1,307 @@
+// Package userdict creates, parses, merges and writes the sources of DECtalk
+// user dictionaries.
+//
+// A dictionary maps words to their pronunciation in arpabet, the same notation
+// used in phoneme commands such as [hx'ehlow]. It can be read from and written
+// to the source text format:
+//
+//	; comments start with a semicolon
+//	Zedong    z'ehd`owng          noun name
+//	DECtalk   [d'ehkt`aok]
+//
+// Lexicons in the W3C Pronunciation Lexicon Specification format can be
+// imported with [ParsePLS].
+//
+// The engine only loads dictionaries compiled by the tools shipped with
+// DECtalk, whose format is not documented. [Dictionary.Compile] runs such a
+// tool on the source text, and [Dictionary.Load] swaps the result into a
+// running text-to-speech system.
+package userdict
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Flags holds the form classes of an entry, which help the engine to pick the
+// right pronunciation depending on the part of speech.
+type Flags uint32
+
+const (
+	Noun Flags = 1 << iota
+	Verb
+	Adjective
+	Adverb
+	Function
+	Name
+	Abbreviation
+)
+
+// flagNames holds the names of the flags in bit order, as used in the source
+// text format.
+var flagNames = []string{
+	"noun",
+	"verb",
+	"adjective",
+	"adverb",
+	"function",
+	"name",
+	"abbreviation",
+}
+
+func (f Flags) String() string {
+	var names []string
+	for i, name := range flagNames {
+		if f&(1<<i) != 0 {
+			names = append(names, name)
+			f &^= 1 << i
+		}
+	}
+	if f != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint32(f)))
+	}
+	return strings.Join(names, " ")
+}
+
+// ParseFlags parses the space separated names of flags as returned by
+// [Flags.String].
+func ParseFlags(s string) (Flags, error) {
+	var f Flags
+	for _, field := range strings.Fields(s) {
+		flag, ok := parseFlag(field)
+		if !ok {
+			return 0, fmt.Errorf("unknown flag %q", field)
+		}
+		f |= flag
+	}
+	return f, nil
+}
+
+func parseFlag(name string) (Flags, bool) {
+	name = strings.ToLower(name)
+	for i, n := range flagNames {
+		if name == n {
+			return 1 << i, true
+		}
+	}
+	return 0, false
+}
+
+// Entry is a single word of a dictionary.
+type Entry struct {
+	// Word is the word as it appears in text.
+	Word string
+
+	// Phonemes is the pronunciation in arpabet, without brackets.
+	Phonemes string
+
+	Flags Flags
+}
+
+// ErrInvalidEntry is wrapped by [*SyntaxError] and returned by
+// [Dictionary.Add] for entries without a word or pronunciation.
+var ErrInvalidEntry = errors.New("invalid dictionary entry")
+
+// SyntaxError is returned by [Parse] for a malformed line.
+type SyntaxError struct {
+	// Line is the line number, counting from 1.
+	Line int
+
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return ErrInvalidEntry
+}
+
+// Dictionary is a set of entries, at most one per word. The zero value is an
+// empty dictionary ready to use.
+type Dictionary struct {
+	entries map[string]Entry
+}
+
+// New returns a dictionary holding the given entries. Later entries replace
+// earlier ones for the same word.
+func New(entries ...Entry) (*Dictionary, error) {
+	d := new(Dictionary)
+	for _, e := range entries {
+		if err := d.Add(e); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Add adds an entry, replacing any previous entry for the same word. Brackets
+// around the pronunciation are removed.
+func (d *Dictionary) Add(e Entry) error {
+	e.Word = strings.TrimSpace(e.Word)
+	e.Phonemes = strings.TrimSpace(strings.Trim(strings.TrimSpace(e.Phonemes), "[]"))
+	if e.Word == "" || e.Phonemes == "" || strings.ContainsAny(e.Word, " \t\r\n") {
+		return fmt.Errorf("%w: %q", ErrInvalidEntry, e.Word)
+	}
+	if d.entries == nil {
+		d.entries = make(map[string]Entry)
+	}
+	d.entries[e.Word] = e
+	return nil
+}
+
+// Remove removes the entry for a word, if any.
+func (d *Dictionary) Remove(word string) {
+	delete(d.entries, word)
+}
+
+// Lookup returns the entry for a word.
+func (d *Dictionary) Lookup(word string) (Entry, bool) {
+	e, ok := d.entries[word]
+	return e, ok
+}
+
+// Len returns the number of entries.
+func (d *Dictionary) Len() int {
+	return len(d.entries)
+}
+
+// Entries returns all entries, sorted by word.
+func (d *Dictionary) Entries() []Entry {
+	entries := make([]Entry, 0, len(d.entries))
+	for _, e := range d.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Word < entries[j].Word
+	})
+	return entries
+}
+
+// Merge adds all entries of other, replacing entries of d for the same words.
+func (d *Dictionary) Merge(other *Dictionary) {
+	for _, e := range other.entries {
+		// Entries of other have been validated already.
+		_ = d.Add(e)
+	}
+}
+
+// Parse reads a dictionary in the source text format. Every line holds a word,
+// its pronunciation, optionally in brackets, and any number of flag names, all
+// separated by white space. Empty lines and lines starting with ";" or "#" are
+// ignored.
+func Parse(r io.Reader) (*Dictionary, error) {
+	d := new(Dictionary)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == ';' || text[0] == '#' {
+			continue
+		}
+		e, err := parseLine(text)
+		if err != nil {
+			return nil, &SyntaxError{Line: line, Message: err.Error()}
+		}
+		if err := d.Add(e); err != nil {
+			return nil, &SyntaxError{Line: line, Message: err.Error()}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func parseLine(text string) (Entry, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return Entry{}, errors.New("missing pronunciation")
+	}
+	e := Entry{Word: fields[0]}
+	rest := fields[1:]
+
+	// A bracketed pronunciation may contain spaces.
+	if strings.HasPrefix(rest[0], "[") {
+		end := -1
+		for i, f := range rest {
+			if strings.HasSuffix(f, "]") {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return Entry{}, errors.New("unterminated pronunciation")
+		}
+		e.Phonemes = strings.Join(rest[:end+1], " ")
+		rest = rest[end+1:]
+	} else {
+		e.Phonemes = rest[0]
+		rest = rest[1:]
+	}
+
+	flags, err := ParseFlags(strings.Join(rest, " "))
+	if err != nil {
+		return Entry{}, err
+	}
+	e.Flags = flags
+	return e, nil
+}
+
+// WriteText writes the dictionary in the source text format accepted by
+// [Parse], sorted by word.
+func (d *Dictionary) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range d.Entries() {
+		line := e.Word + "\t[" + e.Phonemes + "]"
+		if e.Flags != 0 {
+			line += "\t" + e.Flags.String()
+		}
+		if _, err := bw.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ErrUnknownFormat is returned by [ParseFile] for unknown file extensions.
+var ErrUnknownFormat = errors.New("unknown dictionary format")
+
+// ParseFile reads a dictionary from a file, choosing the format by its
+// extension: ".yaml" and ".yml" for [ParseYAML], ".pls" for [ParsePLS] with
+// the language of the lexicon and ".txt" or none for [Parse].
+//
+// Lexemes of PLS lexicons that can not be converted are skipped. The
+// dictionary of the other lexemes is then returned together with an
+// [*UnmappedError] listing them.
+func ParseFile(name string) (*Dictionary, error) {
+	parse := Parse
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		parse = ParseYAML
+	case ".pls":
+		parse = func(r io.Reader) (*Dictionary, error) {
+			d, unmapped, err := ParsePLS(r, "")
+			if err == nil && len(unmapped) > 0 {
+				err = &UnmappedError{Unmapped: unmapped}
+			}
+			return d, err
+		}
+	case ".txt", "":
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
@@ -0,0 +1,259 @@
+package userdict_test
+
+import (
+	"errors"
+	"github.com/icedream/go-dectalkdapi/userdict"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const source = `; test dictionary
+Zedong    z'ehd` + "`" + `owng    noun name
+DECtalk   [d'ehkt` + "`" + `aok]
+Müller    m'uhlrr
+`
+
+func TestParse(t *testing.T) {
+	d, err := userdict.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if d.Len() != 3 {
+		t.Fatalf("Expected 3 entries, got %d", d.Len())
+	}
+	e, ok := d.Lookup("Zedong")
+	if !ok || e.Phonemes != "z'ehd`owng" || e.Flags != userdict.Noun|userdict.Name {
+		t.Errorf("Unexpected entry for Zedong: %+v", e)
+	}
+	if e, _ := d.Lookup("DECtalk"); e.Phonemes != "d'ehkt`aok" {
+		t.Errorf("Expected brackets to be removed, got %q", e.Phonemes)
+	}
+
+	_, err = userdict.Parse(strings.NewReader("word [unterminated\n"))
+	var syntaxErr *userdict.SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 1 {
+		t.Errorf("Expected a SyntaxError in line 1, got %v", err)
+	}
+	if _, err := userdict.Parse(strings.NewReader("word w'rrd bogus\n")); !errors.Is(err, userdict.ErrInvalidEntry) {
+		t.Errorf("Expected ErrInvalidEntry for an unknown flag, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	d, _ := userdict.New(
+		userdict.Entry{Word: "tomato", Phonemes: "taxm'eytow"},
+		userdict.Entry{Word: "potato", Phonemes: "paxt'eytow"},
+	)
+	other, _ := userdict.New(userdict.Entry{Word: "tomato", Phonemes: "taxm'aatow"})
+	d.Merge(other)
+
+	if e, _ := d.Lookup("tomato"); e.Phonemes != "taxm'aatow" {
+		t.Errorf("Expected merged entry to win, got %q", e.Phonemes)
+	}
+	if d.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", d.Len())
+	}
+}
+
+type loader struct {
+	calls []string
+	data  []byte
+}
+
+// copyCompiler stands in for the dictionary tools of DECtalk by copying the
+// source.
+func copyCompiler(source, dictFile string) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dictFile, data, 0o644)
+}
+
+func (l *loader) UnloadUserDictionary() error {
+	l.calls = append(l.calls, "unload")
+	return errors.New("no dictionary loaded")
+}
+
+func (l *loader) LoadUserDictionary(dictFile string) error {
+	l.calls = append(l.calls, "load")
+	f, err := os.Open(dictFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	d, err := userdict.Parse(f)
+	if err != nil {
+		return err
+	}
+	l.data = []byte(d.Entries()[0].Word)
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	d, _ := userdict.New(userdict.Entry{Word: "tomato", Phonemes: "taxm'aatow"})
+	l := new(loader)
+	if err := d.Load(l, copyCompiler); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if strings.Join(l.calls, ",") != "unload,load" || string(l.data) != "tomato" {
+		t.Errorf("Unexpected calls %v with %q", l.calls, l.data)
+	}
+
+	l = new(loader)
+	failing := func(source, dictFile string) error { return errors.New("compiler failed") }
+	if err := d.Load(l, failing); err == nil || len(l.calls) != 0 {
+		t.Errorf("Expected Load() to fail before loading, got %v after %v", err, l.calls)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if _, err := exec.LookPath("cp"); err != nil {
+		t.Skip("cp not found")
+	}
+	d, _ := userdict.New(userdict.Entry{Word: "tomato", Phonemes: "taxm'aatow"})
+	dictFile := filepath.Join(t.TempDir(), "user.dic")
+	if err := d.Compile(userdict.Command("cp", "{source}", "{dict}"), dictFile); err != nil {
+		t.Fatalf("Compile() failed: %v", err)
+	}
+	data, err := os.ReadFile(dictFile)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	if string(data) != "tomato\t[taxm'aatow]\n" {
+		t.Errorf("Expected the source text to be compiled, got %q", data)
+	}
+	if err := d.Compile(userdict.Command("cp"), dictFile); err == nil {
+		t.Errorf("Expected Compile() to fail when the command fails")
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	const lexicon = `# test lexicon
+Zedong: z'ehd` + "`" + `owng
+DECtalk: "[d'ehkt` + "`" + `aok]"
+tomato:
+  phonemes: taxm'aatow
+  flags: [noun, name]
+`
+	d, err := userdict.ParseYAML(strings.NewReader(lexicon))
+	if err != nil {
+		t.Fatalf("ParseYAML() failed: %v", err)
+	}
+	if d.Len() != 3 {
+		t.Fatalf("Expected 3 entries, got %d", d.Len())
+	}
+	if e, _ := d.Lookup("DECtalk"); e.Phonemes != "d'ehkt`aok" {
+		t.Errorf("Expected quotes and brackets to be removed, got %q", e.Phonemes)
+	}
+	if e, _ := d.Lookup("tomato"); e.Phonemes != "taxm'aatow" || e.Flags != userdict.Noun|userdict.Name {
+		t.Errorf("Unexpected entry for tomato: %+v", e)
+	}
+
+	if _, err := userdict.ParseYAML(strings.NewReader("tomato:\n  color: red\n")); !errors.Is(err, userdict.ErrInvalidEntry) {
+		t.Errorf("Expected ErrInvalidEntry for an unknown key, got %v", err)
+	}
+
+	const quoted = `"std::cout": s'iyawt  # a comment
+'it''s': "'ihts"
+potato:
+  phonemes: paxt'eytow # another comment
+  flags:
+    - noun
+    - name  # a comment
+`
+	d, err = userdict.ParseYAML(strings.NewReader(quoted))
+	if err != nil {
+		t.Fatalf("ParseYAML() failed: %v", err)
+	}
+	if e, ok := d.Lookup("std::cout"); !ok || e.Phonemes != "s'iyawt" {
+		t.Errorf("Unexpected entry for a quoted key: %+v", e)
+	}
+	if e, ok := d.Lookup("it's"); !ok || e.Phonemes != "'ihts" {
+		t.Errorf("Unexpected entry for a single-quoted key: %+v", e)
+	}
+	if e, _ := d.Lookup("potato"); e.Phonemes != "paxt'eytow" || e.Flags != userdict.Noun|userdict.Name {
+		t.Errorf("Unexpected entry for potato: %+v", e)
+	}
+
+	for _, lexicon := range []string{
+		"word: 'unterminated\n",
+		"word: `aa\n",
+		"word: \"a\" b\n",
+		"- word\n",
+		"word:\n  phonemes: w'rrd\n  - noun\n",
+	} {
+		var syntaxErr *userdict.SyntaxError
+		if _, err := userdict.ParseYAML(strings.NewReader(lexicon)); !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected a SyntaxError for %q, got %v", lexicon, err)
+		}
+	}
+}
+
+func TestParsePLS(t *testing.T) {
+	const lexicon = `<?xml version="1.0" encoding="UTF-8"?>
+<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon"
+    alphabet="ipa" xml:lang="en-US">
+  <lexeme role="claws:noun">
+    <grapheme>tomato</grapheme>
+    <grapheme>Tomato</grapheme>
+    <phoneme>təˈmeɪtoʊ</phoneme>
+    <phoneme prefer="true">təˈmɑtoʊ</phoneme>
+  </lexeme>
+  <lexeme>
+    <grapheme>hello</grapheme>
+    <phoneme alphabet="x-sampa">h@"loU</phoneme>
+  </lexeme>
+  <lexeme>
+    <grapheme>W3C</grapheme>
+    <alias>World Wide Web Consortium</alias>
+  </lexeme>
+  <lexeme>
+    <grapheme>quux</grapheme>
+    <phoneme alphabet="x-acme">kwuks</phoneme>
+  </lexeme>
+</lexicon>`
+	d, unmapped, err := userdict.ParsePLS(strings.NewReader(lexicon), "")
+	if err != nil {
+		t.Fatalf("ParsePLS() failed: %v", err)
+	}
+	if d.Len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", d.Len())
+	}
+	if e, _ := d.Lookup("Tomato"); e.Phonemes != "taxm'aatow" || e.Flags != userdict.Noun {
+		t.Errorf("Unexpected entry for Tomato: %+v", e)
+	}
+	if e, _ := d.Lookup("hello"); e.Phonemes != "hxaxl'ow" {
+		t.Errorf("Unexpected entry for hello: %+v", e)
+	}
+
+	if len(unmapped) != 2 {
+		t.Fatalf("Expected 2 unmapped lexemes, got %v", unmapped)
+	}
+	if unmapped[0].Lexeme != 3 || !errors.Is(unmapped[0], userdict.ErrNoPronunciation) {
+		t.Errorf("Expected lexeme 3 to have no pronunciation, got %v", unmapped[0])
+	}
+	if unmapped[1].Lexeme != 4 || !errors.Is(unmapped[1], userdict.ErrUnknownAlphabet) {
+		t.Errorf("Expected lexeme 4 to use an unknown alphabet, got %v", unmapped[1])
+	}
+
+	if _, _, err := userdict.ParsePLS(strings.NewReader(lexicon), "ja"); !errors.Is(err, userdict.ErrUnknownLanguage) {
+		t.Errorf("Expected ErrUnknownLanguage, got %v", err)
+	}
+
+	name := filepath.Join(t.TempDir(), "lexicon.pls")
+	if err := os.WriteFile(name, []byte(lexicon), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err = userdict.ParseFile(name)
+	var unmappedErr *userdict.UnmappedError
+	if !errors.As(err, &unmappedErr) || len(unmappedErr.Unmapped) != 2 {
+		t.Errorf("Expected an UnmappedError with 2 lexemes, got %v", err)
+	}
+	if d == nil || d.Len() != 3 {
+		t.Errorf("Expected the 3 converted entries along with the error, got %v", d)
+	}
+}
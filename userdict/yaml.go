@@ -0,0 +1,241 @@
+package userdict
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseYAML reads a dictionary from a YAML lexicon. It is not a general YAML
+// parser: it accepts the subset of YAML needed for a mapping from words to
+// either a pronunciation or a mapping with the keys "phonemes" and "flags",
+// and returns a [*SyntaxError] for anything else.
+//
+//	# comments start with a hash
+//	Zedong: z'ehd`owng  # also at the end of a line
+//	DECtalk: "[d'ehkt`aok]"
+//	"std::cout": s'iyawt
+//	tomato:
+//	  phonemes: taxm'aatow
+//	  flags: [noun]
+//	potato:
+//	  phonemes: paxt'eytow
+//	  flags:
+//	    - noun
+//
+// Keys and values are plain, single-quoted or double-quoted scalars on a
+// single line. As in YAML, values starting with a quote, a backtick or a
+// bracket must be quoted, such as pronunciations starting with a stress mark.
+// Flags may be given as a flow or block sequence, or as space separated names.
+// Anchors, tags, multi-line scalars and flow mappings are not supported.
+func ParseYAML(r io.Reader) (*Dictionary, error) {
+	d := new(Dictionary)
+	var current *Entry
+	currentLine := 0
+	// flagItems is set after a "flags" key without a value, which may be
+	// followed by a block sequence.
+	flagItems := false
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		e := *current
+		current = nil
+		if err := d.Add(e); err != nil {
+			return &SyntaxError{Line: currentLine, Message: err.Error()}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		if text == "" || text[0] == '#' || text == "---" {
+			continue
+		}
+		indented := raw[0] == ' ' || raw[0] == '\t'
+
+		if text == "-" || strings.HasPrefix(text, "- ") {
+			if !indented || current == nil || !flagItems {
+				return nil, &SyntaxError{Line: line, Message: "unexpected sequence item"}
+			}
+			item, err := sequenceItem(text[1:])
+			if err != nil {
+				return nil, &SyntaxError{Line: line, Message: err.Error()}
+			}
+			flags, err := ParseFlags(item)
+			if err != nil {
+				return nil, &SyntaxError{Line: line, Message: err.Error()}
+			}
+			current.Flags |= flags
+			continue
+		}
+		flagItems = false
+
+		key, value, err := splitMapping(text)
+		if err != nil {
+			return nil, &SyntaxError{Line: line, Message: err.Error()}
+		}
+
+		if !indented {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current = &Entry{Word: key, Phonemes: value}
+			currentLine = line
+			continue
+		}
+
+		if current == nil {
+			return nil, &SyntaxError{Line: line, Message: "indented key without word"}
+		}
+		switch key {
+		case "phonemes":
+			current.Phonemes = value
+		case "flags":
+			flags, err := ParseFlags(strings.NewReplacer("[", " ", "]", " ", ",", " ").Replace(value))
+			if err != nil {
+				return nil, &SyntaxError{Line: line, Message: err.Error()}
+			}
+			current.Flags = flags
+			flagItems = value == ""
+		default:
+			return nil, &SyntaxError{Line: line, Message: "unknown key " + key}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// splitMapping splits a line of a block mapping into key and value, removing
+// quotes and a trailing comment. A flow sequence is returned as it is, with
+// its brackets.
+func splitMapping(text string) (key, value string, err error) {
+	key, rest, err := scalar(text, true)
+	if err != nil {
+		return "", "", err
+	}
+	if key == "" || !strings.HasPrefix(rest, ":") || len(rest) > 1 && rest[1] != ' ' && rest[1] != '\t' {
+		return "", "", errors.New("expected key: value")
+	}
+
+	rest = strings.TrimLeft(rest[1:], " \t")
+	if strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return "", "", errors.New("unterminated flow sequence")
+		}
+		value, rest = rest[:end+1], rest[end+1:]
+	} else if value, rest, err = scalar(rest, false); err != nil {
+		return "", "", err
+	}
+	if err := endOfLine(rest); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// sequenceItem returns the value of a block sequence item, given the text
+// after its dash.
+func sequenceItem(text string) (string, error) {
+	item, rest, err := scalar(strings.TrimLeft(text, " \t"), false)
+	if err != nil {
+		return "", err
+	}
+	return item, endOfLine(rest)
+}
+
+// endOfLine checks that only white space and a comment follow a value.
+func endOfLine(rest string) error {
+	if rest = strings.TrimLeft(rest, " \t"); rest != "" && rest[0] != '#' {
+		return fmt.Errorf("unexpected %q after value", rest)
+	}
+	return nil
+}
+
+// scalar parses the plain or quoted scalar at the start of s and returns it
+// and the rest of s. A plain key ends at a colon followed by white space, a
+// plain value at a comment.
+func scalar(s string, key bool) (value, rest string, err error) {
+	if s == "" {
+		return "", "", nil
+	}
+	switch c := s[0]; {
+	case c == '"':
+		return doubleQuoted(s)
+	case c == '\'':
+		return singleQuoted(s)
+	case c == '#' && !key:
+		return "", s, nil
+	case strings.IndexByte("#[]{}&*!|>%@`,", c) >= 0:
+		return "", "", fmt.Errorf("scalars starting with %q must be quoted", c)
+	}
+
+	end := len(s)
+	for i := 0; i < len(s); i++ {
+		if key && s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ' || s[i+1] == '\t') ||
+			!key && s[i] == '#' && i > 0 && (s[i-1] == ' ' || s[i-1] == '\t') {
+			end = i
+			break
+		}
+	}
+	return strings.TrimRight(s[:end], " \t"), s[end:], nil
+}
+
+// yamlEscapes maps the escapes supported in double-quoted scalars to the
+// characters they stand for.
+var yamlEscapes = map[byte]byte{
+	'"':  '"',
+	'\\': '\\',
+	'/':  '/',
+	' ':  ' ',
+	't':  '\t',
+}
+
+func doubleQuoted(s string) (value, rest string, err error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 == len(s) {
+				break
+			}
+			i++
+			escaped, ok := yamlEscapes[s[i]]
+			if !ok {
+				return "", "", fmt.Errorf("unsupported escape \\%c", s[i])
+			}
+			b.WriteByte(escaped)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", errors.New("unterminated double-quoted scalar")
+}
+
+func singleQuoted(s string) (value, rest string, err error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '\'' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+			continue
+		}
+		return b.String(), s[i+1:], nil
+	}
+	return "", "", errors.New("unterminated single-quoted scalar")
+}
@@ -0,0 +1,126 @@
+//go:build (windows && 386) || linux
+// +build windows,386 linux
+
+package dectalkdapi
+
+/*
+#if defined WIN32
+#include <windows.h>
+#include <TTSAPI.H>
+#else
+#include <dtk/ttsapi.h>
+#endif
+*/
+import "C"
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// worker runs functions on a single goroutine that is locked to its OS
+// thread. The engine keeps per-thread state, such as the language selected by
+// SelectLang, which would otherwise get lost as the Go scheduler moves
+// goroutines between threads.
+type worker struct {
+	calls chan func()
+}
+
+func newWorker() *worker {
+	w := &worker{
+		calls: make(chan func()),
+	}
+	go w.run()
+	return w
+}
+
+func (w *worker) run() {
+	// The thread is never unlocked so that it is terminated together with
+	// the goroutine instead of being reused with leftover engine state.
+	runtime.LockOSThread()
+	for f := range w.calls {
+		f()
+	}
+}
+
+// do runs f on the worker thread and waits for it to return.
+func (w *worker) do(f func()) {
+	done := make(chan struct{})
+	w.calls <- func() {
+		defer close(done)
+		f()
+	}
+	<-done
+}
+
+func (w *worker) stop() {
+	close(w.calls)
+}
+
+// run runs f on the worker thread of t, or directly if t has no worker.
+func (t *TTS) run(f func()) {
+	if t.worker == nil {
+		f()
+		return
+	}
+	t.worker.do(f)
+}
+
+// call runs an engine function like run and converts its result to an error.
+func (t *TTS) call(f func() C.MMRESULT) error {
+	var result C.MMRESULT
+	t.run(func() {
+		result = f()
+	})
+	return mmResultToError(result)
+}
+
+// StartupLocked starts up the text-to-speech system like [Startup], but on a
+// dedicated goroutine that is locked to its own OS thread. Every call on the
+// returned instance is then executed on that thread, one at a time, which
+// makes the instance safe for concurrent use by multiple goroutines.
+//
+// If lang is not nil, it is selected for the thread before starting up, so the
+// instance speaks that language without the caller having to deal with the
+// thread affinity of [SelectLang].
+//
+// [TTS.Reset], [TTS.AddBuffer] and the reset that [TTS.Shutdown] starts with
+// are the only calls that bypass the thread. They have to be usable while
+// another goroutine is blocked in [TTS.Sync] on that thread, either to
+// interrupt it or to keep the engine supplied with buffers until it returns.
+func StartupLocked(deviceOptions DeviceOption, lang *TTSLanguage) (*TTS, error) {
+	tts := new(TTS)
+	tts.worker = newWorker()
+	tts.lang = lang
+
+	var err error
+	tts.worker.do(func() {
+		if lang != nil && !SelectLang(lang) {
+			err = ErrCanNotLoadLanguage
+			return
+		}
+		err = tts.startup(deviceOptions)
+	})
+	if err != nil {
+		tts.worker.stop()
+		return nil, err
+	}
+	return tts, nil
+}
+
+// Language returns the language the instance was started with: the one given
+// to [StartupLocked], or else the one last selected with [SelectLang] before
+// the instance was started. It returns nil if neither is known, in which case
+// the engine speaks its default language.
+func (t *TTS) Language() *TTSLanguage {
+	return t.lang
+}
+
+// langMu serializes loading and unloading languages, which modifies state
+// shared by the whole process.
+var langMu sync.Mutex
+
+// selectedLang is the language last selected with SelectLang, which
+// instances started without a language adopt.
+var selectedLang atomic.Pointer[TTSLanguage]